@@ -0,0 +1,98 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const accessHistoryQuery = "SELECT DIRECT_OBJECTS_ACCESSED, OBJECTS_MODIFIED " +
+	"FROM SNOWFLAKE.ACCOUNT_USAGE.ACCESS_HISTORY WHERE QUERY_ID = ?"
+
+// AccessDirection tells whether a query read or wrote an object
+type AccessDirection string
+
+const (
+	// AccessRead is an object read by the query
+	AccessRead AccessDirection = "READ"
+	// AccessWrite is an object written by the query
+	AccessWrite AccessDirection = "WRITE"
+)
+
+// ObjectRef is a table, view or other object accessed by a query
+type ObjectRef struct {
+	Domain    string // e.g. Table or View
+	Name      string // fully qualified name
+	ID        int64
+	Columns   []string
+	Direction AccessDirection
+}
+
+type accessHistoryObject struct {
+	ObjectDomain string `json:"objectDomain"`
+	ObjectName   string `json:"objectName"`
+	ObjectID     int64  `json:"objectId"`
+	Columns      []struct {
+		ColumnName string `json:"columnName"`
+	} `json:"columns"`
+}
+
+// AccessedObjects returns the objects the query qid read directly and the
+// objects it wrote, as recorded in SNOWFLAKE.ACCOUNT_USAGE.ACCESS_HISTORY.
+// The role in use must be able to read the view, which requires Enterprise
+// Edition, and a query only appears in it up to three hours after it ran;
+// until then no objects are returned.
+func (sc *snowflakeConn) AccessedObjects(ctx context.Context, qid string) ([]ObjectRef, error) {
+	rows, err := sc.queryContextInternal(ctx, accessHistoryQuery, []driver.NamedValue{{Ordinal: 1, Value: qid}})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var objects []ObjectRef
+	dest := make([]driver.Value, len(rows.Columns()))
+	for {
+		if err = rows.Next(dest); err == io.EOF {
+			return objects, nil
+		} else if err != nil {
+			return nil, err
+		}
+		for i, direction := range []AccessDirection{AccessRead, AccessWrite} {
+			refs, err := parseAccessHistoryObjects(dest[i], direction)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, refs...)
+		}
+	}
+}
+
+func parseAccessHistoryObjects(v driver.Value, direction AccessDirection) ([]ObjectRef, error) {
+	if v == nil {
+		return nil, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected access history value: %v", v)
+	}
+	var objects []accessHistoryObject
+	if err := json.Unmarshal([]byte(s), &objects); err != nil {
+		return nil, err
+	}
+	refs := make([]ObjectRef, len(objects))
+	for i, o := range objects {
+		refs[i] = ObjectRef{
+			Domain:    o.ObjectDomain,
+			Name:      o.ObjectName,
+			ID:        o.ObjectID,
+			Direction: direction,
+		}
+		for _, c := range o.Columns {
+			refs[i].Columns = append(refs[i].Columns, c.ColumnName)
+		}
+	}
+	return refs, nil
+}
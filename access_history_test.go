@@ -0,0 +1,64 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAccessedObjects(t *testing.T) {
+	read := `[{"objectDomain": "Table", "objectName": "DB.PUBLIC.ORDERS", "objectId": 1026,
+		"columns": [{"columnId": 1, "columnName": "ID"}, {"columnId": 2, "columnName": "AMOUNT"}]},
+		{"objectDomain": "View", "objectName": "DB.PUBLIC.CUSTOMERS_V", "objectId": 2048,
+		"columns": [{"columnId": 7, "columnName": "NAME"}]}]`
+	written := `[{"objectDomain": "Table", "objectName": "DB.PUBLIC.ORDER_TOTALS", "objectId": 3072,
+		"columns": [{"columnId": 1, "columnName": "TOTAL"}]}]`
+	var req execRequest
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		return &execResponse{
+			Data: execResponseData{
+				QueryID: "01a2b3c4-0000-0000-0000-000000000002",
+				RowType: []execResponseRowType{
+					{Name: "DIRECT_OBJECTS_ACCESSED", Type: "array"},
+					{Name: "OBJECTS_MODIFIED", Type: "array"},
+				},
+				RowSet: [][]*string{{&read, &written}},
+				Total:  1,
+			},
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	objects, err := sc.AccessedObjects(context.Background(), "01a2b3c4-0000-0000-0000-000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.SQLText != accessHistoryQuery {
+		t.Fatalf("unexpected query. expected: %v, got: %v", accessHistoryQuery, req.SQLText)
+	}
+	if b, ok := req.Bindings["1"]; !ok || b.Value != "01a2b3c4-0000-0000-0000-000000000001" {
+		t.Fatalf("the query ID should be bound. got: %v", req.Bindings)
+	}
+	expected := []ObjectRef{
+		{Domain: "Table", Name: "DB.PUBLIC.ORDERS", ID: 1026, Columns: []string{"ID", "AMOUNT"}, Direction: AccessRead},
+		{Domain: "View", Name: "DB.PUBLIC.CUSTOMERS_V", ID: 2048, Columns: []string{"NAME"}, Direction: AccessRead},
+		{Domain: "Table", Name: "DB.PUBLIC.ORDER_TOTALS", ID: 3072, Columns: []string{"TOTAL"}, Direction: AccessWrite},
+	}
+	if !reflect.DeepEqual(objects, expected) {
+		t.Fatalf("unexpected accessed objects. expected: %+v, got: %+v", expected, objects)
+	}
+}
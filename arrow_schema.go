@@ -0,0 +1,92 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/arrow"
+)
+
+// keys of the metadata of the fields returned by DescribeArrowSchema
+const (
+	arrowMetaLogicalType = "logicalType"
+	arrowMetaPrecision   = "precision"
+	arrowMetaScale       = "scale"
+)
+
+// DescribeArrowSchema describes the result of the query without running it
+// and returns its columns as an Arrow schema. FIXED columns are decimals with
+// the column precision and scale, date and time columns are DATE32, TIME64
+// and timestamps, with the timestamps of TIMESTAMP_LTZ and TIMESTAMP_TZ
+// columns in UTC, and semi-structured and geospatial columns are strings.
+// Timestamps are in nanoseconds unless another unit is set with
+// WithArrowSchemaTimestampUnit. The Snowflake type, precision and scale of a
+// column are kept in the metadata of its field.
+func (sc *snowflakeConn) DescribeArrowSchema(ctx context.Context, query string, args ...driver.NamedValue) (*arrow.Schema, error) {
+	if sc.rest == nil {
+		return nil, driver.ErrBadConn
+	}
+	data, err := sc.exec(ctx, query, false /* noResult */, false /* isInternal */, true /* describeOnly */, args)
+	if err != nil {
+		if data != nil {
+			code, err := strconv.Atoi(data.Code)
+			if err != nil {
+				return nil, err
+			}
+			return nil, &SnowflakeError{
+				Number:   code,
+				SQLState: data.Data.SQLState,
+				Message:  err.Error(),
+				QueryID:  data.Data.QueryID,
+			}
+		}
+		return nil, err
+	}
+	unit := getArrowSchemaTimestampUnit(ctx)
+	fields := make([]arrow.Field, len(data.Data.RowType))
+	for i, rowType := range data.Data.RowType {
+		if fields[i], err = rowTypeToArrowField(rowType, unit); err != nil {
+			return nil, err
+		}
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+func rowTypeToArrowField(rowType execResponseRowType, unit arrow.TimeUnit) (arrow.Field, error) {
+	var dt arrow.DataType
+	switch getSnowflakeType(strings.ToUpper(rowType.Type)) {
+	case fixedType:
+		dt = &arrow.Decimal128Type{Precision: int32(rowType.Precision), Scale: int32(rowType.Scale)}
+	case realType:
+		dt = arrow.PrimitiveTypes.Float64
+	case textType, variantType, objectType, arrayType, geographyType, geometryType:
+		dt = arrow.BinaryTypes.String
+	case binaryType:
+		dt = arrow.BinaryTypes.Binary
+	case booleanType:
+		dt = arrow.FixedWidthTypes.Boolean
+	case dateType:
+		dt = arrow.FixedWidthTypes.Date32
+	case timeType:
+		dt = arrow.FixedWidthTypes.Time64ns
+	case timestampNtzType:
+		dt = &arrow.TimestampType{Unit: unit}
+	case timestampLtzType, timestampTzType:
+		dt = &arrow.TimestampType{Unit: unit, TimeZone: "UTC"}
+	default:
+		return arrow.Field{}, fmt.Errorf("unsupported data type of column %v: %v", rowType.Name, rowType.Type)
+	}
+	return arrow.Field{
+		Name:     rowType.Name,
+		Type:     dt,
+		Nullable: rowType.Nullable,
+		Metadata: arrow.NewMetadata(
+			[]string{arrowMetaLogicalType, arrowMetaPrecision, arrowMetaScale},
+			[]string{strings.ToUpper(rowType.Type), strconv.FormatInt(rowType.Precision, 10), strconv.FormatInt(rowType.Scale, 10)}),
+	}, nil
+}
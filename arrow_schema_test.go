@@ -0,0 +1,124 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/google/uuid"
+)
+
+func TestDescribeArrowSchema(t *testing.T) {
+	var req execRequest
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		return &execResponse{
+			Data: execResponseData{
+				RowType: []execResponseRowType{
+					{Name: "ID", Type: "fixed", Precision: 38, Scale: 0},
+					{Name: "AMOUNT", Type: "fixed", Precision: 10, Scale: 2, Nullable: true},
+					{Name: "RATIO", Type: "real", Nullable: true},
+					{Name: "NOTE", Type: "text", Length: 16777216, Nullable: true},
+					{Name: "FLAG", Type: "boolean", Nullable: true},
+					{Name: "DAY", Type: "date", Nullable: true},
+					{Name: "CREATED", Type: "timestamp_ntz", Scale: 9, Nullable: true},
+					{Name: "UPDATED", Type: "timestamp_tz", Scale: 9, Nullable: true},
+				},
+			},
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	query := "select id, amount, ratio, note, flag, day, created, updated from orders where id = ?"
+	schema, err := sc.DescribeArrowSchema(context.Background(), query, driver.NamedValue{Ordinal: 1, Value: int64(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !req.DescribeOnly || req.SQLText != query {
+		t.Fatalf("the query should have been described only. request: %+v", req)
+	}
+	if _, ok := req.Bindings["1"]; !ok {
+		t.Fatalf("the arguments should be bound. got: %v", req.Bindings)
+	}
+	expected := []struct {
+		name     string
+		typ      arrow.DataType
+		nullable bool
+		logical  string
+		scale    string
+	}{
+		{"ID", &arrow.Decimal128Type{Precision: 38, Scale: 0}, false, "FIXED", "0"},
+		{"AMOUNT", &arrow.Decimal128Type{Precision: 10, Scale: 2}, true, "FIXED", "2"},
+		{"RATIO", arrow.PrimitiveTypes.Float64, true, "REAL", "0"},
+		{"NOTE", arrow.BinaryTypes.String, true, "TEXT", "0"},
+		{"FLAG", arrow.FixedWidthTypes.Boolean, true, "BOOLEAN", "0"},
+		{"DAY", arrow.FixedWidthTypes.Date32, true, "DATE", "0"},
+		{"CREATED", &arrow.TimestampType{Unit: arrow.Nanosecond}, true, "TIMESTAMP_NTZ", "9"},
+		{"UPDATED", &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}, true, "TIMESTAMP_TZ", "9"},
+	}
+	if len(schema.Fields()) != len(expected) {
+		t.Fatalf("unexpected number of fields. expected: %v, got: %v", len(expected), len(schema.Fields()))
+	}
+	for i, e := range expected {
+		f := schema.Field(i)
+		if f.Name != e.name || !arrow.TypeEqual(f.Type, e.typ) || f.Nullable != e.nullable {
+			t.Fatalf("unexpected field %v. expected: %v %v nullable=%v, got: %v %v nullable=%v",
+				i, e.name, e.typ, e.nullable, f.Name, f.Type, f.Nullable)
+		}
+		if logical := f.Metadata.Values()[f.Metadata.FindKey(arrowMetaLogicalType)]; logical != e.logical {
+			t.Fatalf("unexpected logical type of %v. expected: %v, got: %v", f.Name, e.logical, logical)
+		}
+		if scale := f.Metadata.Values()[f.Metadata.FindKey(arrowMetaScale)]; scale != e.scale {
+			t.Fatalf("unexpected scale of %v. expected: %v, got: %v", f.Name, e.scale, scale)
+		}
+	}
+}
+
+func TestDescribeArrowSchemaTimestampUnit(t *testing.T) {
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		return &execResponse{
+			Data: execResponseData{
+				RowType: []execResponseRowType{
+					{Name: "CREATED", Type: "timestamp_ntz", Scale: 9},
+					{Name: "UPDATED", Type: "timestamp_ltz", Scale: 9},
+				},
+			},
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	for _, unit := range []arrow.TimeUnit{arrow.Second, arrow.Millisecond, arrow.Microsecond, arrow.Nanosecond} {
+		ctx, err := WithArrowSchemaTimestampUnit(context.Background(), unit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := sc.DescribeArrowSchema(ctx, "select created, updated from orders")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range schema.Fields() {
+			if ts, ok := f.Type.(*arrow.TimestampType); !ok || ts.Unit != unit {
+				t.Fatalf("unexpected type of %v. expected a timestamp in %v, got: %v", f.Name, unit, f.Type)
+			}
+		}
+	}
+	if _, err := WithArrowSchemaTimestampUnit(context.Background(), arrow.TimeUnit(42)); err == nil {
+		t.Fatal("should have failed to use an unsupported unit")
+	}
+}
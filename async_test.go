@@ -5,8 +5,17 @@ package gosnowflake
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestAsyncMode(t *testing.T) {
@@ -126,3 +135,236 @@ func retrieveRows(rows *sql.Rows, ch chan string) {
 	ch <- s
 	close(ch)
 }
+
+func TestAsyncMultiStatementUnlimitedCount(t *testing.T) {
+	const parentID = "01a2b3c4-0000-0000-0000-000000000010"
+	responses := map[string][]string{
+		"/queries/" + parentID + "/result": {
+			`{"code": "333333", "success": true, "data": {"queryId": "` + parentID + `", "getResultUrl": "/queries/` + parentID + `/result"}}`,
+			`{"code": "0", "success": true, "data": {"queryId": "` + parentID + `", "statementTypeId": 4096,
+				"rowtype": [{"name": "multiple statement execution", "type": "text"}], "rowset": [["Multiple statements executed successfully."]],
+				"total": 1, "resultIds": "01a2b3c4-0000-0000-0000-000000000011,01a2b3c4-0000-0000-0000-000000000012", "resultTypes": "4096,4096"}}`,
+		},
+		"/queries/01a2b3c4-0000-0000-0000-000000000011/result": {
+			`{"code": "0", "success": true, "data": {"queryId": "01a2b3c4-0000-0000-0000-000000000011", "statementTypeId": 4096,
+				"rowtype": [{"name": "1", "type": "fixed"}], "rowset": [["1"]], "total": 1, "queryResultFormat": "json"}}`,
+		},
+		"/queries/01a2b3c4-0000-0000-0000-000000000012/result": {
+			`{"code": "0", "success": true, "data": {"queryId": "01a2b3c4-0000-0000-0000-000000000012", "statementTypeId": 4096,
+				"rowtype": [{"name": "2", "type": "fixed"}], "rowset": [["2"], ["3"]], "total": 2, "queryResultFormat": "json"}}`,
+		},
+	}
+	var mu sync.Mutex
+	getMock := func(_ context.Context, _ *snowflakeRestful, u *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		body := `{"code": "0", "success": true, "data": {"queries": []}}` // monitoring data
+		if bodies, ok := responses[u.Path]; ok {
+			body = bodies[0]
+			if len(bodies) > 1 {
+				responses[u.Path] = bodies[1:]
+			}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}
+	var req execRequest
+	postMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, body []byte, _ time.Duration, _ bool) (*http.Response, error) {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(`{"code": "333334", "success": true, "data": {"queryId": "` + parentID +
+				`", "getResultUrl": "/queries/` + parentID + `/result"}}`)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:            "https",
+			Host:                "abc.snowflakecomputing.com",
+			Port:                443,
+			TokenAccessor:       getSimpleTokenAccessor(),
+			FuncPostQuery:       postRestfulQuery,
+			FuncPostQueryHelper: postRestfulQueryHelper,
+			FuncPost:            postMock,
+			FuncGet:             getMock,
+		},
+	}
+	ctx, _ := WithMultiStatement(WithAsyncMode(context.Background()), 0)
+	rows, err := sc.QueryContext(ctx, "select 1; select 2 union all select 3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if count, ok := req.Parameters[string(multiStatementCount)].(float64); !ok || count != 0 {
+		t.Fatalf("expected an unlimited statement count, got: %v", req.Parameters)
+	}
+
+	var values []string
+	dest := make([]driver.Value, 1)
+	rs := rows.(driver.RowsNextResultSet)
+	for {
+		for rows.Next(dest) == nil {
+			values = append(values, dest[0].(string))
+		}
+		if !rs.HasNextResultSet() {
+			break
+		}
+		if err = rs.NextResultSet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(values, []string{"1", "2", "3"}) {
+		t.Fatalf("unexpected rows of the two result sets: %v", values)
+	}
+}
+
+func TestAsyncMultiStatementNoResultIDs(t *testing.T) {
+	getMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(`{"code": "0", "success": true, "data": {"queryId": "01a2b3c4-0000-0000-0000-000000000010",
+				"statementTypeId": 4096, "rowtype": [{"name": "multiple statement execution", "type": "text"}], "total": 0}}`)),
+		}, nil
+	}
+	sr := &snowflakeRestful{
+		Protocol:      "https",
+		Host:          "abc.snowflakecomputing.com",
+		Port:          443,
+		TokenAccessor: getSimpleTokenAccessor(),
+		FuncGet:       getMock,
+	}
+	ctx := setResultType(context.Background(), queryResultType)
+	rows := &snowflakeRows{ctx: ctx, status: QueryStatusInProgress, errChannel: make(chan error)}
+	go getAsync(ctx, sr, map[string]string{}, sr.getFullURL("/queries/01a2b3c4-0000-0000-0000-000000000010/result", nil),
+		time.Second, nil, rows, &Config{Params: map[string]*string{}})
+	err := rows.Next(make([]driver.Value, 1))
+	if se, ok := err.(*SnowflakeError); !ok || se.Number != ErrNoResultIDs {
+		t.Fatalf("expected error %v, got: %v", ErrNoResultIDs, err)
+	}
+}
+
+func TestAsyncPollBackoff(t *testing.T) {
+	defer func(backoff backoffConfig) {
+		asyncPollBackoff = backoff
+	}(asyncPollBackoff)
+	asyncPollBackoff = backoffConfig{initial: 20 * time.Millisecond, max: 20 * time.Millisecond, multiplier: 1}
+
+	var polls []time.Time
+	getMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		polls = append(polls, time.Now())
+		body := `{"code": "333334", "success": true, "data": {"queryId": "01a2b3c4-0000-0000-0000-000000000011"}}`
+		if len(polls) == 3 {
+			body = `{"code": "0", "success": true, "data": {"queryId": "01a2b3c4-0000-0000-0000-000000000011",
+				"rowtype": [{"name": "1", "type": "fixed"}], "rowset": [["1"]], "total": 1}}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	}
+	sr := &snowflakeRestful{
+		Protocol:      "https",
+		Host:          "abc.snowflakecomputing.com",
+		Port:          443,
+		TokenAccessor: getSimpleTokenAccessor(),
+		FuncGet:       getMock,
+	}
+	ctx := setResultType(context.Background(), queryResultType)
+	rows := &snowflakeRows{ctx: ctx, status: QueryStatusInProgress, errChannel: make(chan error)}
+	go getAsync(ctx, sr, map[string]string{}, sr.getFullURL("/queries/01a2b3c4-0000-0000-0000-000000000011/result", nil),
+		time.Second, nil, rows, &Config{Params: map[string]*string{}})
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	if len(polls) != 3 {
+		t.Fatalf("expected 3 polls, got: %v", len(polls))
+	}
+	for i := 1; i < len(polls); i++ {
+		if d := polls[i].Sub(polls[i-1]); d < 10*time.Millisecond {
+			t.Fatalf("poll %v followed the previous one after %v, expected a backoff of at least %v", i, d, 10*time.Millisecond)
+		}
+	}
+}
+
+func TestAsyncPollCanceled(t *testing.T) {
+	getMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"code": "333334", "success": true, "data": {"queryId": "01a2b3c4-0000-0000-0000-000000000012"}}`)),
+		}, nil
+	}
+	sr := &snowflakeRestful{
+		Protocol:      "https",
+		Host:          "abc.snowflakecomputing.com",
+		Port:          443,
+		TokenAccessor: getSimpleTokenAccessor(),
+		FuncGet:       getMock,
+	}
+	ctx, cancel := context.WithCancel(setResultType(context.Background(), queryResultType))
+	rows := &snowflakeRows{ctx: ctx, status: QueryStatusInProgress, errChannel: make(chan error)}
+	go getAsync(ctx, sr, map[string]string{}, sr.getFullURL("/queries/01a2b3c4-0000-0000-0000-000000000012/result", nil),
+		time.Second, nil, rows, &Config{Params: map[string]*string{}})
+	cancel()
+	err := rows.Next(make([]driver.Value, 1))
+	if se, ok := err.(*SnowflakeError); !ok || se.Message != context.Canceled.Error() {
+		t.Fatalf("expected the polling to stop with %v, got: %v", context.Canceled, err)
+	}
+}
+
+type noSyncWaitPolicy struct{}
+
+func (noSyncWaitPolicy) InitialWait() time.Duration     { return 0 }
+func (noSyncWaitPolicy) PollInterval() time.Duration    { return 0 }
+func (noSyncWaitPolicy) MaxSyncDuration() time.Duration { return 0 }
+
+func TestSubmitPolicyNoSyncWait(t *testing.T) {
+	const qid = "01a2b3c4-0000-0000-0000-000000000020"
+	getMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(`{"code": "0", "success": true, "data": {"queryId": "` + qid +
+				`", "statementTypeId": 12544, "rowtype": [{"name": "number of rows inserted", "type": "fixed"}], "rowset": [["3"]]}}`)),
+		}, nil
+	}
+	postMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ []byte, _ time.Duration, _ bool) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(`{"code": "333333", "success": true, "data": {"queryId": "` + qid +
+				`", "getResultUrl": "/queries/` + qid + `/result"}}`)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:            "https",
+			Host:                "abc.snowflakecomputing.com",
+			Port:                443,
+			TokenAccessor:       getSimpleTokenAccessor(),
+			FuncPostQuery:       postRestfulQuery,
+			FuncPostQueryHelper: postRestfulQueryHelper,
+			FuncPost:            postMock,
+			FuncGet:             getMock,
+		},
+	}
+	ctx := WithSubmitPolicy(context.Background(), noSyncWaitPolicy{})
+	res, err := sc.ExecContext(ctx, "insert into t values (1), (2), (3)", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sfRes := res.(SnowflakeResult)
+	if sfRes.GetStatus() != QueryStatusInProgress {
+		t.Fatalf("expected the query to be in progress, got: %v", sfRes.GetStatus())
+	}
+	if sfRes.GetQueryID() != qid {
+		t.Fatalf("expected query ID %v, got: %v", qid, sfRes.GetQueryID())
+	}
+	if count, err := res.RowsAffected(); err != nil || count != 3 {
+		t.Fatalf("expected 3 affected rows, got: %v, err: %v", count, err)
+	}
+	if sfRes.GetStatus() != QueryStatusComplete {
+		t.Fatalf("expected the query to be complete, got: %v", sfRes.GetStatus())
+	}
+}
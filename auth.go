@@ -8,8 +8,10 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"runtime"
@@ -18,7 +20,6 @@ import (
 	"time"
 
 	"github.com/form3tech-oss/jwt-go"
-	"github.com/google/uuid"
 )
 
 const (
@@ -39,7 +40,8 @@ const (
 	AuthTypeOkta
 	// AuthTypeJwt is to use Jwt to perform authentication
 	AuthTypeJwt
-	// AuthTypeTokenAccessor is to use the provided token accessor and bypass authentication
+	// AuthTypeTokenAccessor is to use the provided token accessor and bypass authentication. The session of its
+	// tokens is checked with a SELECT 1 when the connection is opened.
 	AuthTypeTokenAccessor
 )
 
@@ -199,7 +201,7 @@ func postAuth(
 	timeout time.Duration) (
 	data *authResponse, err error) {
 	params.Add(requestIDKey, getOrGenerateRequestIDFromContext(ctx).String())
-	params.Add(requestGUIDKey, uuid.New().String())
+	params.Add(requestGUIDKey, UUIDGenerator().String())
 
 	fullURL := sr.getFullURL(loginRequestPath, params)
 	logger.Infof("full URL: %v", fullURL)
@@ -276,10 +278,14 @@ func authenticate(
 	}
 
 	sessionParameters := make(map[string]interface{})
+	// the parameters are updated by the queries running on the connection
+	// when the session logs in again
+	sc.paramsMutex.Lock()
 	for k, v := range sc.cfg.Params {
 		// upper casing to normalize keys
 		sessionParameters[strings.ToUpper(k)] = *v
 	}
+	sc.paramsMutex.Unlock()
 
 	sessionParameters[sessionClientValidateDefaultParameters] = sc.cfg.ValidateDefaultParameters != ConfigBoolFalse
 
@@ -298,9 +304,20 @@ func authenticate(
 		requestMain.LoginName = sc.cfg.User
 		requestMain.Authenticator = AuthTypeExternalBrowser.String()
 	case AuthTypeOAuth:
+		if sc.cfg.TokenProvider != nil {
+			token, err := sc.cfg.TokenProvider(ctx)
+			if err != nil {
+				return nil, err
+			}
+			sc.paramsMutex.Lock()
+			sc.cfg.Token = token
+			sc.paramsMutex.Unlock()
+		}
+		sc.paramsMutex.Lock()
+		requestMain.Token = sc.cfg.Token
+		sc.paramsMutex.Unlock()
 		requestMain.LoginName = sc.cfg.User
 		requestMain.Authenticator = AuthTypeOAuth.String()
-		requestMain.Token = sc.cfg.Token
 	case AuthTypeOkta:
 		requestMain.RawSAMLResponse = string(samlResponse)
 	case AuthTypeJwt:
@@ -384,6 +401,7 @@ func authenticate(
 	}
 	logger.Info("Authentication SUCCESS")
 	sc.rest.TokenAccessor.SetTokens(respd.Data.Token, respd.Data.MasterToken, respd.Data.SessionID)
+	sc.rest.setSessionTokenValidity(respd.Data.Validity * time.Second)
 	return &respd.Data, nil
 }
 
@@ -416,6 +434,68 @@ func prepareJWTToken(config *Config) (string, error) {
 	return tokenString, err
 }
 
+// canRefreshOAuthToken returns true if the session can log in again with a
+// fresh OAuth access token once it can no longer be renewed
+func (sc *snowflakeConn) canRefreshOAuthToken() bool {
+	return sc != nil && sc.cfg.Authenticator == AuthTypeOAuth && sc.cfg.TokenProvider != nil
+}
+
+// reauthenticateOAuth logs in again with an access token from
+// Config.TokenProvider, replacing the tokens of the expired session. The new
+// login starts a new session, without the temporary objects, variables and
+// open transaction of the expired one.
+func reauthenticateOAuth(ctx context.Context, sc *snowflakeConn) error {
+	logger.WithContext(ctx).Warn("session expired, logging in to a new session with a fresh OAuth token. " +
+		"the temporary objects, variables and open transaction of the expired session are lost")
+	authData, err := authenticate(ctx, sc, nil, nil)
+	if err != nil {
+		return err
+	}
+	sc.populateSessionParameters(authData.Parameters)
+	return nil
+}
+
+// retryLogin runs a step of the login again, after a backoff that doubles each
+// time, while it fails with a transient error, up to Config.LoginMaxRetries
+// times
+func (sc *snowflakeConn) retryLogin(step func() error) error {
+	ctx := sc.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	backoff := sc.cfg.LoginRetryBackoff
+	if backoff <= 0 {
+		backoff = defaultLoginRetryBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		err := step()
+		if err == nil || attempt > sc.cfg.LoginMaxRetries || !isRetryableLoginError(err) {
+			return err
+		}
+		logger.WithContext(ctx).Warnf("login attempt %v failed, retrying in %v. err: %v", attempt, backoff, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// isRetryableLoginError returns true if a login failed because of the network
+// or the availability of Snowflake or the IdP rather than the credentials
+func isRetryableLoginError(err error) bool {
+	var se *SnowflakeError
+	if errors.As(err, &se) {
+		return se.Number == ErrCodeServiceUnavailable
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
 // Authenticate with sc.cfg
 func authenticateWithConfig(sc *snowflakeConn) error {
 	var authData *authResponseMain
@@ -423,6 +503,14 @@ func authenticateWithConfig(sc *snowflakeConn) error {
 	var proofKey []byte
 	var err error
 	logger.Infof("Authenticating via %v", sc.cfg.Authenticator.String())
+	login := func() (err error) {
+		authData, err = authenticate(
+			sc.ctx,
+			sc,
+			samlResponse,
+			proofKey)
+		return err
+	}
 	switch sc.cfg.Authenticator {
 	case AuthTypeExternalBrowser:
 		samlResponse, proofKey, err = authenticateByExternalBrowser(
@@ -433,34 +521,50 @@ func authenticateWithConfig(sc *snowflakeConn) error {
 			sc.cfg.Account,
 			sc.cfg.User,
 			sc.cfg.Password)
-		if err != nil {
-			sc.cleanup()
-			return err
+		if err == nil {
+			// the token and proof key of the browser login are single use
+			err = login()
 		}
 	case AuthTypeOkta:
-		samlResponse, err = authenticateBySAML(
-			sc.ctx,
-			sc.rest,
-			sc.cfg.OktaURL,
-			sc.cfg.Application,
-			sc.cfg.Account,
-			sc.cfg.User,
-			sc.cfg.Password)
-		if err != nil {
-			sc.cleanup()
-			return err
+		// the SAML response is single use, so each attempt gets a new one
+		err = sc.retryLogin(func() (err error) {
+			samlResponse, err = authenticateBySAML(
+				sc.ctx,
+				sc.rest,
+				sc.cfg.OktaURL,
+				sc.cfg.Application,
+				sc.cfg.Account,
+				sc.cfg.User,
+				sc.cfg.Password)
+			if err != nil {
+				return err
+			}
+			return login()
+		})
+	case AuthTypeSnowflake:
+		if sc.cfg.Passcode != "" || sc.cfg.PasscodeInPassword {
+			// MFA passcodes are single use
+			err = login()
+		} else {
+			err = sc.retryLogin(login)
 		}
+	default:
+		// JWTs are signed again and OAuth tokens fetched again by each attempt
+		err = sc.retryLogin(login)
 	}
-	authData, err = authenticate(
-		sc.ctx,
-		sc,
-		samlResponse,
-		proofKey)
 	if err != nil {
 		sc.cleanup()
 		return err
 	}
 	sc.populateSessionParameters(authData.Parameters)
 	sc.ctx = context.WithValue(sc.ctx, SFSessionIDKey, authData.SessionID)
+	if sc.cfg.Authenticator == AuthTypeTokenAccessor {
+		// no login was made with the tokens, so check their session is usable
+		if err = sc.Ping(sc.ctx); err != nil {
+			logger.Errorf("the session of the token accessor is not usable. err: %v", err)
+			sc.cleanup()
+			return err
+		}
+	}
 	return nil
 }
@@ -9,11 +9,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/form3tech-oss/jwt-go"
+	"github.com/google/uuid"
 )
 
 func TestUnitPostAuth(t *testing.T) {
@@ -416,6 +420,71 @@ func TestUnitAuthenticateOAuth(t *testing.T) {
 	}
 }
 
+func TestUnitAuthenticateOAuthTokenProvider(t *testing.T) {
+	var sentTokens []string
+	sessions := 0
+	postAuthMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, jsonBody []byte, _ time.Duration) (*authResponse, error) {
+		var ar authRequest
+		if err := json.Unmarshal(jsonBody, &ar); err != nil {
+			return nil, err
+		}
+		sentTokens = append(sentTokens, ar.Data.Token)
+		sessions++
+		return &authResponse{
+			Success: true,
+			Data: authResponseMain{
+				Token:       fmt.Sprintf("session%v", sessions),
+				MasterToken: fmt.Sprintf("master%v", sessions),
+				SessionID:   int64(sessions),
+			},
+		}, nil
+	}
+	renewMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ []byte, _ time.Duration, _ bool) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(`{"data": null, "code": "` + masterTokenExpiredCode +
+				`", "message": "Authentication token has expired.", "success": false}`)),
+		}, nil
+	}
+	sc := getDefaultSnowflakeConn()
+	sc.cfg.Authenticator = AuthTypeOAuth
+	calls := 0
+	sc.cfg.TokenProvider = func(ctx context.Context) (string, error) {
+		calls++
+		return fmt.Sprintf("oauth%v", calls), nil
+	}
+	sc.rest = &snowflakeRestful{
+		Protocol:      "https",
+		Host:          "abc.snowflakecomputing.com",
+		Port:          443,
+		FuncPostAuth:  postAuthMock,
+		FuncPost:      renewMock,
+		TokenAccessor: getSimpleTokenAccessor(),
+		Connection:    sc,
+	}
+
+	if _, err := authenticate(context.Background(), sc, nil, nil); err != nil {
+		t.Fatalf("failed to run. err: %v", err)
+	}
+	if err := renewRestfulSession(context.Background(), sc.rest, time.Second); err != nil {
+		t.Fatalf("failed to renew the session. err: %v", err)
+	}
+	expected := []string{"oauth1", "oauth2"}
+	if len(sentTokens) != len(expected) || sentTokens[0] != expected[0] || sentTokens[1] != expected[1] {
+		t.Fatalf("each login should use a fresh OAuth token. expected: %v, got: %v", expected, sentTokens)
+	}
+	if token, masterToken, sessionID := sc.rest.TokenAccessor.GetTokens(); token != "session2" || masterToken != "master2" || sessionID != 2 {
+		t.Fatalf("the session tokens should be replaced. got: %v, %v, %v", token, masterToken, sessionID)
+	}
+
+	sc.cfg.TokenProvider = func(ctx context.Context) (string, error) {
+		return "", errors.New("token endpoint unavailable")
+	}
+	if err := renewRestfulSession(context.Background(), sc.rest, time.Second); err == nil {
+		t.Fatal("should have failed to get a fresh token")
+	}
+}
+
 func TestUnitAuthenticatePasscode(t *testing.T) {
 	var err error
 	sr := &snowflakeRestful{
@@ -467,3 +536,170 @@ func TestUnitAuthenticateJWT(t *testing.T) {
 		t.Fatalf("invalid token passed")
 	}
 }
+
+func TestUnitAuthenticateWithConfigTokenAccessorPing(t *testing.T) {
+	for _, tc := range []struct {
+		success bool
+		code    string
+	}{
+		{success: true, code: "0"},
+		{success: false, code: "390112"}, // session no longer exists
+	} {
+		ta := getSimpleTokenAccessor()
+		ta.SetTokens("auth_token", "master_token", 123)
+		var queries []string
+		postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+			var req execRequest
+			if err := json.Unmarshal(body, &req); err != nil {
+				return nil, err
+			}
+			queries = append(queries, req.SQLText)
+			return &execResponse{Code: tc.code, Success: tc.success, Message: "session no longer exists"}, nil
+		}
+		sc := getDefaultSnowflakeConn()
+		sc.ctx = context.Background()
+		sc.cfg.Authenticator = AuthTypeTokenAccessor
+		sc.cfg.TokenAccessor = ta
+		sc.rest = &snowflakeRestful{
+			FuncPostAuth:  postAuthFailServiceIssue,
+			FuncPostQuery: postQueryMock,
+			TokenAccessor: ta,
+		}
+
+		err := authenticateWithConfig(sc)
+		if len(queries) != 1 || queries[0] != "SELECT 1" {
+			t.Fatalf("the session should have been checked with a ping. queries: %v", queries)
+		}
+		if tc.success {
+			if err != nil {
+				t.Fatalf("should not have failed, err %v", err)
+			}
+			if sc.rest == nil {
+				t.Fatal("the connection should be usable")
+			}
+			continue
+		}
+		if err == nil {
+			t.Fatal("should have failed to use the expired session")
+		}
+		if sc.rest != nil {
+			t.Fatal("the connection should have been cleaned up")
+		}
+	}
+}
+
+func TestUnitAuthenticateWithConfigLoginRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		firstErr func(context.Context, *snowflakeRestful, *url.Values, map[string]string, []byte, time.Duration) (*authResponse, error)
+		calls    int
+		success  bool
+	}{
+		{name: "service unavailable", firstErr: postAuthFailServiceIssue, calls: 2, success: true},
+		{name: "wrong account", firstErr: postAuthFailWrongAccount, calls: 1, success: false},
+	} {
+		calls := 0
+		sc := getDefaultSnowflakeConn()
+		sc.ctx = context.Background()
+		sc.cfg.LoginMaxRetries = 3
+		sc.cfg.LoginRetryBackoff = time.Millisecond
+		sc.rest.FuncPostAuth = func(ctx context.Context, sr *snowflakeRestful, params *url.Values, headers map[string]string, body []byte, timeout time.Duration) (*authResponse, error) {
+			calls++
+			if calls == 1 {
+				return tc.firstErr(ctx, sr, params, headers, body, timeout)
+			}
+			return postAuthSuccess(ctx, sr, params, headers, body, timeout)
+		}
+
+		err := authenticateWithConfig(sc)
+		if calls != tc.calls {
+			t.Fatalf("%v: expected %v login attempts, got: %v", tc.name, tc.calls, calls)
+		}
+		if tc.success && err != nil {
+			t.Fatalf("%v: should have succeeded after a retry, err: %v", tc.name, err)
+		}
+		if !tc.success && err == nil {
+			t.Fatalf("%v: should not have been retried", tc.name)
+		}
+	}
+
+	sc := getDefaultSnowflakeConn()
+	sc.ctx = context.Background()
+	calls := 0
+	sc.rest.FuncPostAuth = func(ctx context.Context, sr *snowflakeRestful, params *url.Values, headers map[string]string, body []byte, timeout time.Duration) (*authResponse, error) {
+		calls++
+		return postAuthFailServiceIssue(ctx, sr, params, headers, body, timeout)
+	}
+	if err := authenticateWithConfig(sc); err == nil || calls != 1 {
+		t.Fatalf("logins should not be retried by default. attempts: %v, err: %v", calls, err)
+	}
+	// the single use SAML response of Okta is obtained again for each attempt
+	sc = getDefaultSnowflakeConn()
+	sc.ctx = context.Background()
+	sc.cfg.Authenticator = AuthTypeOkta
+	sc.cfg.OktaURL = &url.URL{Scheme: "https", Host: "abc.com"}
+	sc.cfg.LoginMaxRetries = 3
+	sc.cfg.LoginRetryBackoff = time.Millisecond
+	calls = 0
+	samlCalls := 0
+	sc.rest.FuncPostAuthSAML = func(ctx context.Context, sr *snowflakeRestful, headers map[string]string, body []byte, timeout time.Duration) (*authResponse, error) {
+		samlCalls++
+		return postAuthSAMLAuthSuccess(ctx, sr, headers, body, timeout)
+	}
+	sc.rest.FuncPostAuthOKTA = postAuthOKTASuccess
+	sc.rest.FuncGetSSO = getSSOSuccess
+	sc.rest.Protocol, sc.rest.Host, sc.rest.Port = "https", "abc.com", 443
+	sc.rest.FuncPostAuth = func(ctx context.Context, sr *snowflakeRestful, params *url.Values, headers map[string]string, body []byte, timeout time.Duration) (*authResponse, error) {
+		calls++
+		if calls == 1 {
+			return postAuthFailServiceIssue(ctx, sr, params, headers, body, timeout)
+		}
+		return postAuthSuccess(ctx, sr, params, headers, body, timeout)
+	}
+	if err := authenticateWithConfig(sc); err != nil {
+		t.Fatalf("should have succeeded after a retry, err: %v", err)
+	}
+	if calls != 2 || samlCalls != 2 {
+		t.Fatalf("expected a new SAML response for each of the 2 login attempts. logins: %v, SAML requests: %v", calls, samlCalls)
+	}
+
+	// MFA passcodes are single use
+	sc = getDefaultSnowflakeConn()
+	sc.ctx = context.Background()
+	sc.cfg.Passcode = "123456"
+	sc.cfg.LoginMaxRetries = 3
+	sc.cfg.LoginRetryBackoff = time.Millisecond
+	calls = 0
+	sc.rest.FuncPostAuth = func(ctx context.Context, sr *snowflakeRestful, params *url.Values, headers map[string]string, body []byte, timeout time.Duration) (*authResponse, error) {
+		calls++
+		return postAuthFailServiceIssue(ctx, sr, params, headers, body, timeout)
+	}
+	if err := authenticateWithConfig(sc); err == nil || calls != 1 {
+		t.Fatalf("a login with a passcode should not be retried. attempts: %v, err: %v", calls, err)
+	}
+}
+
+func TestUnitSessionID(t *testing.T) {
+	sc := getDefaultSnowflakeConn()
+	sc.ctx = context.Background()
+	sc.rest.FuncPostAuth = func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration) (*authResponse, error) {
+		return &authResponse{
+			Success: true,
+			Data:    authResponseMain{Token: "t", MasterToken: "m", SessionID: 4567},
+		}, nil
+	}
+	var sp SessionIDProvider = sc
+	if id := sp.SessionID(); id != -1 {
+		t.Fatalf("expected -1 before authentication, got: %v", id)
+	}
+	if err := authenticateWithConfig(sc); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if id := sp.SessionID(); id != 4567 {
+		t.Fatalf("expected the session ID of the login, got: %v", id)
+	}
+	sc.cleanup()
+	if id := sp.SessionID(); id != -1 {
+		t.Fatalf("expected -1 after the connection is cleaned up, got: %v", id)
+	}
+}
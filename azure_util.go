@@ -3,6 +3,7 @@
 package gosnowflake
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -25,7 +26,7 @@ type azureLocation struct {
 	path          string
 }
 
-func (util *snowflakeAzureUtil) createClient(info *execResponseStageInfo, _ bool) cloudClient {
+func (util *snowflakeAzureUtil) createClient(info *execResponseStageInfo, _ bool) (cloudClient, error) {
 	sasToken := info.Creds.AzureSasToken
 	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{
 		Retry: azblob.RetryOptions{
@@ -35,9 +36,12 @@ func (util *snowflakeAzureUtil) createClient(info *execResponseStageInfo, _ bool
 		},
 	})
 
-	u, _ := url.Parse(fmt.Sprintf("https://%s.%s/%s%s", info.StorageAccount, info.EndPoint, info.Path, sasToken))
+	u, err := url.Parse(fmt.Sprintf("https://%s.%s/%s%s", info.StorageAccount, info.EndPoint, info.Path, sasToken))
+	if err != nil {
+		return nil, err
+	}
 	containerURL := azblob.NewContainerURL(*u, p)
-	return &containerURL
+	return &containerURL, nil
 }
 
 // cloudUtil implementation
@@ -126,13 +130,24 @@ func (util *snowflakeAzureUtil) uploadFile(
 
 	var err error
 	blobURL := azContainerURL.NewBlockBlobURL(path)
-	if meta.srcStream != nil {
-		uploadSrc := meta.srcStream
-		if meta.realSrcStream != nil {
-			uploadSrc = meta.realSrcStream
+	if meta.srcReader != nil {
+		uploadSrc := meta.srcReader
+		if meta.realSrcReader != nil {
+			uploadSrc = meta.realSrcReader
 		}
 		_, err = azblob.UploadStreamToBlockBlob(context.Background(), uploadSrc, blobURL, azblob.UploadStreamToBlockBlobOptions{
-			BufferSize: uploadSrc.Len(),
+			BufferSize: int(multiPartThreshold),
+			MaxBuffers: maxConcurrency,
+			Metadata:   azureMeta,
+		})
+	} else if meta.srcStream != nil {
+		uploadStream := meta.srcStream
+		if meta.realSrcStream != nil {
+			uploadStream = meta.realSrcStream
+		}
+		// read a copy so that the stream can be uploaded again on retry
+		_, err = azblob.UploadStreamToBlockBlob(context.Background(), bytes.NewReader(uploadStream.Bytes()), blobURL, azblob.UploadStreamToBlockBlobOptions{
+			BufferSize: uploadStream.Len(),
 			Metadata:   azureMeta,
 		})
 	} else {
@@ -0,0 +1,114 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+func TestUploadStreamToAzure(t *testing.T) {
+	contents := "123,test1\n456,test2\n"
+	var requests int
+	var blob []byte
+	var metadata http.Header
+	blocks := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if requests == 1 {
+			// the first attempt fails and is retried with the whole stream
+			w.Header().Set("x-ms-error-code", "ServerBusy")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><Error><Code>ServerBusy</Code><Message>busy</Message></Error>`))
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, "/container/stage/data.csv") {
+			t.Errorf("unexpected path: %v", r.URL.Path)
+		}
+		switch r.URL.Query().Get("comp") {
+		case "block":
+			blocks[r.URL.Query().Get("blockid")] = b
+		case "blocklist":
+			var list struct {
+				Latest []string `xml:"Latest"`
+			}
+			if err = xml.Unmarshal(b, &list); err != nil {
+				t.Error(err)
+			}
+			blob = nil
+			for _, id := range list.Latest {
+				blob = append(blob, blocks[id]...)
+			}
+			metadata = r.Header
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL + "/container")
+	containerURL := azblob.NewContainerURL(*u, azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{
+		Retry: azblob.RetryOptions{MaxTries: 1},
+	}))
+
+	for _, sized := range []bool{false, true} {
+		requests = 0
+		sfe := testStageEncryption()
+		meta := fileMetadata{
+			name:               "data.csv",
+			stageLocationType:  "AZURE",
+			noSleepingTime:     true,
+			parallel:           1,
+			client:             &containerURL,
+			stageInfo:          &execResponseStageInfo{Location: "container/stage/", LocationType: "AZURE"},
+			dstFileName:        "data.csv",
+			srcFileName:        "data.csv",
+			realSrcFileName:    "data.csv",
+			overwrite:          true,
+			encryptionMaterial: sfe,
+			options:            &SnowflakeFileTransferOptions{multiPartThreshold: dataSizeThreshold},
+		}
+		if sized {
+			meta.srcReader = strings.NewReader(contents)
+			meta.srcFileSize = len(contents)
+		} else {
+			meta.srcStream = bytes.NewBufferString(contents)
+			meta.sha256Digest, meta.uploadSize = new(snowflakeFileUtil).getDigestAndSizeForStream(&meta.srcStream)
+		}
+		err := new(remoteStorageUtil).uploadOneFile(&meta)
+		if sized {
+			// a sized stream is consumed by the first attempt and can't be retried
+			if err == nil || meta.resStatus != needRetry {
+				t.Fatalf("the upload of a sized stream should have failed. status: %v, err: %v", meta.resStatus, err)
+			}
+			meta.srcReader = strings.NewReader(contents)
+			meta.realSrcReader = nil
+			err = new(remoteStorageUtil).uploadOneFile(&meta)
+		}
+		if err != nil {
+			t.Fatalf("sized: %v, err: %v", sized, err)
+		}
+		if meta.resStatus != uploaded {
+			t.Fatalf("sized: %v. the stream should have been uploaded. status: %v", sized, meta.resStatus)
+		}
+		if int64(len(blob)) != encryptedSize(int64(len(contents))) {
+			t.Fatalf("sized: %v. unexpected size of the uploaded blob: %v", sized, len(blob))
+		}
+		if metadata.Get("x-ms-meta-matdesc") == "" {
+			t.Fatalf("sized: %v. the material descriptor should have been sent", sized)
+		}
+		if decrypted := decryptUploadedFile(t, sfe, blob, metadata.Get("x-ms-meta-encryptiondata")); decrypted != contents {
+			t.Fatalf("sized: %v. unexpected contents uploaded. expected: %q, got: %q", sized, contents, decrypted)
+		}
+	}
+}
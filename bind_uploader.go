@@ -3,24 +3,105 @@
 package gosnowflake
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"io"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	bindStageName   = "SYSTEM$BIND"
 	createStageStmt = "CREATE TEMPORARY STAGE " + bindStageName + " file_format=" +
 		"(type=csv field_optionally_enclosed_by='\"')"
-
-	// size (in bytes) of max input stream (10MB default) as per JDBC specs
-	inputStreamBufferSize = 1024 * 1024 * 10
 )
 
+// CSVDialect is the CSV format of the stage files that large array binds are
+// uploaded as. The zero value of a field uses its default.
+type CSVDialect struct {
+	Delimiter rune // FIELD_DELIMITER of the files (default ',')
+	Quote     rune // FIELD_OPTIONALLY_ENCLOSED_BY of the files (default '"')
+	Escape    rune // ESCAPE of the enclosed fields (default none: quotes are doubled)
+}
+
+var defaultCSVDialect = CSVDialect{Delimiter: ',', Quote: '"'}
+
+func (d CSVDialect) withDefaults() CSVDialect {
+	if d.Delimiter == 0 {
+		d.Delimiter = defaultCSVDialect.Delimiter
+	}
+	if d.Quote == 0 {
+		d.Quote = defaultCSVDialect.Quote
+	}
+	return d
+}
+
+func (d CSVDialect) valid() bool {
+	d = d.withDefaults()
+	for _, r := range []rune{d.Delimiter, d.Quote, d.Escape} {
+		if r == '\n' || r == '\r' {
+			return false
+		}
+	}
+	return d.Delimiter != d.Quote && d.Escape != d.Delimiter && d.Escape != d.Quote
+}
+
+// escape encloses value in quotes if it contains special characters
+func (d CSVDialect) escape(value string) string {
+	if value == "" {
+		return string(d.Quote) + string(d.Quote)
+	}
+	if !strings.ContainsAny(value, string([]rune{d.Quote, d.Delimiter, '\n', '\\'})) &&
+		(d.Escape == 0 || !strings.ContainsRune(value, d.Escape)) {
+		return value
+	}
+	var b strings.Builder
+	b.Grow(len(value) + 2)
+	b.WriteRune(d.Quote)
+	for _, r := range value {
+		switch {
+		case d.Escape != 0 && (r == d.Quote || r == d.Escape):
+			b.WriteRune(d.Escape)
+		case d.Escape == 0 && r == d.Quote:
+			b.WriteRune(d.Quote)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteRune(d.Quote)
+	return b.String()
+}
+
+// createStageStatement returns the statement creating the bind stage with a
+// file format parsing the files written in d
+func (d CSVDialect) createStageStatement() string {
+	if d == defaultCSVDialect {
+		return createStageStmt
+	}
+	format := fmt.Sprintf("type=csv field_delimiter=%v field_optionally_enclosed_by=%v",
+		sqlCharLiteral(d.Delimiter), sqlCharLiteral(d.Quote))
+	if d.Escape != 0 {
+		format += " escape=" + sqlCharLiteral(d.Escape)
+	}
+	return "CREATE TEMPORARY STAGE " + bindStageName + " file_format=(" + format + ")"
+}
+
+func sqlCharLiteral(r rune) string {
+	return sqlStringLiteral(string(r))
+}
+
+func sqlStringLiteral(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	return "'" + strings.ReplaceAll(s, "'", "\\'") + "'"
+}
+
 type bindUploader struct {
 	ctx            context.Context
 	sc             *snowflakeConn
@@ -29,16 +110,25 @@ type bindUploader struct {
 	arrayBindStage string
 }
 
+func (bu *bindUploader) csvDialect() CSVDialect {
+	if bu.sc == nil || bu.sc.cfg == nil {
+		return defaultCSVDialect
+	}
+	return bu.sc.cfg.BindCSVDialect.withDefaults()
+}
+
 func (bu *bindUploader) upload(bindings []driver.NamedValue) (*execResponse, error) {
 	bindingRows, _ := bu.buildRowsAsBytes(bindings)
+	chunkSize := bu.sc.cfg.BindUploadChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultBindUploadChunkSize
+	}
 	startIdx := 0
 	numBytes := 0
 	rowNum := 0
-	bu.fileCount = 0
-	var data *execResponse
-	var err error
+	var files []*bytes.Buffer
 	for rowNum < len(bindingRows) {
-		for numBytes < inputStreamBufferSize && rowNum < len(bindingRows) {
+		for numBytes < chunkSize && rowNum < len(bindingRows) {
 			numBytes += len(bindingRows[rowNum])
 			rowNum++
 		}
@@ -48,19 +138,229 @@ func (bu *bindUploader) upload(bindings []driver.NamedValue) (*execResponse, err
 		for i := startIdx; i < rowNum; i++ {
 			b.Write(bindingRows[i])
 		}
+		files = append(files, &b)
+		startIdx = rowNum
+		numBytes = 0
+	}
+	bu.fileCount = len(files)
+	if bu.fileCount == 0 {
+		return nil, nil
+	}
+
+	// the first upload creates the stage and tells how many files can be
+	// uploaded in parallel
+	data, err := bu.uploadStreamInternal(files[0], "0", true)
+	if err != nil {
+		return nil, err
+	}
+	parallel := int(data.Data.Parallel)
+	if parallel < 1 {
+		parallel = 1
+	}
+	var wg sync.WaitGroup
+	var errMutex sync.Mutex
+	next := make(chan int)
+	for w := 0; w < intMin(parallel, bu.fileCount-1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if _, uploadErr := bu.uploadStreamInternal(files[i], strconv.Itoa(i), true); uploadErr != nil {
+					errMutex.Lock()
+					if err == nil {
+						err = uploadErr
+					}
+					errMutex.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 1; i < bu.fileCount; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// CopyOnError is the ON_ERROR option of the COPY run by LoadCSV
+type CopyOnError string
+
+const (
+	// CopyOnErrorAbortStatement aborts the load at the first error
+	CopyOnErrorAbortStatement CopyOnError = "ABORT_STATEMENT"
+	// CopyOnErrorContinue loads the rows without errors
+	CopyOnErrorContinue CopyOnError = "CONTINUE"
+	// CopyOnErrorSkipFile skips the stage files with errors. LoadCSV splits
+	// the data into files of Config.BindUploadChunkSize bytes.
+	CopyOnErrorSkipFile CopyOnError = "SKIP_FILE"
+)
+
+func (o CopyOnError) valid() bool {
+	switch o {
+	case "", CopyOnErrorAbortStatement, CopyOnErrorContinue, CopyOnErrorSkipFile:
+		return true
+	}
+	return false
+}
+
+// CopyOptions configures how LoadCSV parses and loads its data
+type CopyOptions struct {
+	FieldDelimiter string      // FIELD_DELIMITER of the data (default ",")
+	SkipHeader     int         // number of header lines to skip
+	OnError        CopyOnError // ON_ERROR option of the COPY (default CopyOnErrorAbortStatement)
+}
+
+// tableNameRegexp matches a table name, optionally qualified by its database
+// and schema, made of unquoted or double-quoted identifiers
+var tableNameRegexp = regexp.MustCompile(`^(?:[A-Za-z_][A-Za-z0-9_$]*|"(?:[^"]|"")+")` +
+	`(?:\.(?:[A-Za-z_][A-Za-z0-9_$]*|"(?:[^"]|"")+")){0,2}$`)
 
+// LoadCSV uploads CSV data to a temporary stage and loads it into table with
+// COPY INTO, returning the number of rows loaded. The data is streamed to the
+// stage in files of Config.BindUploadChunkSize bytes.
+func (sc *snowflakeConn) LoadCSV(ctx context.Context, table string, data io.Reader, opts CopyOptions) (int64, error) {
+	if !tableNameRegexp.MatchString(table) {
+		return 0, &SnowflakeError{
+			Number:      ErrInvalidLoadCSVArgument,
+			Message:     errMsgInvalidTableName,
+			MessageArgs: []interface{}{table},
+		}
+	}
+	if !opts.OnError.valid() {
+		return 0, &SnowflakeError{
+			Number:      ErrInvalidLoadCSVArgument,
+			Message:     errMsgInvalidCopyOnError,
+			MessageArgs: []interface{}{opts.OnError},
+		}
+	}
+	chunkSize := sc.cfg.BindUploadChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultBindUploadChunkSize
+	}
+	bu := bindUploader{
+		sc:        sc,
+		ctx:       ctx,
+		stagePath: "@" + bindStageName + "/" + UUIDGenerator().String(),
+	}
+	r := bufio.NewReader(data)
+	// the header is skipped here, as the COPY would skip it in every file
+	var header bytes.Buffer
+	for i := 0; i < opts.SkipHeader; i++ {
+		if err := readCSVRecord(r, &header); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	for eof := false; !eof; {
+		var chunk bytes.Buffer
+		for chunk.Len() < chunkSize {
+			if err := readCSVRecord(r, &chunk); err == io.EOF {
+				eof = true
+				break
+			} else if err != nil {
+				return 0, err
+			}
+		}
+		if chunk.Len() == 0 {
+			break
+		}
+		if bu.arrayBindStage == "" {
+			// unlike for the array binds, a failure to create the stage
+			// doesn't stop the session from using it
+			if err := bu.createStage(); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := bu.uploadStreamInternal(&chunk, strconv.Itoa(bu.fileCount), true); err != nil {
+			return 0, err
+		}
 		bu.fileCount++
-		data, err = bu.uploadStreamInternal(&b, true)
+	}
+	if bu.fileCount == 0 {
+		return 0, nil
+	}
+	rows, err := sc.queryContextInternal(ctx, copyIntoStatement(table, bu.stagePath, opts), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	return rowsLoaded(rows)
+}
+
+// readCSVRecord appends the next record of r to b, including the line breaks
+// enclosed in quotes. It returns io.EOF if r has no records left.
+func readCSVRecord(r *bufio.Reader, b *bytes.Buffer) error {
+	quoted := false
+	n := 0
+	for {
+		line, err := r.ReadBytes('\n')
+		b.Write(line)
+		n += len(line)
+		if bytes.Count(line, []byte{'"'})%2 == 1 {
+			quoted = !quoted
+		}
+		if err == io.EOF && n > 0 {
+			return nil
+		}
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if !quoted {
+			return nil
 		}
-		startIdx = rowNum
-		numBytes = 0
 	}
-	return data, nil
 }
 
-func (bu *bindUploader) uploadStreamInternal(inputStream *bytes.Buffer, compressData bool) (*execResponse, error) {
+func copyIntoStatement(table string, stagePath string, opts CopyOptions) string {
+	delimiter := opts.FieldDelimiter
+	if delimiter == "" {
+		delimiter = ","
+	}
+	query := fmt.Sprintf("COPY INTO %v FROM %v FILE_FORMAT=(type=csv field_delimiter=%v "+
+		"field_optionally_enclosed_by='\"') PURGE=TRUE", table, stagePath, sqlStringLiteral(delimiter))
+	if opts.OnError != "" {
+		query += " ON_ERROR=" + string(opts.OnError)
+	}
+	return query
+}
+
+// rowsLoaded sums the rows_loaded column of a COPY INTO result, which has a
+// row per loaded file
+func rowsLoaded(rows driver.Rows) (int64, error) {
+	columns := rows.Columns()
+	idx := -1
+	for i, c := range columns {
+		if strings.EqualFold(c, "rows_loaded") {
+			idx = i
+			break
+		}
+	}
+	var total int64
+	dest := make([]driver.Value, len(columns))
+	for {
+		if err := rows.Next(dest); err == io.EOF {
+			return total, nil
+		} else if err != nil {
+			return total, err
+		}
+		if idx < 0 || dest[idx] == nil {
+			// no files were loaded
+			continue
+		}
+		n, err := strconv.ParseInt(fmt.Sprint(dest[idx]), 10, 64)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+}
+
+func (bu *bindUploader) uploadStreamInternal(inputStream *bytes.Buffer, dstFileName string, compressData bool) (*execResponse, error) {
 	err := bu.createStageIfNeeded()
 	if err != nil {
 		return nil, err
@@ -73,8 +373,8 @@ func (bu *bindUploader) uploadStreamInternal(inputStream *bytes.Buffer, compress
 		}
 	}
 
-	// use a placeholder for source file
-	putCommand := fmt.Sprintf("put 'file:///tmp/placeholder' '%v' overwrite=true", stageName)
+	// use a placeholder for source file, named after the file on the stage
+	putCommand := fmt.Sprintf("put 'file:///tmp/placeholder/%v' '%v' overwrite=true", dstFileName, stageName)
 	// for Windows queries
 	putCommand = strings.ReplaceAll(putCommand, "\\", "\\\\")
 	// prepare context for PUT command
@@ -88,7 +388,24 @@ func (bu *bindUploader) createStageIfNeeded() error {
 	if bu.arrayBindStage != "" {
 		return nil
 	}
-	data, err := bu.sc.exec(bu.ctx, createStageStmt, false, false, false, []driver.NamedValue{})
+	if err := bu.createStage(); err != nil {
+		// stop using the bind stage for the rest of the session. the
+		// parameters are shared with the queries running on the connection.
+		newThreshold := "0"
+		bu.sc.paramsMutex.Lock()
+		bu.sc.cfg.Params[sessionArrayBindStageThreshold] = &newThreshold
+		bu.sc.paramsMutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// createStage creates the temporary stage that the files are uploaded to
+func (bu *bindUploader) createStage() error {
+	data, err := bu.sc.exec(bu.ctx, bu.csvDialect().createStageStatement(), false, false, false, []driver.NamedValue{})
+	if err != nil {
+		return err
+	}
 	if !data.Success {
 		code, err := strconv.Atoi(data.Code)
 		if err != nil {
@@ -97,17 +414,10 @@ func (bu *bindUploader) createStageIfNeeded() error {
 		return &SnowflakeError{
 			Number:   code,
 			SQLState: data.Data.SQLState,
-			Message:  err.Error(),
+			Message:  data.Message,
 			QueryID:  data.Data.QueryID}
 	}
-	if err != nil {
-		return err
-	}
 	bu.arrayBindStage = bindStageName
-	if err != nil {
-		newThreshold := "0"
-		bu.sc.cfg.Params[sessionArrayBindStageThreshold] = &newThreshold
-	}
 	return nil
 }
 
@@ -121,19 +431,26 @@ func (bu *bindUploader) buildRowsAsBytes(columns []driver.NamedValue) ([][]byte,
 		}
 	}
 
-	_, column := snowflakeArrayToString(&columns[0], true)
+	emptyAsNull := bu.sc != nil && bu.sc.cfg != nil && bu.sc.cfg.EmptyStringAsNullInArrays
+	t, column := snowflakeArrayToString(&columns[0], true)
+	if emptyAsNull && t == textType {
+		emptyStringsToNull(column)
+	}
 	numRows := len(column)
 	csvRows := make([][]byte, 0)
-	rows := make([][]string, 0)
+	rows := make([][]*string, 0)
 	for rowIdx := 0; rowIdx < numRows; rowIdx++ {
-		rows = append(rows, make([]string, numColumns))
+		rows = append(rows, make([]*string, numColumns))
 	}
 
 	for rowIdx := 0; rowIdx < numRows; rowIdx++ {
-		rows[rowIdx][0] = *column[rowIdx]
+		rows[rowIdx][0] = column[rowIdx]
 	}
 	for colIdx := 1; colIdx < numColumns; colIdx++ {
-		_, column = snowflakeArrayToString(&columns[colIdx], true)
+		t, column = snowflakeArrayToString(&columns[colIdx], true)
+		if emptyAsNull && t == textType {
+			emptyStringsToNull(column)
+		}
 		iNumRows := len(column)
 		if iNumRows != numRows {
 			return nil, &SnowflakeError{
@@ -143,64 +460,174 @@ func (bu *bindUploader) buildRowsAsBytes(columns []driver.NamedValue) ([][]byte,
 			}
 		}
 		for rowIdx := 0; rowIdx < numRows; rowIdx++ {
-			rows[rowIdx][colIdx] = *column[rowIdx] // length of column = number of rows
+			rows[rowIdx][colIdx] = column[rowIdx] // length of column = number of rows
 		}
 	}
+	dialect := bu.csvDialect()
 	for _, row := range rows {
-		csvRows = append(csvRows, bu.createCSVRecord(row))
+		csvRows = append(csvRows, dialect.createCSVRecord(row))
 	}
 	return csvRows, nil
 }
 
-func (bu *bindUploader) createCSVRecord(data []string) []byte {
+// createCSVRecord writes a nil value as an empty field, which the stage loads
+// as NULL, while empty strings are quoted
+func (d CSVDialect) createCSVRecord(data []*string) []byte {
 	var b strings.Builder
 	b.Grow(1024)
 	for i := 0; i < len(data); i++ {
 		if i > 0 {
-			b.WriteString(",")
+			b.WriteRune(d.Delimiter)
+		}
+		if data[i] != nil {
+			b.WriteString(d.escape(*data[i]))
 		}
-		b.WriteString(escapeForCSV(data[i]))
 	}
 	b.WriteString("\n")
 	return []byte(b.String())
 }
 
-func getBindValues(bindings []driver.NamedValue) (map[string]execBindParameter, error) {
+func getBindValues(bindings []driver.NamedValue, cfg *Config) (map[string]execBindParameter, error) {
+	var serializer BindValueSerializer
+	var emptyAsNull, emptyAsNullInArrays bool
+	if cfg != nil {
+		serializer = cfg.BindValueSerializer
+		emptyAsNull = cfg.EmptyStringAsNull
+		emptyAsNullInArrays = cfg.EmptyStringAsNullInArrays
+	}
 	tsmode := timestampNtzType
+	jsonMode := nullType
 	idx := 1
 	var err error
 	bindValues := make(map[string]execBindParameter, len(bindings))
 	for _, binding := range bindings {
 		t := goTypeToSnowflake(binding.Value, tsmode)
 		if t == changeType {
-			tsmode, err = dataTypeMode(binding.Value)
+			var mode snowflakeType
+			mode, err = dataTypeMode(binding.Value)
 			if err != nil {
 				return nil, err
 			}
+			// the VARIANT and OBJECT types apply to the maps and structs that
+			// follow, the others to the time.Time values
+			if mode == variantType || mode == objectType {
+				jsonMode = mode
+			} else {
+				tsmode = mode
+			}
 		} else {
 			var val interface{}
-			if t == sliceType {
+			isJSON := isJSONBindValue(binding.Value)
+			if isJSON && jsonMode == nullType {
+				return nil, &SnowflakeError{
+					Number:      ErrBindSerialization,
+					Message:     errMsgJSONBindWithoutDataType,
+					MessageArgs: []interface{}{binding.Value},
+				}
+			}
+			if isJSON {
+				var b []byte
+				if b, err = json.Marshal(binding.Value); err != nil {
+					return nil, err
+				}
+				s := string(b)
+				t, val = jsonMode, &s
+			} else if t == sliceType {
 				// retrieve array binding data
-				t, val = snowflakeArrayToString(&binding, false)
+				var arr []*string
+				t, arr = snowflakeArrayToString(&binding, false)
+				if emptyAsNullInArrays && t == textType {
+					emptyStringsToNull(arr)
+				}
+				val = arr
+			} else if s, ok, err := serializeBindValue(serializer, binding.Value, t); err != nil {
+				return nil, err
+			} else if ok {
+				t, val = textType, &s
 			} else {
-				val, err = valueToString(binding.Value, tsmode)
+				var s *string
+				s, err = valueToString(binding.Value, tsmode)
 				if err != nil {
 					return nil, err
 				}
+				if emptyAsNull && t == textType && s != nil && *s == "" {
+					s = nil
+				}
+				val = s
 			}
 			if t == nullType || t == unSupportedType {
 				t = textType // if null or not supported, pass to GS as text
 			}
-			bindValues[strconv.Itoa(idx)] = execBindParameter{
+			// a named parameter is referenced as :name in the SQL text. the
+			// server numbers the ? placeholders on their own, so only the
+			// unnamed parameters take a position.
+			key := binding.Name
+			if key == "" {
+				key = strconv.Itoa(idx)
+				idx++
+			}
+			bindValues[key] = execBindParameter{
 				Type:  t.String(),
 				Value: val,
 			}
-			idx++
 		}
 	}
 	return bindValues, nil
 }
 
+// isJSONBindValue returns true if v is a map or a struct, or a pointer to one,
+// that is bound as JSON after DataTypeVariant or DataTypeObject. Structs that
+// the driver or database/sql already convert, i.e. time.Time and
+// driver.Valuer implementations, are not.
+func isJSONBindValue(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if _, ok := v.(driver.Valuer); ok {
+		return false
+	}
+	if _, ok := v.(time.Time); ok {
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return false
+		}
+		rv = rv.Elem()
+	}
+	return rv.Kind() == reflect.Map || rv.Kind() == reflect.Struct && rv.Type() != reflect.TypeOf(time.Time{})
+}
+
+// checkArrayBindLengths returns an error if the array binds don't all have
+// the same number of values. Columns are numbered from 1 in the order of the
+// bindings, not counting the timestamp type markers.
+func checkArrayBindLengths(bindings []driver.NamedValue) error {
+	firstCol, firstLen := 0, 0
+	col := 0
+	for i := range bindings {
+		if goTypeToSnowflake(bindings[i].Value, timestampNtzType) == changeType {
+			continue // data type marker
+		}
+		v := reflect.ValueOf(bindings[i].Value)
+		col++
+		if !supportedArrayBind(&bindings[i]) || v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+			continue
+		}
+		n := v.Elem().Len()
+		if firstCol == 0 {
+			firstCol, firstLen = col, n
+		} else if n != firstLen {
+			return &SnowflakeError{
+				Number:      ErrBindSerialization,
+				Message:     errMsgArrayBindLengthMismatch,
+				MessageArgs: []interface{}{col, n, firstCol, firstLen},
+			}
+		}
+	}
+	return nil
+}
+
 func arrayBindValueCount(bindValues []driver.NamedValue) int {
 	if !isArrayBind(bindValues) {
 		return 0
@@ -221,6 +648,17 @@ func isArrayBind(bindings []driver.NamedValue) bool {
 	return true
 }
 
+// hasNamedBinding returns true if any of the bindings is a named parameter,
+// which the files uploaded to the bind stage cannot refer to
+func hasNamedBinding(bindings []driver.NamedValue) bool {
+	for _, binding := range bindings {
+		if binding.Name != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func supportedArrayBind(nv *driver.NamedValue) bool {
 	switch reflect.TypeOf(nv.Value) {
 	case reflect.TypeOf(&intArray{}), reflect.TypeOf(&int32Array{}),
@@ -246,3 +684,13 @@ func supportedArrayBind(nv *driver.NamedValue) bool {
 		return false
 	}
 }
+
+// emptyStringsToNull replaces the empty strings of arr with nil, which binds
+// them as NULL
+func emptyStringsToNull(arr []*string) {
+	for i, s := range arr {
+		if s != nil && *s == "" {
+			arr[i] = nil
+		}
+	}
+}
@@ -4,11 +4,25 @@ package gosnowflake
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 const (
@@ -100,8 +114,8 @@ func TestBindingDateTimeTimestamp(t *testing.T) {
 		if !canNull {
 			dbt.Errorf("expected nullable: %v, got: %v", true, canNull)
 		}
-		if columnTypes[0].DatabaseTypeName() != "TIMESTAMP_NTZ" {
-			dbt.Errorf("expected database type: %v, got: %v", "TIMESTAMP_NTZ", columnTypes[0].DatabaseTypeName())
+		if columnTypes[0].DatabaseTypeName() != "TIMESTAMP_NTZ(9)" {
+			dbt.Errorf("expected database type: %v, got: %v", "TIMESTAMP_NTZ(9)", columnTypes[0].DatabaseTypeName())
 		}
 		dbt.mustFailDecimalSize(columnTypes[0])
 		dbt.mustFailLength(columnTypes[0])
@@ -161,6 +175,80 @@ func TestBindingBinary(t *testing.T) {
 	})
 }
 
+type variantBindTest struct {
+	Name string            `json:"name"`
+	Tags map[string]string `json:"tags"`
+}
+
+func TestBindingVariant(t *testing.T) {
+	runTests(t, dsn, func(dbt *DBTest) {
+		dbt.mustExec("CREATE OR REPLACE TABLE varianttest (id int, v variant)")
+		v := variantBindTest{Name: "a", Tags: map[string]string{"k": "v"}}
+		dbt.mustExec("INSERT INTO varianttest(id,v) SELECT 1, ?", DataTypeVariant, v)
+		rows := dbt.mustQuery("SELECT v FROM varianttest WHERE id=?", 1)
+		defer rows.Close()
+		if rows.Next() {
+			var s string
+			if err := rows.Scan(&s); err != nil {
+				dbt.Errorf("failed to scan data. err: %v", err)
+			}
+			var got variantBindTest
+			if err := json.Unmarshal([]byte(s), &got); err != nil {
+				dbt.Errorf("failed to unmarshal %v. err: %v", s, err)
+			}
+			if !reflect.DeepEqual(v, got) {
+				dbt.Errorf("failed to match data. expected: %v, got: %v", v, got)
+			}
+		} else {
+			dbt.Errorf("no data")
+		}
+		dbt.mustExec("DROP TABLE varianttest")
+	})
+}
+
+func TestVariantBindValues(t *testing.T) {
+	tm := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	bindings := []driver.NamedValue{
+		{Ordinal: 1, Value: DataTypeVariant},
+		{Ordinal: 2, Value: variantBindTest{Name: "a", Tags: map[string]string{"k": "v"}}},
+		{Ordinal: 3, Value: DataTypeObject},
+		{Ordinal: 4, Value: map[string]int{"n": 1}},
+		{Ordinal: 5, Value: tm},
+	}
+	sc := &snowflakeConn{}
+	for _, i := range []int{1, 3} {
+		if err := sc.CheckNamedValue(&bindings[i]); err != nil {
+			t.Fatalf("%T should be accepted, err: %v", bindings[i].Value, err)
+		}
+	}
+	bindValues, err := getBindValues(bindings, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := bindValues["1"]; v.Type != variantType.String() || *v.Value.(*string) != `{"name":"a","tags":{"k":"v"}}` {
+		t.Fatalf("expected the struct as a VARIANT, got %v %v", v.Type, *v.Value.(*string))
+	}
+	if v := bindValues["2"]; v.Type != objectType.String() || *v.Value.(*string) != `{"n":1}` {
+		t.Fatalf("expected the map as an OBJECT, got %v %v", v.Type, *v.Value.(*string))
+	}
+	// the timestamp type is unchanged by the markers
+	if v := bindValues["3"]; v.Type != timestampNtzType.String() {
+		t.Fatalf("expected a TIMESTAMP_NTZ, got %v", v.Type)
+	}
+
+	// a map or a struct without a marker is not bound as the text of its %v
+	_, err = getBindValues([]driver.NamedValue{{Ordinal: 1, Value: map[string]string{"k": "v"}}}, nil)
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrBindSerialization {
+		t.Fatalf("a map bound without a marker should fail, got: %v", err)
+	}
+
+	for _, v := range []interface{}{sql.NullString{}, &tm} {
+		if err = sc.CheckNamedValue(&driver.NamedValue{Value: v}); err != driver.ErrSkip {
+			t.Fatalf("%T should be left to database/sql, got: %v", v, err)
+		}
+	}
+}
+
 func TestBindingTimestampTZ(t *testing.T) {
 	runTests(t, dsn, func(dbt *DBTest) {
 		expected := time.Now()
@@ -360,6 +448,116 @@ func testBindingArray(t *testing.T, bulk bool) {
 	createDSN("UTC")
 }
 
+func TestBindingBinaryArray(t *testing.T) {
+	blobs := [][]byte{{0x00, 0x01, 0xfe, 0xff}, nil, {}, bytes.Repeat([]byte{0xab}, 1024)}
+	for _, bulk := range []bool{false, true} {
+		runTests(t, dsn, func(dbt *DBTest) {
+			dbt.mustExec("CREATE OR REPLACE TABLE binarraytest (id int, b binary)")
+			defer dbt.mustExec("DROP TABLE IF EXISTS binarraytest")
+			if bulk {
+				dbt.mustExec("ALTER SESSION SET CLIENT_STAGE_ARRAY_BINDING_THRESHOLD = 1")
+			}
+			ids := []int{0, 1, 2, 3}
+			dbt.mustExec("INSERT INTO binarraytest VALUES (?, ?)", Array(&ids), Array(&blobs))
+			rows := dbt.mustQuery("SELECT b FROM binarraytest ORDER BY id")
+			defer rows.Close()
+			cnt := 0
+			for ; rows.Next(); cnt++ {
+				var b []byte
+				if err := rows.Scan(&b); err != nil {
+					t.Fatal(err)
+				}
+				if (b == nil) != (blobs[cnt] == nil) || !bytes.Equal(b, blobs[cnt]) {
+					t.Fatalf("failed to fetch blob %v. bulk: %v, expected: %v, got: %v", cnt, bulk, blobs[cnt], b)
+				}
+			}
+			if cnt != len(blobs) {
+				t.Fatalf("expected %v rows, got: %v", len(blobs), cnt)
+			}
+		})
+	}
+}
+
+func TestBinaryArrayBindNull(t *testing.T) {
+	blobs := [][]byte{{0x01, 0x02}, nil, {}}
+	bindings := []driver.NamedValue{{Ordinal: 1, Value: Array(&blobs)}}
+	bindValues, err := getBindValues(bindings, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := bindValues["1"]
+	values := v.Value.([]*string)
+	if v.Type != binaryType.String() || len(values) != 3 || *values[0] != "0102" || values[1] != nil || *values[2] != "" {
+		t.Fatalf("unexpected binding of binary values: %v %v", v.Type, values)
+	}
+
+	bu := bindUploader{}
+	csvRows, err := bu.buildRowsAsBytes(bindings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a NULL is an empty field and an empty value a quoted one
+	expected := []string{"0102\n", "\n", "\"\"\n"}
+	for i, row := range csvRows {
+		if string(row) != expected[i] {
+			t.Fatalf("unexpected CSV row %v. expected: %q, got: %q", i, expected[i], row)
+		}
+	}
+}
+
+func TestEmptyStringAsNullBind(t *testing.T) {
+	strs := []string{"a", "", "b"}
+	bindings := []driver.NamedValue{
+		{Ordinal: 1, Value: ""},
+		{Ordinal: 2, Value: "x"},
+		{Ordinal: 3, Value: Array(&strs)},
+	}
+	for _, tc := range []struct {
+		name         string
+		cfg          *Config
+		scalarNull   bool
+		arrayNull    bool
+		expectedRows []string
+	}{
+		{"default", nil, false, false, []string{"a\n", "\"\"\n", "b\n"}},
+		{"scalars", &Config{EmptyStringAsNull: true}, true, false, []string{"a\n", "\"\"\n", "b\n"}},
+		{"arrays", &Config{EmptyStringAsNullInArrays: true}, false, true, []string{"a\n", "\n", "b\n"}},
+		{"both", &Config{EmptyStringAsNull: true, EmptyStringAsNullInArrays: true}, true, true, []string{"a\n", "\n", "b\n"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bindValues, err := getBindValues(bindings, tc.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := bindValues["1"].Value.(*string); (v == nil) != tc.scalarNull || v != nil && *v != "" {
+				t.Fatalf("unexpected binding of an empty string: %v", v)
+			}
+			if v := bindValues["2"].Value.(*string); v == nil || *v != "x" {
+				t.Fatalf("unexpected binding of a string: %v", v)
+			}
+			values := bindValues["3"].Value.([]*string)
+			if len(values) != 3 || *values[0] != "a" || (values[1] == nil) != tc.arrayNull || *values[2] != "b" {
+				t.Fatalf("unexpected binding of an array: %v", values)
+			}
+
+			// arrays bound through a stage follow the same setting
+			bu := bindUploader{sc: &snowflakeConn{cfg: tc.cfg}}
+			csvRows, err := bu.buildRowsAsBytes(bindings[2:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, row := range csvRows {
+				if string(row) != tc.expectedRows[i] {
+					t.Fatalf("unexpected CSV row %v. expected: %q, got: %q", i, tc.expectedRows[i], row)
+				}
+			}
+		})
+	}
+	if strs[1] != "" {
+		t.Fatal("the bound array should not have been changed")
+	}
+}
+
 func TestBulkArrayBinding(t *testing.T) {
 	if runningOnGithubAction() && !runningOnAWS() {
 		t.Skip("skipping non aws environment; safeguard to be removed after azure/gcs put support")
@@ -396,3 +594,460 @@ func TestBulkArrayBinding(t *testing.T) {
 		}
 	})
 }
+
+type isoTimeSerializer struct {
+	calls int
+}
+
+func (s *isoTimeSerializer) SerializeBindValue(v driver.Value, typ string) (string, bool, error) {
+	s.calls++
+	if tm, ok := v.(time.Time); ok {
+		return typ + " " + tm.Format(time.RFC3339Nano), true, nil
+	}
+	return "", false, nil
+}
+
+func TestBindValueSerializer(t *testing.T) {
+	tm := time.Date(2021, 6, 7, 8, 9, 10, 123456789, time.UTC)
+	bindings := []driver.NamedValue{
+		{Ordinal: 1, Value: int64(42)},
+		{Ordinal: 2, Value: DataTypeTimestampLtz},
+		{Ordinal: 3, Value: tm},
+	}
+	serializer := &isoTimeSerializer{}
+	bindValues, err := getBindValues(bindings, &Config{BindValueSerializer: serializer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serializer.calls != 2 {
+		t.Fatalf("expected the serializer to be consulted for 2 values, got %v", serializer.calls)
+	}
+	if v := bindValues["1"]; v.Type != fixedType.String() || *v.Value.(*string) != "42" {
+		t.Fatalf("expected the default conversion for an int64, got %v %v", v.Type, *v.Value.(*string))
+	}
+	expected := "TIMESTAMP_LTZ 2021-06-07T08:09:10.123456789Z"
+	if v := bindValues["2"]; v.Type != textType.String() || *v.Value.(*string) != expected {
+		t.Fatalf("expected the serialized time %v as TEXT, got %v %v", expected, v.Type, *v.Value.(*string))
+	}
+
+	bindValues, err = getBindValues(bindings, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := bindValues["2"]; v.Type != timestampLtzType.String() || *v.Value.(*string) != strconv.FormatInt(tm.UnixNano(), 10) {
+		t.Fatalf("expected the default conversion without a serializer, got %v %v", v.Type, *v.Value.(*string))
+	}
+}
+
+func TestBindingNamed(t *testing.T) {
+	var req execRequest
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		req = execRequest{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		return &execResponse{
+			Data: execResponseData{
+				RowType: []execResponseRowType{{Name: "ID", Type: "fixed"}},
+				RowSet:  [][]*string{},
+			},
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	query := "select id from orders where id = :id or parent_id = :id and status = ?"
+	if _, err := sc.QueryContext(context.Background(), query, []driver.NamedValue{
+		{Name: "id", Ordinal: 1, Value: int64(42)},
+		{Ordinal: 2, Value: "open"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Bindings) != 2 {
+		t.Fatalf("expected 2 bindings, got %v", req.Bindings)
+	}
+	if v, ok := req.Bindings["id"]; !ok || v.Type != fixedType.String() || v.Value != "42" {
+		t.Fatalf("the named parameter should be bound by name. got: %v", req.Bindings)
+	}
+	if v, ok := req.Bindings["1"]; !ok || v.Type != textType.String() || v.Value != "open" {
+		t.Fatalf("the unnamed parameter should be bound as the first positional one. got: %v", req.Bindings)
+	}
+	// named array binds are not uploaded to the bind stage
+	threshold := "1"
+	sc.cfg.Params[sessionArrayBindStageThreshold] = &threshold
+	ids := []int{1, 2, 3}
+	if _, err := sc.ExecContext(context.Background(), "insert into orders (id) values (:id)", []driver.NamedValue{
+		{Name: "id", Ordinal: 1, Value: Array(&ids)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if req.BindStage != "" || len(req.Bindings) != 1 {
+		t.Fatalf("the named array bind should have been sent with the query. stage: %v, bindings: %v", req.BindStage, req.Bindings)
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "loadcsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var queries []string
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		queries = append(queries, req.SQLText)
+		if resp, ok := localStagePutResponse(req, tmpDir); ok {
+			return resp, nil
+		}
+		data := execResponseData{}
+		if strings.HasPrefix(req.SQLText, "COPY INTO") {
+			file1, file2, loaded1, loaded2, status := "0.gz", "1.gz", "2", "1", "LOADED"
+			data = execResponseData{
+				RowType: []execResponseRowType{
+					{Name: "file", Type: "text"},
+					{Name: "status", Type: "text"},
+					{Name: "rows_loaded", Type: "fixed"},
+				},
+				RowSet: [][]*string{{&file1, &status, &loaded1}, {&file2, &status, &loaded2}},
+				Total:  2,
+			}
+		}
+		return &execResponse{Data: data, Code: "0", Success: true}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}, BindUploadChunkSize: 5},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+
+	csv := "id|name\n1|a\n2|\"b\nc\"\n3|d\n"
+	loaded, err := sc.LoadCSV(context.Background(), "test_load", strings.NewReader(csv), CopyOptions{
+		FieldDelimiter: "|",
+		SkipHeader:     1,
+		OnError:        CopyOnErrorContinue,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != 3 {
+		t.Fatalf("expected 3 rows loaded, got: %v", loaded)
+	}
+	if len(queries) != 4 || queries[0] != createStageStmt {
+		t.Fatalf("expected to create the stage, PUT 2 files and COPY. got: %v", queries)
+	}
+	copyQuery := queries[3]
+	for _, clause := range []string{"COPY INTO test_load FROM @" + bindStageName + "/", "field_delimiter='|'", "ON_ERROR=CONTINUE"} {
+		if !strings.Contains(copyQuery, clause) {
+			t.Fatalf("COPY statement should contain %v. got: %v", clause, copyQuery)
+		}
+	}
+	if strings.Contains(copyQuery, "skip_header") {
+		t.Fatalf("the header should be skipped before the upload. got: %v", copyQuery)
+	}
+	// a file ends with the record that fills it, and quoted line breaks
+	// do not end records
+	for i, expected := range []string{"1|a\n2|\"b\nc\"\n", "3|d\n"} {
+		staged, err := ioutil.ReadFile(filepath.Join(tmpDir, strconv.Itoa(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(staged) != expected {
+			t.Fatalf("unexpected content of file %v. expected: %q, got: %q", i, expected, staged)
+		}
+	}
+
+	queries = nil
+	if loaded, err = sc.LoadCSV(context.Background(), "test_load", strings.NewReader("id|name\n"), CopyOptions{SkipHeader: 1}); err != nil || loaded != 0 {
+		t.Fatalf("expected no rows loaded, got: %v, err: %v", loaded, err)
+	}
+	if len(queries) != 0 {
+		t.Fatalf("nothing should be run without data. got: %v", queries)
+	}
+
+	for _, table := range []string{"db.sch.test_load", `"my ""table"""`, `db."sch.x".t`} {
+		if _, err = sc.LoadCSV(context.Background(), table, strings.NewReader(""), CopyOptions{}); err != nil {
+			t.Fatalf("table name %v should be valid. err: %v", table, err)
+		}
+	}
+	for _, table := range []string{"", "test_load; drop table t", "a.b.c.d", `"test_load`, "1table"} {
+		_, err = sc.LoadCSV(context.Background(), table, strings.NewReader(csv), CopyOptions{})
+		if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrInvalidLoadCSVArgument {
+			t.Fatalf("table name %q should be rejected. err: %v", table, err)
+		}
+	}
+	_, err = sc.LoadCSV(context.Background(), "test_load", strings.NewReader(csv), CopyOptions{OnError: "CONTINUE PURGE=FALSE"})
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrInvalidLoadCSVArgument {
+		t.Fatalf("an unknown ON_ERROR option should be rejected. err: %v", err)
+	}
+	if len(queries) != 0 {
+		t.Fatalf("nothing should be run with invalid arguments. got: %v", queries)
+	}
+}
+
+func TestLoadCSVStageCreationFailure(t *testing.T) {
+	stageErr := &SnowflakeError{Number: 3001, Message: "insufficient privileges"}
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		return nil, stageErr
+	}
+	threshold := "65280"
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{sessionArrayBindStageThreshold: &threshold}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	if _, err := sc.LoadCSV(context.Background(), "test_load", strings.NewReader("1,a\n"), CopyOptions{}); err != stageErr {
+		t.Fatalf("expected the error of the stage creation, got: %v", err)
+	}
+	if v := sc.cfg.Params[sessionArrayBindStageThreshold]; v == nil || *v != threshold {
+		t.Fatalf("the array bind threshold should be unchanged, got: %v", v)
+	}
+}
+
+var putPattern = regexp.MustCompile(`^put 'file://(\S+)'`)
+
+// localStagePutResponse answers a PUT of a file stream with a LOCAL_FS stage
+// in stageDir, so the stream is written to stageDir under its name on the
+// stage. ok is false if the query is not a PUT.
+func localStagePutResponse(req execRequest, stageDir string) (resp *execResponse, ok bool) {
+	m := putPattern.FindStringSubmatch(req.SQLText)
+	if m == nil {
+		return nil, false
+	}
+	return &execResponse{
+		Data: execResponseData{
+			Command:           "UPLOAD",
+			SrcLocations:      []string{m[1]},
+			SourceCompression: "auto_detect",
+			StageInfo: execResponseStageInfo{
+				Location:     stageDir,
+				LocationType: "LOCAL_FS",
+			},
+		},
+		Code:    "0",
+		Success: true,
+	}, true
+}
+
+func TestBindUploaderParallelUpload(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "bindupload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	const parallel = 3
+	var inFlight, maxInFlight int32
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		resp, ok := localStagePutResponse(req, tmpDir)
+		if !ok {
+			return &execResponse{Code: "0", Success: true}, nil
+		}
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		resp.Data.Parallel = parallel
+		return resp, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}, BindUploadChunkSize: 1}, // a file per row
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	numRows := 20
+	intArr := make([]int, numRows)
+	strArr := make([]string, numRows)
+	for i := 0; i < numRows; i++ {
+		intArr[i] = i
+		strArr[i] = "test" + strconv.Itoa(i)
+	}
+	uploader := bindUploader{
+		sc:        sc,
+		ctx:       context.Background(),
+		stagePath: "@" + bindStageName + "/" + uuid.New().String(),
+	}
+	if _, err = uploader.upload([]driver.NamedValue{
+		{Ordinal: 1, Value: Array(&intArr)},
+		{Ordinal: 2, Value: Array(&strArr)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if uploader.fileCount != numRows {
+		t.Fatalf("expected %v bind files, got: %v", numRows, uploader.fileCount)
+	}
+	if maxInFlight < 2 || maxInFlight > parallel {
+		t.Fatalf("expected between 2 and %v concurrent uploads, got: %v", parallel, maxInFlight)
+	}
+	for i := 0; i < numRows; i++ {
+		staged, err := ioutil.ReadFile(filepath.Join(tmpDir, strconv.Itoa(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := fmt.Sprintf("%v,test%v\n", i, i)
+		if string(staged) != expected {
+			t.Fatalf("unexpected content of bind file %v. expected: %q, got: %q", i, expected, staged)
+		}
+	}
+}
+
+func TestBindUploaderChunkSize(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "bindupload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		if resp, ok := localStagePutResponse(req, tmpDir); ok {
+			return resp, nil
+		}
+		return &execResponse{Code: "0", Success: true}, nil
+	}
+	sc := &snowflakeConn{
+		// every row is 10 bytes, so a file holds 4 rows
+		cfg:  &Config{Params: map[string]*string{}, BindUploadChunkSize: 35},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	var intArr []int
+	var strArr []string
+	for i := 10; i < 100; i++ {
+		intArr = append(intArr, i)
+		strArr = append(strArr, "test"+strconv.Itoa(i))
+	}
+	uploader := bindUploader{
+		sc:        sc,
+		ctx:       context.Background(),
+		stagePath: "@" + bindStageName + "/" + uuid.New().String(),
+	}
+	if _, err = uploader.upload([]driver.NamedValue{
+		{Ordinal: 1, Value: Array(&intArr)},
+		{Ordinal: 2, Value: Array(&strArr)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if uploader.fileCount != 23 {
+		t.Fatalf("expected 23 bind files, got: %v", uploader.fileCount)
+	}
+
+	cfg := &Config{Account: "a", User: "u", Password: "p"}
+	if err = fillMissingConfigParameters(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BindUploadChunkSize != defaultBindUploadChunkSize {
+		t.Fatalf("expected the default chunk size, got: %v", cfg.BindUploadChunkSize)
+	}
+	cfg = &Config{Account: "a", User: "u", Password: "p", BindUploadChunkSize: -1}
+	if err = fillMissingConfigParameters(cfg); err != ErrInvalidBindUploadChunkSize {
+		t.Fatalf("expected %v, got: %v", ErrInvalidBindUploadChunkSize, err)
+	}
+}
+
+func TestBindCSVDialect(t *testing.T) {
+	var queries []string
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		queries = append(queries, req.SQLText)
+		return &execResponse{Code: "0", Success: true}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}, BindCSVDialect: CSVDialect{Delimiter: '|', Escape: '\\'}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	ids := []int{1, 2, 3, 4}
+	values := []string{"a,b", `say "hi"`, "two\nlines", `back\slash|pipe`}
+	uploader := bindUploader{sc: sc, ctx: context.Background()}
+	csvRows, err := uploader.buildRowsAsBytes([]driver.NamedValue{
+		{Ordinal: 1, Value: Array(&ids)},
+		{Ordinal: 2, Value: Array(&values)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"1|a,b\n",
+		`2|"say \"hi\""` + "\n",
+		"3|\"two\nlines\"\n",
+		`4|"back\\slash|pipe"` + "\n",
+	}
+	for i, row := range csvRows {
+		if string(row) != expected[i] {
+			t.Fatalf("unexpected CSV row %v. expected: %q, got: %q", i, expected[i], row)
+		}
+	}
+
+	if err = uploader.createStageIfNeeded(); err != nil {
+		t.Fatal(err)
+	}
+	expectedStmt := "CREATE TEMPORARY STAGE " + bindStageName +
+		` file_format=(type=csv field_delimiter='|' field_optionally_enclosed_by='"' escape='\\')`
+	if len(queries) != 1 || queries[0] != expectedStmt {
+		t.Fatalf("expected the stage to follow the dialect. expected: %v, got: %v", expectedStmt, queries)
+	}
+
+	cfg := &Config{Account: "a", User: "u", Password: "p", BindCSVDialect: CSVDialect{Delimiter: '"'}}
+	if err = fillMissingConfigParameters(cfg); err != ErrInvalidBindCSVDialect {
+		t.Fatalf("expected %v, got: %v", ErrInvalidBindCSVDialect, err)
+	}
+}
+
+func TestArrayBindLengthMismatch(t *testing.T) {
+	ints := []int{1, 2, 3}
+	strs := []string{"a", "b"}
+	posted := false
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		posted = true
+		return &execResponse{Success: true}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	_, err := sc.ExecContext(context.Background(), "insert into t values (?, ?)", []driver.NamedValue{
+		{Ordinal: 1, Value: Array(&ints)},
+		{Ordinal: 2, Value: Array(&strs)},
+	})
+	driverErr, ok := err.(*SnowflakeError)
+	if !ok || driverErr.Number != ErrBindSerialization {
+		t.Fatalf("should have failed with ErrBindSerialization. err: %v", err)
+	}
+	if expected := "array bind of column 2 has 2 values but the one of column 1 has 3"; !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected the error to contain %q, got: %v", expected, err)
+	}
+	if posted {
+		t.Fatal("the query should not have been submitted")
+	}
+
+	// a binary value counts as a column, unlike a data type marker
+	times := []time.Time{time.Now(), time.Now(), time.Now()}
+	_, err = sc.ExecContext(context.Background(), "insert into t values (?, ?, ?)", []driver.NamedValue{
+		{Ordinal: 1, Value: []byte("abc")},
+		{Ordinal: 2, Value: DataTypeTimestampLtz},
+		{Ordinal: 3, Value: Array(&times, timestampLtzType)},
+		{Ordinal: 4, Value: Array(&strs)},
+	})
+	if expected := "array bind of column 3 has 2 values but the one of column 2 has 3"; err == nil || !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected the error to contain %q, got: %v", expected, err)
+	}
+}
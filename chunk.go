@@ -30,17 +30,40 @@ type largeChunkDecoder struct {
 	ioError error
 }
 
-func decodeLargeChunk(r io.Reader, rowCount int, cellCount int) ([][]*string, error) {
-	logger.Info("custom JSON Decoder")
-	lcd := largeChunkDecoder{
+func newLargeChunkDecoder(r io.Reader, rowCount int, cellCount int) *largeChunkDecoder {
+	return &largeChunkDecoder{
 		r, rowCount, cellCount,
 		0, 0,
 		make([]byte, defaultChunkBufferSize),
 		bytes.NewBuffer(make([]byte, defaultStringBufferSize)),
 		nil,
 	}
+}
 
-	rows, err := lcd.decode()
+func decodeLargeChunk(r io.Reader, rowCount int, cellCount int) ([][]*string, error) {
+	logger.Info("custom JSON Decoder")
+	lcd := newLargeChunkDecoder(r, rowCount, cellCount)
+	rows := make([][]*string, 0, rowCount)
+	err := lcd.decode(func(row []*string) {
+		rows = append(rows, row)
+	})
+	if lcd.ioError != nil && lcd.ioError != io.EOF {
+		return nil, lcd.ioError
+	} else if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// decodeLargeChunkRows decodes a chunk with the custom decoder into the rows of
+// the chunk downloader, without an intermediate [][]*string
+func decodeLargeChunkRows(r io.Reader, rowCount int, cellCount int) ([]chunkRowType, error) {
+	lcd := newLargeChunkDecoder(r, rowCount, cellCount)
+	rows := make([]chunkRowType, 0, rowCount)
+	err := lcd.decode(func(row []*string) {
+		rows = append(rows, chunkRowType{RowSet: row})
+	})
 	if lcd.ioError != nil && lcd.ioError != io.EOF {
 		return nil, lcd.ioError
 	} else if err != nil {
@@ -54,14 +77,14 @@ func (lcd *largeChunkDecoder) mkError(s string) error {
 	return fmt.Errorf("corrupt chunk: %s", s)
 }
 
-func (lcd *largeChunkDecoder) decode() ([][]*string, error) {
+// decode passes each row of the chunk to emit as soon as it is decoded
+func (lcd *largeChunkDecoder) decode(emit func(row []*string)) error {
 	if lcd.nextByteNonWhitespace() != '[' {
-		return nil, lcd.mkError("expected chunk to begin with '['")
+		return lcd.mkError("expected chunk to begin with '['")
 	}
 
-	rows := make([][]*string, 0, lcd.rows)
 	if lcd.nextByteNonWhitespace() == ']' {
-		return rows, nil // special case of an empty chunk
+		return nil // special case of an empty chunk
 	}
 	lcd.rewind(1)
 
@@ -69,20 +92,20 @@ OuterLoop:
 	for {
 		row, err := lcd.decodeRow()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		rows = append(rows, row)
+		emit(row)
 
 		switch c := lcd.nextByteNonWhitespace(); {
 		case c == ',':
 			continue // more elements in the array
 		case c == ']':
-			return rows, nil // we've scanned the whole chunk
+			return nil // we've scanned the whole chunk
 		default:
 			break OuterLoop
 		}
 	}
-	return nil, lcd.mkError("invalid row boundary")
+	return lcd.mkError("invalid row boundary")
 }
 
 func (lcd *largeChunkDecoder) decodeRow() ([]*string, error) {
@@ -29,8 +29,12 @@ type chunkDownloader interface {
 	next() (chunkRowType, error)
 	reset()
 	getChunkMetas() []execResponseChunk
+	getChunkHeaders() map[string]string
 	getQueryResultFormat() resultFormat
 	getRowType() []execResponseRowType
+	getRowSetBase64() string
+	getTotal() int64
+	hasInlineRows() bool
 	setNextChunkDownloader(downloader chunkDownloader)
 	getNextChunkDownloader() chunkDownloader
 }
@@ -51,11 +55,13 @@ type snowflakeChunkDownloader struct {
 	ChunksChan         chan int
 	ChunksError        chan *chunkError
 	ChunksErrorCounter int
+	MaxRetries         int // 0 uses maxChunkDownloaderErrorCounter, a negative value disables retries
 	ChunksFinalErrors  []*chunkError
 	ChunksMutex        *sync.Mutex
 	DoneDownloadCond   *sync.Cond
 	NextDownloader     chunkDownloader
 	Qrmk               string
+	SseCAlgorithm      string
 	QueryResultFormat  string
 	RowSet             rowSetType
 	FuncDownload       func(context.Context, *snowflakeChunkDownloader, int)
@@ -87,6 +93,10 @@ func (scd *snowflakeChunkDownloader) nextResultSet() error {
 }
 
 func (scd *snowflakeChunkDownloader) start() error {
+	if scd.ctx == nil {
+		// the options of the query are read from the context of the chunks
+		scd.ctx = context.Background()
+	}
 	scd.CurrentChunkSize = len(scd.RowSet.JSON) // cache the size
 	scd.CurrentIndex = -1                       // initial chunks idx
 	scd.CurrentChunkIndex = -1                  // initial chunk
@@ -98,7 +108,7 @@ func (scd *snowflakeChunkDownloader) start() error {
 		// if the rowsetbase64 retrieved from the server is empty, move on to downloading chunks
 		var err error
 		firstArrowChunk := buildFirstArrowChunk(scd.RowSet.RowSetBase64)
-		scd.CurrentChunk, err = firstArrowChunk.decodeArrowChunk(scd.RowSet.RowType)
+		scd.CurrentChunk, err = firstArrowChunk.decodeArrowChunk(scd.ctx, scd.RowSet.RowType)
 		scd.CurrentChunkSize = firstArrowChunk.rowCount
 		if err != nil {
 			return err
@@ -137,19 +147,31 @@ func (scd *snowflakeChunkDownloader) schedule() {
 	}
 }
 
-func (scd *snowflakeChunkDownloader) checkErrorRetry() (err error) {
+func (scd *snowflakeChunkDownloader) maxRetries() int {
+	if scd.MaxRetries == 0 {
+		return maxChunkDownloaderErrorCounter
+	}
+	if scd.MaxRetries < 0 {
+		return 0
+	}
+	return scd.MaxRetries
+}
+
+// checkErrorRetry retries the download of a chunk that failed, and returns the
+// error of the chunk once it may not be retried anymore
+func (scd *snowflakeChunkDownloader) checkErrorRetry() *chunkError {
 	select {
 	case errc := <-scd.ChunksError:
-		if scd.ChunksErrorCounter < maxChunkDownloaderErrorCounter && errc.Error != context.Canceled {
+		if scd.ChunksErrorCounter < scd.maxRetries() && errc.Error != context.Canceled {
 			// add the index to the chunks channel so that the download will be retried.
 			go scd.FuncDownload(scd.ctx, scd, errc.Index)
 			scd.ChunksErrorCounter++
 			logger.Warningf("chunk idx: %v, err: %v. retrying (%v/%v)...",
-				errc.Index, errc.Error, scd.ChunksErrorCounter, maxChunkDownloaderErrorCounter)
+				errc.Index, errc.Error, scd.ChunksErrorCounter, scd.maxRetries())
 		} else {
 			scd.ChunksFinalErrors = append(scd.ChunksFinalErrors, errc)
 			logger.Warningf("chunk idx: %v, err: %v. no further retry", errc.Index, errc.Error)
-			return errc.Error
+			return errc
 		}
 	default:
 		logger.Info("no error is detected.")
@@ -161,6 +183,7 @@ func (scd *snowflakeChunkDownloader) next() (chunkRowType, error) {
 	for {
 		scd.CurrentIndex++
 		if scd.CurrentIndex < scd.CurrentChunkSize {
+			scd.TotalRowIndex++
 			return scd.CurrentChunk[scd.CurrentIndex], nil
 		}
 		scd.CurrentChunkIndex++ // next chunk
@@ -178,10 +201,18 @@ func (scd *snowflakeChunkDownloader) next() (chunkRowType, error) {
 			logger.Debugf("waiting for chunk idx: %v/%v",
 				scd.CurrentChunkIndex+1, len(scd.ChunkMetas))
 
-			err := scd.checkErrorRetry()
-			if err != nil {
+			if errc := scd.checkErrorRetry(); errc != nil {
 				scd.ChunksMutex.Unlock()
-				return chunkRowType{}, err
+				if errc.Error == context.Canceled || errc.Error == context.DeadlineExceeded {
+					return chunkRowType{}, errc.Error
+				}
+				// the failed chunk may be a later one than the chunk being read
+				return chunkRowType{}, &SnowflakeError{
+					Number:      ErrFailedToGetChunk,
+					SQLState:    SQLStateConnectionFailure,
+					Message:     errMsgFailedToGetChunkAfterRows,
+					MessageArgs: []interface{}{errc.Index, scd.TotalRowIndex + 1, errc.Error},
+				}
 			}
 
 			// wait for chunk downloader goroutine to broadcast the event,
@@ -190,6 +221,8 @@ func (scd *snowflakeChunkDownloader) next() (chunkRowType, error) {
 		}
 		logger.Debugf("ready: chunk %v", scd.CurrentChunkIndex+1)
 		scd.CurrentChunk = scd.Chunks[scd.CurrentChunkIndex]
+		// the retries are counted until a chunk is downloaded successfully
+		scd.ChunksErrorCounter = 0
 		scd.ChunksMutex.Unlock()
 		scd.CurrentChunkSize = len(scd.CurrentChunk)
 
@@ -213,6 +246,10 @@ func (scd *snowflakeChunkDownloader) getChunkMetas() []execResponseChunk {
 	return scd.ChunkMetas
 }
 
+func (scd *snowflakeChunkDownloader) getChunkHeaders() map[string]string {
+	return chunkRequestHeaders(scd.ChunkHeader, scd.Qrmk, scd.SseCAlgorithm)
+}
+
 func (scd *snowflakeChunkDownloader) getQueryResultFormat() resultFormat {
 	if scd.QueryResultFormat == "arrow" {
 		return arrowFormat
@@ -232,6 +269,18 @@ func (scd *snowflakeChunkDownloader) getRowType() []execResponseRowType {
 	return scd.RowSet.RowType
 }
 
+func (scd *snowflakeChunkDownloader) getRowSetBase64() string {
+	return scd.RowSet.RowSetBase64
+}
+
+func (scd *snowflakeChunkDownloader) getTotal() int64 {
+	return scd.Total
+}
+
+func (scd *snowflakeChunkDownloader) hasInlineRows() bool {
+	return len(scd.RowSet.JSON) > 0 || scd.RowSet.RowSetBase64 != ""
+}
+
 func getChunk(
 	ctx context.Context,
 	scd *snowflakeChunkDownloader,
@@ -243,7 +292,7 @@ func getChunk(
 	if err != nil {
 		return nil, err
 	}
-	return newRetryHTTP(ctx, scd.sc.rest.Client, http.NewRequest, u, headers, timeout).execute()
+	return newRetryHTTP(ctx, scd.sc.rest.Client, http.NewRequest, u, headers, timeout).setObserver(scd.sc.rest.RetryObserver).execute()
 }
 
 /* largeResultSetReader is a reader that wraps the large result set with leading and tailing brackets. */
@@ -263,12 +312,16 @@ func (r *largeResultSetReader) Read(p []byte) (n int, err error) {
 		len, err = r.body.Read(p)
 		if err == io.EOF {
 			r.status = 2
-			return len, nil
-		}
-		if err != nil {
+			if len > 0 {
+				return len, nil
+			}
+			// an empty read ends the data for some decoders, so the tailing
+			// bracket is returned right away
+		} else if err != nil {
 			return 0, err
+		} else {
+			return len, nil
 		}
-		return len, nil
 	}
 	if r.status == 2 {
 		p[0] = 0x5d // tail 0x5d (])
@@ -293,17 +346,7 @@ func downloadChunk(ctx context.Context, scd *snowflakeChunkDownloader, idx int)
 }
 
 func downloadChunkHelper(ctx context.Context, scd *snowflakeChunkDownloader, idx int) error {
-	headers := make(map[string]string)
-	if len(scd.ChunkHeader) > 0 {
-		logger.Debug("chunk header is provided.")
-		for k, v := range scd.ChunkHeader {
-			headers[k] = v
-		}
-	} else {
-		headers[headerSseCAlgorithm] = headerSseCAes
-		headers[headerSseCKey] = scd.Qrmk
-	}
-
+	headers := scd.getChunkHeaders()
 	resp, err := scd.FuncGet(ctx, scd, scd.ChunkMetas[idx].URL, headers, scd.sc.rest.RequestTimeout)
 	if err != nil {
 		return err
@@ -329,22 +372,22 @@ func downloadChunkHelper(ctx context.Context, scd *snowflakeChunkDownloader, idx
 }
 
 func decodeChunk(scd *snowflakeChunkDownloader, idx int, bufStream *bufio.Reader) (err error) {
-	gzipMagic, err := bufStream.Peek(2)
-	if err != nil {
-		return err
-	}
 	start := time.Now()
-	var source io.Reader
-	if gzipMagic[0] == 0x1f && gzipMagic[1] == 0x8b {
-		// detects and uncompresses Gzip format data
-		bufStream0, err := gzip.NewReader(bufStream)
+	var source io.Reader = bufStream
+	if !rawChunksEnabled(scd.ctx) {
+		gzipMagic, err := bufStream.Peek(2)
 		if err != nil {
 			return err
 		}
-		defer bufStream0.Close()
-		source = bufStream0
-	} else {
-		source = bufStream
+		if gzipMagic[0] == 0x1f && gzipMagic[1] == 0x8b {
+			// detects and uncompresses Gzip format data
+			bufStream0, err := gzip.NewReader(bufStream)
+			if err != nil {
+				return err
+			}
+			defer bufStream0.Close()
+			source = bufStream0
+		}
 	}
 	st := &largeResultSetReader{
 		status: 0,
@@ -352,8 +395,13 @@ func decodeChunk(scd *snowflakeChunkDownloader, idx int, bufStream *bufio.Reader
 	}
 	var respd []chunkRowType
 	if scd.getQueryResultFormat() != arrowFormat {
-		var decRespd [][]*string
-		if !CustomJSONDecoderEnabled {
+		if customJSONDecoderEnabled(scd.ctx) {
+			respd, err = decodeLargeChunkRows(st, scd.ChunkMetas[idx].RowCount, scd.CellCount)
+			if err != nil {
+				return err
+			}
+		} else {
+			var decRespd [][]*string
 			dec := json.NewDecoder(st)
 			for {
 				if err := dec.Decode(&decRespd); err == io.EOF {
@@ -362,14 +410,9 @@ func decodeChunk(scd *snowflakeChunkDownloader, idx int, bufStream *bufio.Reader
 					return err
 				}
 			}
-		} else {
-			decRespd, err = decodeLargeChunk(st, scd.ChunkMetas[idx].RowCount, scd.CellCount)
-			if err != nil {
-				return err
-			}
+			respd = make([]chunkRowType, len(decRespd))
+			populateJSONRowSet(respd, decRespd)
 		}
-		respd = make([]chunkRowType, len(decRespd))
-		populateJSONRowSet(respd, decRespd)
 	} else {
 		ipcReader, err := ipc.NewReader(source)
 		if err != nil {
@@ -381,7 +424,7 @@ func decodeChunk(scd *snowflakeChunkDownloader, idx int, bufStream *bufio.Reader
 			int(scd.totalUncompressedSize()),
 			memory.NewGoAllocator(),
 		}
-		respd, err = arc.decodeArrowChunk(scd.RowSet.RowType)
+		respd, err = arc.decodeArrowChunk(scd.ctx, scd.RowSet.RowType)
 		if err != nil {
 			return err
 		}
@@ -399,6 +442,15 @@ func decodeChunk(scd *snowflakeChunkDownloader, idx int, bufStream *bufio.Reader
 	return nil
 }
 
+// sseCAlgorithmOrDefault returns the configured SSE-C algorithm, falling back
+// to AES256 when none is set.
+func sseCAlgorithmOrDefault(algorithm string) string {
+	if algorithm == "" {
+		return headerSseCAes
+	}
+	return algorithm
+}
+
 func populateJSONRowSet(dst []chunkRowType, src [][]*string) {
 	// populate string rowset from src to dst's chunkRowType struct's RowSet field
 	for i, row := range src {
@@ -416,6 +468,7 @@ type streamChunkDownloader struct {
 	ChunkMetas     []execResponseChunk
 	NextDownloader chunkDownloader
 	RowSet         rowSetType
+	inlineRows     bool
 }
 
 func (scd *streamChunkDownloader) totalUncompressedSize() (acc int64) {
@@ -496,6 +549,13 @@ func (scd *streamChunkDownloader) getChunkMetas() []execResponseChunk {
 	return scd.ChunkMetas
 }
 
+func (scd *streamChunkDownloader) getChunkHeaders() map[string]string {
+	if f, ok := scd.fetcher.(*httpStreamChunkFetcher); ok {
+		return chunkRequestHeaders(f.headers, f.qrmk, f.sseCAlgorithm)
+	}
+	return nil
+}
+
 func (scd *streamChunkDownloader) getQueryResultFormat() resultFormat {
 	return jsonFormat
 }
@@ -512,6 +572,19 @@ func (scd *streamChunkDownloader) getRowType() []execResponseRowType {
 	return scd.RowSet.RowType
 }
 
+func (scd *streamChunkDownloader) getRowSetBase64() string {
+	return ""
+}
+
+func (scd *streamChunkDownloader) getTotal() int64 {
+	return scd.Total
+}
+
+func (scd *streamChunkDownloader) hasInlineRows() bool {
+	// RowSet.JSON is released once its rows are sent
+	return scd.inlineRows
+}
+
 func useStreamDownloader(ctx context.Context) bool {
 	val := ctx.Value(streamChunkDownload)
 	if val == nil {
@@ -529,11 +602,13 @@ type streamChunkFetcher interface {
 }
 
 type httpStreamChunkFetcher struct {
-	ctx      context.Context
-	client   *http.Client
-	clientIP net.IP
-	headers  map[string]string
-	qrmk     string
+	ctx           context.Context
+	client        *http.Client
+	clientIP      net.IP
+	headers       map[string]string
+	qrmk          string
+	sseCAlgorithm string
+	raw           bool // see WithRawChunks
 }
 
 func newStreamChunkDownloader(
@@ -552,20 +627,14 @@ func newStreamChunkDownloader(
 		Total:      total,
 		ChunkMetas: chunks,
 		RowSet:     rowSetType{RowType: rowType, JSON: firstRows},
+		inlineRows: len(firstRows) > 0,
 	}
 }
 
 func (f *httpStreamChunkFetcher) fetch(URL string, rows chan<- []*string) error {
-	headers := f.headers
-	if len(headers) == 0 {
-		headers = map[string]string{
-			headerSseCAlgorithm: headerSseCAes,
-			headerSseCKey:       f.qrmk,
-		}
-	}
-
+	headers := chunkRequestHeaders(f.headers, f.qrmk, f.sseCAlgorithm)
 	fullURL, _ := url.Parse(URL)
-	res, err := newRetryHTTP(context.Background(), f.client, http.NewRequest, fullURL, f.headers, 0).execute()
+	res, err := newRetryHTTP(context.Background(), f.client, http.NewRequest, fullURL, headers, 0).execute()
 	if err != nil {
 		return err
 	}
@@ -574,20 +643,22 @@ func (f *httpStreamChunkFetcher) fetch(URL string, rows chan<- []*string) error
 		b, _ := ioutil.ReadAll(res.Body)
 		return fmt.Errorf("status (%d): %s", res.StatusCode, string(b))
 	}
-	if err := copyChunkStream(res.Body, rows); err != nil {
+	if err := copyChunkStream(res.Body, rows, f.raw); err != nil {
 		return fmt.Errorf("read: %w", err)
 	}
 	return nil
 }
 
-func copyChunkStream(body io.Reader, rows chan<- []*string) error {
+// copyChunkStream sends the rows of a JSON chunk to rows. Unless raw is set,
+// gzip compressed chunks are detected and decompressed.
+func copyChunkStream(body io.Reader, rows chan<- []*string, raw bool) error {
 	bufStream := bufio.NewReader(body)
 	gzipMagic, err := bufStream.Peek(2)
 	if err != nil {
 		return err
 	}
 	var source io.Reader
-	if gzipMagic[0] == 0x1f && gzipMagic[1] == 0x8b {
+	if !raw && gzipMagic[0] == 0x1f && gzipMagic[1] == 0x8b {
 		// detect and decompress Gzip format data
 		bufStream0, err := gzip.NewReader(bufStream)
 		if err != nil {
@@ -625,3 +696,20 @@ func copyChunkStream(body io.Reader, rows chan<- []*string) error {
 	}
 	return nil
 }
+
+// chunkRequestHeaders returns the headers to download result chunks with: the
+// chunk headers of the result if the server provided any, and otherwise the
+// SSE-C headers with the query result master key.
+func chunkRequestHeaders(chunkHeaders map[string]string, qrmk string, sseCAlgorithm string) map[string]string {
+	headers := make(map[string]string)
+	if len(chunkHeaders) > 0 {
+		logger.Debug("chunk header is provided.")
+		for k, v := range chunkHeaders {
+			headers[k] = v
+		}
+	} else {
+		headers[headerSseCAlgorithm] = sseCAlgorithmOrDefault(sseCAlgorithm)
+		headers[headerSseCKey] = qrmk
+	}
+	return headers
+}
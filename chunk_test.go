@@ -1,7 +1,9 @@
 package gosnowflake
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"database/sql"
 	"encoding/json"
@@ -9,6 +11,7 @@ import (
 	"io"
 	"math/rand"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -101,7 +104,7 @@ func TestSmallBufferChunkData(t *testing.T) {
 		nil,
 	}
 
-	if _, err := lcd.decode(); err != nil {
+	if err := lcd.decode(func([]*string) {}); err != nil {
 		t.Fatalf("failed with small buffer: %s", err)
 	}
 }
@@ -283,7 +286,7 @@ func TestCopyChunkStream(t *testing.T) {
 
 	r := strings.NewReader(`["foo","bar",null],["bar",null,"foo"],[]`)
 	c := make(chan []*string, 3)
-	err := copyChunkStream(r, c)
+	err := copyChunkStream(r, c, false)
 	if err != nil {
 		t.Fatalf("error while copying chunk stream. err: %v", err)
 	}
@@ -292,6 +295,23 @@ func TestCopyChunkStream(t *testing.T) {
 	assertEqualRows([]*string{}, <-c)
 }
 
+func TestCopyChunkStreamRaw(t *testing.T) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write([]byte(`["foo"]`))
+	w.Close()
+
+	foo := "foo"
+	c := make(chan []*string, 1)
+	if err := copyChunkStream(bytes.NewReader(b.Bytes()), c, false); err != nil {
+		t.Fatalf("error while copying chunk stream. err: %v", err)
+	}
+	assertEqualRows([]*string{&foo}, <-c)
+	if err := copyChunkStream(bytes.NewReader(b.Bytes()), c, true); err == nil {
+		t.Fatal("a raw chunk should not be decompressed")
+	}
+}
+
 func TestCopyChunkStreamInvalid(t *testing.T) {
 	var r io.Reader
 	var c chan []*string
@@ -299,21 +319,21 @@ func TestCopyChunkStreamInvalid(t *testing.T) {
 
 	r = strings.NewReader("oops")
 	c = make(chan []*string, 1)
-	err = copyChunkStream(r, c)
+	err = copyChunkStream(r, c, false)
 	if err == nil {
 		t.Fatalf("should fail to retrieve data. err: %v", err)
 	}
 
 	r = strings.NewReader(`[["foo"], ["bar"]]`)
 	c = make(chan []*string, 1)
-	err = copyChunkStream(r, c)
+	err = copyChunkStream(r, c, false)
 	if err == nil {
 		t.Fatalf("should fail to retrieve data. err: %v", err)
 	}
 
 	r = strings.NewReader(`{"foo": "bar"}`)
 	c = make(chan []*string, 1)
-	err = copyChunkStream(r, c)
+	err = copyChunkStream(r, c, false)
 	if err == nil {
 		t.Fatalf("should fail to retrieve data. err: %v", err)
 	}
@@ -398,3 +418,68 @@ func TestWithStreamDownloader(t *testing.T) {
 		t.Errorf("number of rows didn't match. expected: %v, got: %v", numrows, cnt)
 	}
 }
+
+// syntheticJSONChunk returns a JSON result chunk, without the enclosing
+// brackets, of rows with an integer and a long string
+func syntheticJSONChunk(numRows int) []byte {
+	var b bytes.Buffer
+	value := strings.Repeat("x", 200)
+	for i := 0; i < numRows; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `["%v","%v",null]`, i, value)
+	}
+	return b.Bytes()
+}
+
+func decodeSyntheticChunk(ctx context.Context, chunk []byte, numRows int) (*snowflakeChunkDownloader, error) {
+	scd := &snowflakeChunkDownloader{
+		ctx:         ctx,
+		CellCount:   3,
+		ChunkMetas:  []execResponseChunk{{RowCount: numRows, UncompressedSize: int64(len(chunk))}},
+		Chunks:      make(map[int][]chunkRowType),
+		ChunksMutex: &sync.Mutex{},
+	}
+	return scd, decodeChunk(scd, 0, bufio.NewReader(bytes.NewReader(chunk)))
+}
+
+func TestDecodeChunkCustomJSONDecoder(t *testing.T) {
+	numRows := 20000
+	chunk := syntheticJSONChunk(numRows)
+	for _, ctx := range []context.Context{context.Background(), WithCustomJSONDecoder(context.Background())} {
+		scd, err := decodeSyntheticChunk(ctx, chunk, numRows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows := scd.Chunks[0]
+		if len(rows) != numRows {
+			t.Fatalf("expected %v rows, got: %v", numRows, len(rows))
+		}
+		if *rows[42].RowSet[0] != "42" || len(*rows[42].RowSet[1]) != 200 || rows[42].RowSet[2] != nil {
+			t.Fatalf("unexpected row: %v", rows[42].RowSet)
+		}
+	}
+}
+
+func BenchmarkDecodeJSONChunk(b *testing.B) {
+	numRows := 20000
+	chunk := syntheticJSONChunk(numRows)
+	for _, bc := range []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"Unmarshal", context.Background()},
+		{"Custom", WithCustomJSONDecoder(context.Background())},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(chunk)))
+			for i := 0; i < b.N; i++ {
+				if _, err := decodeSyntheticChunk(bc.ctx, chunk, numRows); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -7,17 +7,23 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
 	"github.com/google/uuid"
 )
 
@@ -48,6 +54,7 @@ const (
 	sessionClientValidateDefaultParameters = "CLIENT_VALIDATE_DEFAULT_PARAMETERS"
 	sessionArrayBindStageThreshold         = "client_stage_array_binding_threshold"
 	serviceName                            = "service_name"
+	sessionGoQueryResultFormat             = "go_query_result_format"
 )
 
 type resultType string
@@ -74,6 +81,10 @@ type snowflakeConn struct {
 	QueryID         string
 	SQLState        string
 	internal        InternalClient
+	paramsMutex     sync.Mutex // guards the session state updated by concurrent execs
+	// monitoringForbidden is set once the monitoring endpoints have refused
+	// the session, so that they aren't requested again
+	monitoringForbidden uint32
 }
 
 var queryIDPattern = `[\w\-_]+`
@@ -91,7 +102,8 @@ func (sc *snowflakeConn) isDml(v int64) bool {
 // isMultiStmt returns true if the statement type code is of type multistatement
 // Note that the statement type code is also equivalent to type INSERT, so an additional check of the name is required
 func (sc *snowflakeConn) isMultiStmt(data *execResponseData) bool {
-	return data.StatementTypeID == statementTypeIDMulti && data.RowType[0].Name == "multiple statement execution"
+	return data.StatementTypeID == statementTypeIDMulti && len(data.RowType) > 0 &&
+		data.RowType[0].Name == "multiple statement execution"
 }
 
 func (sc *snowflakeConn) exec(
@@ -103,6 +115,11 @@ func (sc *snowflakeConn) exec(
 	bindings []driver.NamedValue) (
 	*execResponse, error) {
 	var err error
+	if sc.cfg.SQLRewriter != nil && !isInternal {
+		if query, err = sc.cfg.SQLRewriter(ctx, query); err != nil {
+			return nil, err
+		}
+	}
 	counter := atomic.AddUint64(&sc.SequenceCounter, 1) // query sequence counter
 
 	req := execRequest{
@@ -118,14 +135,32 @@ func (sc *snowflakeConn) exec(
 	}
 	if tag := ctx.Value(queryTag); tag != nil {
 		req.Parameters[string(queryTag)] = tag
+	} else if sc.cfg.DefaultQueryTag != "" {
+		req.Parameters[string(queryTag)] = sc.cfg.DefaultQueryTag
+	}
+	if id := getTraceID(ctx); id != "" {
+		tag, _ := req.Parameters[string(queryTag)].(string)
+		req.Parameters[string(queryTag)] = queryTagWithTraceID(tag, id)
+	}
+	if isServerResultCacheDisabled(ctx) {
+		req.Parameters[useCachedResult] = false
+	}
+	if timeout := ctx.Value(statementTimeout); timeout != nil {
+		req.Parameters[string(statementTimeout)] = timeout
+	}
+	if limit := getRowLimit(ctx); limit > 0 {
+		req.Parameters[string(rowLimit)] = limit
 	}
 	logger.WithContext(ctx).Infof("parameters: %v", req.Parameters)
 
 	requestID := getOrGenerateRequestIDFromContext(ctx)
 	if len(bindings) > 0 {
+		if err = checkArrayBindLengths(bindings); err != nil {
+			return nil, err
+		}
 		arrayBindThreshold := sc.getArrayBindStageThreshold()
 		numBinds := arrayBindValueCount(bindings)
-		if 0 < arrayBindThreshold && arrayBindThreshold <= numBinds && !describeOnly && isArrayBind(bindings) {
+		if 0 < arrayBindThreshold && arrayBindThreshold <= numBinds && !describeOnly && isArrayBind(bindings) && !hasNamedBinding(bindings) {
 			// bulk array insert binding
 			uploader := bindUploader{
 				sc:        sc,
@@ -137,7 +172,7 @@ func (sc *snowflakeConn) exec(
 			req.BindStage = uploader.stagePath
 		} else {
 			// variable or array binding
-			req.Bindings, err = getBindValues(bindings)
+			req.Bindings, err = getBindValues(bindings, sc.cfg)
 			if err != nil {
 				return nil, err
 			}
@@ -150,7 +185,7 @@ func (sc *snowflakeConn) exec(
 	if isFileTransfer(query) {
 		headers[httpHeaderAccept] = headerContentTypeApplicationJSON
 	}
-	if serviceName, ok := sc.cfg.Params[serviceName]; ok {
+	if serviceName, ok := sc.getParam(serviceName); ok {
 		headers[httpHeaderServiceName] = *serviceName
 	}
 
@@ -190,7 +225,12 @@ func (sc *snowflakeConn) exec(
 			command: query,
 			options: new(SnowflakeFileTransferOptions),
 		}
-		if fs := getFileStream(ctx); fs != nil {
+		if r, size, ok := getSizedFileStream(ctx); ok {
+			// a sized stream is uploaded as it is read, so it is never compressed
+			sfa.sourceReader = r
+			sfa.sourceReaderSize = size
+			sfa.data.AutoCompress = false
+		} else if fs := getFileStream(ctx); fs != nil {
 			sfa.sourceStream = fs
 			if isInternal {
 				sfa.data.AutoCompress = false
@@ -212,12 +252,17 @@ func (sc *snowflakeConn) exec(
 	}
 
 	logger.WithContext(ctx).Info("Exec/Query SUCCESS")
+	sc.paramsMutex.Lock()
 	sc.cfg.Database = data.Data.FinalDatabaseName
 	sc.cfg.Schema = data.Data.FinalSchemaName
 	sc.cfg.Role = data.Data.FinalRoleName
 	sc.cfg.Warehouse = data.Data.FinalWarehouseName
 	sc.QueryID = data.Data.QueryID
 	sc.SQLState = data.Data.SQLState
+	sc.paramsMutex.Unlock()
+	if sc.cfg.QueryIDObserver != nil {
+		sc.cfg.QueryIDObserver(data.Data.QueryID, query)
+	}
 	sc.populateSessionParameters(data.Data.Parameters)
 	return data, err
 }
@@ -232,6 +277,14 @@ func (sc *snowflakeConn) monitoring(qid string, runtime time.Duration) (*QueryMo
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
+	return sc.GetQueryMonitoringData(ctx, qid)
+}
+
+// GetQueryMonitoringData fetches the monitoring data of the query qid, such as
+// the warehouse and cluster that executed it. Unlike the Monitoring method of
+// a result, it fetches the data regardless of how long the query ran. It
+// returns nil if the server has no data for the query.
+func (sc *snowflakeConn) GetQueryMonitoringData(ctx context.Context, qid string) (*QueryMonitoringData, error) {
 	var m monitoringResponse
 	err := sc.getMonitoringResult(ctx, qid, &m)
 	if err != nil {
@@ -245,6 +298,27 @@ func (sc *snowflakeConn) monitoring(qid string, runtime time.Duration) (*QueryMo
 	return &m.Data.Queries[0], nil
 }
 
+func (sc *snowflakeConn) queryGraph(qid string, runtime time.Duration) (*QueryGraphData, error) {
+	// Exit early if this was a "fast" query
+	if runtime < FetchQueryMonitoringDataThreshold {
+		return nil, nil
+	}
+
+	// Bound the GET request to 1 second in the absolute worst case.
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var g queryGraphResponse
+	err := sc.getMonitoringResultAt(ctx, fmt.Sprintf("/monitoring/query-plan-data/%s", qid), &g)
+	if err != nil {
+		return nil, err
+	}
+	if !g.Success {
+		return nil, nil
+	}
+	return &g.Data, nil
+}
+
 func (sc *snowflakeConn) Begin() (driver.Tx, error) {
 	return sc.BeginTx(sc.ctx, driver.TxOptions{})
 }
@@ -285,6 +359,7 @@ func (sc *snowflakeConn) cleanup() {
 func (sc *snowflakeConn) Close() (err error) {
 	logger.WithContext(sc.ctx).Infoln("Close")
 	sc.stopHeartBeat()
+	sc.stopTokenRenewal()
 
 	if !sc.cfg.KeepSessionAlive {
 		err = sc.rest.FuncCloseSession(sc.ctx, sc.rest, sc.rest.RequestTimeout)
@@ -352,8 +427,9 @@ func (sc *snowflakeConn) ExecContext(ctx context.Context, query string, args []d
 		return nil, err
 	}
 
-	// if async exec, return result object right away
-	if noResult {
+	// if async exec or still in progress when the submit policy stopped
+	// waiting, return result object right away
+	if noResult || data.Data.AsyncResult != nil {
 		return data.Data.AsyncResult, nil
 	}
 
@@ -367,20 +443,28 @@ func (sc *snowflakeConn) ExecContext(ctx context.Context, query string, args []d
 		rows := &snowflakeResult{
 			affectedRows: updatedRows,
 			insertID:     -1,
-			queryID:      sc.QueryID,
+			queryID:      data.Data.QueryID,
 		} // last insert id is not supported by Snowflake
-		if m, err := sc.monitoring(sc.QueryID, time.Since(qStart)); err == nil {
+		if m, err := sc.monitoring(data.Data.QueryID, time.Since(qStart)); err == nil {
 			rows.monitoring = m
+		} else {
+			rows.monitoringErr = err
 		}
+		rows.sc = sc
+		rows.runtime = time.Since(qStart)
 		return rows, nil
 	} else if sc.isMultiStmt(&data.Data) {
 		rows, err := sc.handleMultiExec(ctx, data.Data)
 		if err != nil {
 			return nil, err
 		}
-		if m, err := sc.monitoring(sc.QueryID, time.Since(qStart)); err == nil {
+		if m, err := sc.monitoring(data.Data.QueryID, time.Since(qStart)); err == nil {
 			rows.monitoring = m
+		} else {
+			rows.monitoringErr = err
 		}
+		rows.sc = sc
+		rows.runtime = time.Since(qStart)
 		return rows, nil
 	}
 	logger.Debug("DDL")
@@ -434,18 +518,34 @@ func (sc *snowflakeConn) queryContextInternal(ctx context.Context, query string,
 		return nil, err
 	}
 
-	// if async query, return row object right away
-	if noResult {
+	// if async query or still in progress when the submit policy stopped
+	// waiting, return row object right away
+	if noResult || data.Data.AsyncRows != nil {
 		return data.Data.AsyncRows, nil
 	}
 
+	if arrowRequired(ctx) && !sc.isMultiStmt(&data.Data) && resultFormat(data.Data.QueryResultFormat) != arrowFormat {
+		return nil, &SnowflakeError{
+			Number:      ErrNotArrowResult,
+			Message:     errMsgNotArrowResult,
+			MessageArgs: []interface{}{data.Data.QueryResultFormat},
+			QueryID:     data.Data.QueryID,
+		}
+	}
+
 	rows := new(snowflakeRows)
 	rows.sc = sc
-	rows.queryID = sc.QueryID
+	rows.ctx = ctx
+	// the query ID of the connection may already be the one of a concurrent query
+	rows.queryID = data.Data.QueryID
+	rows.version = data.Data.Version
 
-	if m, err := sc.monitoring(sc.QueryID, time.Since(qStart)); err == nil {
+	if m, err := sc.monitoring(data.Data.QueryID, time.Since(qStart)); err == nil {
 		rows.monitoring = m
+	} else {
+		rows.monitoringErr = err
 	}
+	rows.runtime = time.Since(qStart)
 
 	if sc.isMultiStmt(&data.Data) {
 		// handleMultiQuery is responsible to fill rows with childResults
@@ -454,6 +554,16 @@ func (sc *snowflakeConn) queryContextInternal(ctx context.Context, query string,
 			return nil, err
 		}
 	} else {
+		if err = validateResultData(&data.Data); err != nil {
+			return nil, err
+		}
+		rows.truncated = rows.truncated || isResultTruncated(&data.Data)
+		if sc.isArrowRequested() && resultFormat(data.Data.QueryResultFormat) != arrowFormat {
+			logger.WithContext(ctx).Warnf(
+				"GO_QUERY_RESULT_FORMAT is ARROW but the result of query %v is in the %v format. "+
+					"use WithRequireArrow to fail such queries", data.Data.QueryID, data.Data.QueryResultFormat)
+			rows.arrowIgnored = true
+		}
 		rows.addDownloader(populateChunkDownloader(ctx, sc, data.Data))
 	}
 
@@ -494,7 +604,7 @@ func (sc *snowflakeConn) Ping(ctx context.Context) error {
 // CheckNamedValue determines which types are handled by this driver aside from
 // the instances captured by driver.Value
 func (sc *snowflakeConn) CheckNamedValue(nv *driver.NamedValue) error {
-	if supported := supportedArrayBind(nv); !supported {
+	if supported := supportedArrayBind(nv) || isJSONBindValue(nv.Value); !supported {
 		return driver.ErrSkip
 	}
 	return nil
@@ -503,6 +613,8 @@ func (sc *snowflakeConn) CheckNamedValue(nv *driver.NamedValue) error {
 func (sc *snowflakeConn) populateSessionParameters(parameters []nameValueParameter) {
 	// other session parameters (not all)
 	logger.WithContext(sc.ctx).Infof("params: %#v", parameters)
+	changed := make(map[string]string)
+	sc.paramsMutex.Lock()
 	for _, param := range parameters {
 		v := ""
 		switch param.Value.(type) {
@@ -524,12 +636,36 @@ func (sc *snowflakeConn) populateSessionParameters(parameters []nameValueParamet
 			}
 		}
 		logger.Debugf("parameter. name: %v, value: %v", param.Name, v)
-		sc.cfg.Params[strings.ToLower(param.Name)] = &v
+		name := strings.ToLower(param.Name)
+		if old, ok := sc.cfg.Params[name]; !ok || old == nil || *old != v {
+			changed[name] = v
+		}
+		sc.cfg.Params[name] = &v
+	}
+	sc.paramsMutex.Unlock()
+	// the callback may run queries of its own, so it is called without the lock
+	if len(changed) > 0 && sc.cfg.OnSessionParametersChanged != nil {
+		sc.cfg.OnSessionParametersChanged(changed)
 	}
 }
 
+// getParam returns a session parameter without racing concurrent updates
+func (sc *snowflakeConn) getParam(name string) (*string, bool) {
+	sc.paramsMutex.Lock()
+	defer sc.paramsMutex.Unlock()
+	v, ok := sc.cfg.Params[name]
+	return v, ok
+}
+
+// isArrowRequested returns true if the session asks for results in the arrow
+// format, which the server may not honor
+func (sc *snowflakeConn) isArrowRequested() bool {
+	v, ok := sc.getParam(sessionGoQueryResultFormat)
+	return ok && v != nil && strings.EqualFold(*v, string(arrowFormat))
+}
+
 func (sc *snowflakeConn) isClientSessionKeepAliveEnabled() bool {
-	v, ok := sc.cfg.Params[sessionClientSessionKeepAlive]
+	v, ok := sc.getParam(sessionClientSessionKeepAlive)
 	if !ok {
 		return false
 	}
@@ -537,7 +673,7 @@ func (sc *snowflakeConn) isClientSessionKeepAliveEnabled() bool {
 }
 
 func (sc *snowflakeConn) getArrayBindStageThreshold() int {
-	v, ok := sc.cfg.Params[sessionArrayBindStageThreshold]
+	v, ok := sc.getParam(sessionArrayBindStageThreshold)
 	if !ok {
 		return 0
 	}
@@ -549,25 +685,54 @@ func (sc *snowflakeConn) getArrayBindStageThreshold() int {
 }
 
 func (sc *snowflakeConn) startHeartBeat() {
-	if !sc.isClientSessionKeepAliveEnabled() {
+	if !sc.isHeartBeatEnabled() {
 		return
 	}
 	sc.rest.HeartBeat = &heartbeat{
-		restful: sc.rest,
+		restful:  sc.rest,
+		interval: sc.cfg.HeartbeatInterval,
 	}
 	sc.rest.HeartBeat.start()
 }
 
+func (sc *snowflakeConn) isHeartBeatEnabled() bool {
+	if sc.cfg.HeartbeatInterval != 0 {
+		return sc.cfg.HeartbeatInterval > 0
+	}
+	return sc.isClientSessionKeepAliveEnabled()
+}
+
 func (sc *snowflakeConn) stopHeartBeat() {
-	if !sc.isClientSessionKeepAliveEnabled() {
+	if sc.rest == nil || sc.rest.HeartBeat == nil {
 		return
 	}
 	sc.rest.HeartBeat.stop()
+	sc.rest.HeartBeat = nil
+}
+
+func (sc *snowflakeConn) startTokenRenewal() {
+	if !sc.cfg.ProactiveTokenRenewal {
+		return
+	}
+	sc.rest.TokenRenewal = &tokenRenewal{restful: sc.rest}
+	sc.rest.TokenRenewal.start()
+}
+
+func (sc *snowflakeConn) stopTokenRenewal() {
+	if sc.rest == nil || sc.rest.TokenRenewal == nil {
+		return
+	}
+	sc.rest.TokenRenewal.stop()
+	sc.rest.TokenRenewal = nil
 }
 
 func (sc *snowflakeConn) handleMultiExec(ctx context.Context, data execResponseData) (*snowflakeResult, error) {
 	var updatedRows int64
+	if data.ResultIDs == "" {
+		return nil, noResultIDsError(data)
+	}
 	childResults := getChildResults(data.ResultIDs, data.ResultTypes)
+	childStats := make([]ChildResultStat, 0, len(childResults))
 	for _, child := range childResults {
 		resultPath := fmt.Sprintf(urlQueriesResultFmt, child.id)
 		childData, err := sc.getQueryResultResp(ctx, resultPath)
@@ -604,40 +769,102 @@ func (sc *snowflakeConn) handleMultiExec(ctx context.Context, data execResponseD
 				return nil, err
 			}
 			updatedRows += count
+			childStats = append(childStats, ChildResultStat{QueryID: child.id, Rows: count})
+		} else {
+			childStats = append(childStats, ChildResultStat{QueryID: child.id, Rows: childData.Data.Total})
 		}
 	}
 	logger.WithContext(ctx).Infof("number of updated rows: %#v", updatedRows)
 	return &snowflakeResult{
 		affectedRows: updatedRows,
 		insertID:     -1,
-		queryID:      sc.QueryID,
+		queryID:      data.QueryID,
+		childStats:   childStats,
+		sc:           sc,
 	}, nil
 }
 
 // Fill the correspondent rows and add chunk downloader into the rows when iterate the childResults
 func (sc *snowflakeConn) handleMultiQuery(ctx context.Context, data execResponseData, rows *snowflakeRows) error {
+	if data.ResultIDs == "" {
+		return noResultIDsError(data)
+	}
 	childResults := getChildResults(data.ResultIDs, data.ResultTypes)
 
 	for _, child := range childResults {
-		err := sc.rowsForRunningQuery(ctx, child.id, rows)
+		total, err := sc.rowsForRunningQuery(ctx, child.id, rows)
 		if err != nil {
 			return err
 		}
+		rows.childStats = append(rows.childStats, ChildResultStat{QueryID: child.id, Rows: total})
 	}
 	return nil
 }
 
+func noResultIDsError(data execResponseData) error {
+	return &SnowflakeError{
+		Number:   ErrNoResultIDs,
+		SQLState: data.SQLState,
+		Message:  errMsgNoResultIDs,
+		QueryID:  data.QueryID,
+	}
+}
+
+// fetchChildScanBytes looks up the bytes scanned by the child queries of a
+// multi-statement query in their monitoring data. They are left at zero for
+// the children whose data cannot be fetched.
+func (sc *snowflakeConn) fetchChildScanBytes(stats []ChildResultStat) {
+	for i := range stats {
+		// Bound each GET request to 1 second in the absolute worst case.
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		var m monitoringResponse
+		err := sc.getMonitoringResult(ctx, stats[i].QueryID, &m)
+		cancel()
+		if err != nil {
+			logger.Warnf("failed to get monitoring data of child query %v. err: %v", stats[i].QueryID, err)
+			continue
+		}
+		if len(m.Data.Queries) == 1 {
+			stats[i].ScanBytes = m.Data.Queries[0].Stats[monitoringStatScanBytes]
+		}
+	}
+}
+
 func setResultType(ctx context.Context, resType resultType) context.Context {
 	return context.WithValue(ctx, snowflakeResultType, resType)
 }
 
+// getSubmitPolicy returns the SubmitPolicy of the query in ctx, falling back
+// to the one of cfg and then to the default one
+func getSubmitPolicy(ctx context.Context, cfg *Config) SubmitPolicy {
+	if policy, ok := ctx.Value(submitPolicy).(SubmitPolicy); ok && policy != nil {
+		return policy
+	}
+	if cfg != nil && cfg.SubmitPolicy != nil {
+		return cfg.SubmitPolicy
+	}
+	return defaultSubmitPolicy
+}
+
 func getResultType(ctx context.Context) resultType {
 	return ctx.Value(snowflakeResultType).(resultType)
 }
 
+// updateRows sums the row counts of a DML result, which has one column per
+// count, e.g. the rows inserted, updated and deleted by a MERGE or the rows
+// inserted into each table by a multi-table insert
 func updateRows(data execResponseData) (int64, error) {
+	if len(data.RowSet) == 0 && data.RowSetBase64 != "" {
+		return updateRowsFromArrow(data)
+	}
 	var count int64
+	if len(data.RowSet) == 0 {
+		return count, nil
+	}
 	for i, n := 0, len(data.RowType); i < n; i++ {
+		if data.RowSet[0][i] == nil {
+			continue
+		}
 		v, err := strconv.ParseInt(*data.RowSet[0][i], 10, 64)
 		if err != nil {
 			return -1, err
@@ -647,6 +874,40 @@ func updateRows(data execResponseData) (int64, error) {
 	return count, nil
 }
 
+// updateRowsFromArrow is updateRows for a result in the arrow format
+func updateRowsFromArrow(data execResponseData) (int64, error) {
+	b, err := base64.StdEncoding.DecodeString(data.RowSetBase64)
+	if err != nil {
+		return -1, err
+	}
+	rr, err := ipc.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return -1, err
+	}
+	defer rr.Release()
+	chunk := arrowResultChunk{*rr, 0, 0, memory.NewGoAllocator()}
+	rows, err := chunk.decodeArrowChunk(context.Background(), data.RowType)
+	if err != nil {
+		return -1, err
+	}
+	var count int64
+	if len(rows) == 0 {
+		return count, nil
+	}
+	for _, v := range rows[0].ArrowRow {
+		switch n := v.(type) {
+		case nil:
+		case int64:
+			count += n
+		case *big.Int:
+			count += n.Int64()
+		default:
+			return -1, fmt.Errorf("unexpected row count %v of type %T", v, v)
+		}
+	}
+	return count, nil
+}
+
 type childResult struct {
 	id  string
 	typ string
@@ -667,13 +928,13 @@ func getChildResults(IDs string, types string) []childResult {
 
 func (sc *snowflakeConn) getQueryResultResp(ctx context.Context, resultPath string) (*execResponse, error) {
 	headers := getHeaders()
-	if serviceName, ok := sc.cfg.Params[serviceName]; ok {
+	if serviceName, ok := sc.getParam(serviceName); ok {
 		headers[httpHeaderServiceName] = *serviceName
 	}
 	param := make(url.Values)
 	param.Add(requestIDKey, getOrGenerateRequestIDFromContext(ctx).String())
 	param.Add("clientStartTime", strconv.FormatInt(time.Now().Unix(), 10))
-	param.Add(requestGUIDKey, uuid.New().String())
+	param.Add(requestGUIDKey, UUIDGenerator().String())
 	token, _, _ := sc.rest.TokenAccessor.GetTokens()
 	if token != "" {
 		headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, token)
@@ -697,13 +958,25 @@ func (sc *snowflakeConn) getQueryResultResp(ctx context.Context, resultPath stri
 // deserializes it into the provided res (which is given as a generic interface
 // to allow different callers to request different views on the raw response)
 func (sc *snowflakeConn) getMonitoringResult(ctx context.Context, qid string, res interface{}) error {
+	return sc.getMonitoringResultAt(ctx, fmt.Sprintf("/monitoring/queries/%s", qid), res)
+}
+
+// getMonitoringResultAt fetches the monitoring resource at resultPath and
+// deserializes it into the provided res
+func (sc *snowflakeConn) getMonitoringResultAt(ctx context.Context, resultPath string, res interface{}) error {
+	if atomic.LoadUint32(&sc.monitoringForbidden) == 1 {
+		return &SnowflakeError{
+			Number:      ErrMonitoringUnavailable,
+			Message:     errMsgMonitoringUnavailable,
+			MessageArgs: []interface{}{http.StatusForbidden, resultPath},
+		}
+	}
 	headers := make(map[string]string)
 	param := make(url.Values)
-	param.Add(requestGUIDKey, uuid.New().String())
+	param.Add(requestGUIDKey, UUIDGenerator().String())
 	if tok, _, _ := sc.rest.TokenAccessor.GetTokens(); tok != "" {
 		headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, tok)
 	}
-	resultPath := fmt.Sprintf("/monitoring/queries/%s", qid)
 	url := sc.rest.getFullURL(resultPath, &param)
 
 	resp, err := sc.rest.FuncGet(ctx, sc.rest, url, headers, sc.rest.RequestTimeout)
@@ -711,6 +984,18 @@ func (sc *snowflakeConn) getMonitoringResult(ctx context.Context, qid string, re
 		logger.WithContext(ctx).Errorf("failed to get response. err: %v", err)
 		return err
 	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden {
+		// the role may not read monitoring data, which won't change during
+		// the session
+		logger.WithContext(ctx).Warnf("monitoring data is unavailable. HTTP: %v, URL: %v", resp.StatusCode, resultPath)
+		atomic.StoreUint32(&sc.monitoringForbidden, 1)
+		return &SnowflakeError{
+			Number:      ErrMonitoringUnavailable,
+			Message:     errMsgMonitoringUnavailable,
+			MessageArgs: []interface{}{resp.StatusCode, resultPath},
+		}
+	}
 
 	err = json.NewDecoder(resp.Body).Decode(res)
 	if err != nil {
@@ -721,6 +1006,53 @@ func (sc *snowflakeConn) getMonitoringResult(ctx context.Context, qid string, re
 	return nil
 }
 
+// WaitForQueryCompletion blocks until the query qid has finished, checking
+// its status at exponentially growing intervals. It returns an error if the
+// query failed or ctx is done first.
+func (sc *snowflakeConn) WaitForQueryCompletion(ctx context.Context, qid string) error {
+	return sc.pollQueryCompletion(ctx, qid, defaultBackoffConfig, nil)
+}
+
+// WaitForQueryCompletionWithProgress is WaitForQueryCompletion that calls
+// progress with the status of the query after each check, including the
+// last one, so that the bytes scanned and rows produced so far can be shown.
+func (sc *snowflakeConn) WaitForQueryCompletionWithProgress(ctx context.Context, qid string, progress func(status *SnowflakeQueryStatus)) error {
+	return sc.pollQueryCompletion(ctx, qid, defaultBackoffConfig, progress)
+}
+
+// pollQueryCompletion checks the status of a query until it is no longer
+// running, waiting between checks with exponential backoff and jitter. A
+// query without a status, e.g. right after it was submitted, is checked
+// again until the interval reaches its maximum. If progress isn't nil, it is
+// called with the status of every check.
+func (sc *snowflakeConn) pollQueryCompletion(ctx context.Context, qid string, backoff backoffConfig, progress func(status *SnowflakeQueryStatus)) error {
+	for attempt := 0; ; attempt++ {
+		queryRet, err := sc.fetchSettledQueryStatus(ctx, qid)
+		if err == nil {
+			if progress != nil {
+				progress(newSnowflakeQueryStatus(qid, queryRet))
+			}
+			err = queryStatusError(qid, queryRet)
+		}
+		if err == nil {
+			return nil
+		}
+		se, ok := err.(*SnowflakeError)
+		if !ok {
+			return err
+		}
+		noStatus := se.Number == ErrQueryStatus && se.Message == errMsgNoQueryStatus
+		if se.Number != ErrQueryIsRunning && !(noStatus && backoff.interval(attempt) < backoff.max) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff.jittered(attempt)):
+		}
+	}
+}
+
 // checkQueryStatus return error==nil means the query completed successfully and there is complete query result to fetch.
 // when the GS could not return a status (when a query was just submitted GS might not be able to return a status)
 // an ErrQueryStatus will be returned.
@@ -732,20 +1064,34 @@ func (sc *snowflakeConn) getMonitoringResult(ctx context.Context, qid string, re
 // 3, ErrQueryIsRunning, if the requested query is still running and might have complete result later, these statuses
 // were listed in query.sfqueryStatusRunning
 func (sc *snowflakeConn) checkQueryStatus(ctx context.Context, qid string) error {
-	var statusResp statusResponse
-
-	err := sc.getMonitoringResult(ctx, qid, &statusResp)
+	queryRet, err := sc.fetchSettledQueryStatus(ctx, qid)
 	if err != nil {
 		return err
 	}
-	if !statusResp.Success || len(statusResp.Data.Queries) == 0 {
-		logger.WithContext(ctx).Errorf("status query returned not-success or no status returned.")
-		return &SnowflakeError{
-			Number:  ErrQueryStatus,
-			Message: "status query returned not-success or no status returned. Please retry"}
+	return queryStatusError(qid, queryRet)
+}
+
+// fetchSettledQueryStatus gets the status of a query like fetchQueryStatus.
+// NO_DATA right after submission usually means the monitoring record isn't
+// available yet, so it is checked again a few times before it is returned.
+func (sc *snowflakeConn) fetchSettledQueryStatus(ctx context.Context, qid string) (*retStatus, error) {
+	queryRet, err := sc.fetchQueryStatus(ctx, qid)
+	for attempt := 0; err == nil && attempt < noDataRetryAttempts &&
+		strToSFQueryStatus(queryRet.Status) == SFQueryNoData; attempt++ {
+		logger.WithContext(ctx).Debugf("no status data for query %v yet. retrying", qid)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(noDataRetryInterval):
+		}
+		queryRet, err = sc.fetchQueryStatus(ctx, qid)
 	}
+	return queryRet, err
+}
 
-	var queryRet = statusResp.Data.Queries[0]
+// queryStatusError returns the error checkQueryStatus returns for the status
+// queryRet of the query qid
+func queryStatusError(qid string, queryRet *retStatus) error {
 	if queryRet.ErrorCode != 0 {
 		return &SnowflakeError{
 			Number: ErrQueryStatus,
@@ -782,8 +1128,38 @@ func (sc *snowflakeConn) checkQueryStatus(ctx context.Context, qid string) error
 	return nil
 }
 
+// GetQueryStatus returns the status of the query qid with a single request,
+// without waiting for the query to complete. Unlike WaitForQueryCompletion,
+// a query that is still running isn't an error; its Phase tells whether it
+// is executing, queued, waiting for its warehouse or blocked by another
+// statement.
+func (sc *snowflakeConn) GetQueryStatus(ctx context.Context, qid string) (*SnowflakeQueryStatus, error) {
+	queryRet, err := sc.fetchQueryStatus(ctx, qid)
+	if err != nil {
+		return nil, err
+	}
+	return newSnowflakeQueryStatus(qid, queryRet), nil
+}
+
+// fetchQueryStatus gets the status of a query from the monitoring endpoint
+func (sc *snowflakeConn) fetchQueryStatus(ctx context.Context, qid string) (*retStatus, error) {
+	var statusResp statusResponse
+
+	err := sc.getMonitoringResult(ctx, qid, &statusResp)
+	if err != nil {
+		return nil, err
+	}
+	if !statusResp.Success || len(statusResp.Data.Queries) == 0 {
+		logger.WithContext(ctx).Errorf("status query returned not-success or no status returned.")
+		return nil, &SnowflakeError{
+			Number:  ErrQueryStatus,
+			Message: errMsgNoQueryStatus}
+	}
+	return &statusResp.Data.Queries[0], nil
+}
+
 // Fetch query result for a query id from /queries/<qid>/result endpoint.
-func (sc *snowflakeConn) rowsForRunningQuery(ctx context.Context, qid string, rows *snowflakeRows) error {
+func (sc *snowflakeConn) rowsForRunningQuery(ctx context.Context, qid string, rows *snowflakeRows) (int64, error) {
 	resultPath := fmt.Sprintf(urlQueriesResultFmt, qid)
 	resp, err := sc.getQueryResultResp(ctx, resultPath)
 	if err != nil {
@@ -791,26 +1167,41 @@ func (sc *snowflakeConn) rowsForRunningQuery(ctx context.Context, qid string, ro
 		if resp != nil {
 			code, err := strconv.Atoi(resp.Code)
 			if err != nil {
-				return err
+				return 0, err
 			}
-			return &SnowflakeError{
+			return 0, &SnowflakeError{
 				Number:   code,
 				SQLState: resp.Data.SQLState,
 				Message:  err.Error(),
 				QueryID:  resp.Data.QueryID}
 		}
-		return err
+		return 0, err
+	}
+	if rows.version == 0 {
+		rows.version = resp.Data.Version
+	}
+	if resp.Data.ResultIDs != "" {
+		// the result of a multi-statement query chains those of its statements
+		if err = sc.handleMultiQuery(ctx, resp.Data, rows); err != nil {
+			return 0, err
+		}
+		return resp.Data.Total, nil
+	}
+	if err = validateResultData(&resp.Data); err != nil {
+		return 0, err
 	}
+	rows.truncated = rows.truncated || isResultTruncated(&resp.Data)
 	rows.addDownloader(populateChunkDownloader(ctx, sc, resp.Data))
-	return nil
+	return resp.Data.Total, nil
 }
 
 // prepare a Rows object to return for query of 'qid'
 func (sc *snowflakeConn) buildRowsForRunningQuery(ctx context.Context, qid string) (driver.Rows, error) {
 	rows := new(snowflakeRows)
 	rows.sc = sc
+	rows.ctx = ctx
 	rows.queryID = qid
-	err := sc.rowsForRunningQuery(ctx, qid, rows)
+	_, err := sc.rowsForRunningQuery(ctx, qid, rows)
 	if err != nil {
 		return nil, err
 	}
@@ -869,29 +1260,56 @@ func getAsync(
 		sfError.QueryID = rows.queryID
 	}
 	defer close(errChannel)
-	token, _, _ := sr.TokenAccessor.GetTokens()
-	headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, token)
-	resp, err := sr.FuncGet(ctx, sr, URL, headers, timeout)
-	if err != nil {
-		logger.WithContext(ctx).Errorf("failed to get response. err: %v", err)
-		sfError.Message = err.Error()
-		errChannel <- sfError
-		close(errChannel)
-		return
-	}
-	if resp.Body != nil {
-		defer resp.Body.Close()
-	}
-
-	respd := execResponse{}
-	err = json.NewDecoder(resp.Body).Decode(&respd)
-	resp.Body.Close()
-	if err != nil {
-		logger.WithContext(ctx).Errorf("failed to decode JSON. err: %v", err)
-		sfError.Message = err.Error()
-		errChannel <- sfError
-		close(errChannel)
-		return
+	defer sr.untrackRunningQuery(sfError.QueryID)
+	var respd execResponse
+	var err error
+	// poll the result until the query is no longer in progress
+	for attempt := 0; ; attempt++ {
+		token, _, _ := sr.TokenAccessor.GetTokens()
+		headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, token)
+		var resp *http.Response
+		pollStart := time.Now()
+		resp, err = sr.FuncGet(ctx, sr, URL, headers, timeout)
+		if err != nil {
+			logger.WithContext(ctx).Errorf("failed to get response. err: %v", err)
+			sfError.Message = err.Error()
+			errChannel <- sfError
+			return
+		}
+		respd = execResponse{}
+		err = json.NewDecoder(resp.Body).Decode(&respd)
+		resp.Body.Close()
+		if err != nil {
+			logger.WithContext(ctx).Errorf("failed to decode JSON. err: %v", err)
+			sfError.Message = err.Error()
+			errChannel <- sfError
+			return
+		}
+		if respd.Code == sessionExpiredCode {
+			if err = sr.renewExpiredSessionToken(ctx, timeout, token); err != nil {
+				sfError.Message = err.Error()
+				errChannel <- sfError
+				return
+			}
+			continue
+		}
+		if respd.Code != queryInProgressCode && respd.Code != queryInProgressAsyncCode {
+			break
+		}
+		if respd.Data.GetResultURL != "" {
+			URL = sr.getFullURL(respd.Data.GetResultURL, nil)
+		}
+		wait := asyncPollBackoff.jittered(attempt) - time.Since(pollStart)
+		if wait <= 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			sfError.Message = ctx.Err().Error()
+			errChannel <- sfError
+			return
+		case <-time.After(wait):
+		}
 	}
 
 	sc := &snowflakeConn{rest: sr, cfg: cfg}
@@ -904,29 +1322,32 @@ func getAsync(
 				r, err := sc.handleMultiExec(ctx, respd.Data)
 				if err != nil {
 					res.errChannel <- err
-					close(errChannel)
 					return
 				}
 				res.affectedRows, err = r.RowsAffected()
 				if err != nil {
 					res.errChannel <- err
-					close(errChannel)
 					return
 				}
 			}
-			res.queryID = respd.Data.QueryID
+			// the query ID was set when the query was submitted, and is read
+			// by GetQueryID while the result is retrieved
 			res.errChannel <- nil // mark exec status complete
 		} else {
 			rows.sc = sc
-			rows.queryID = respd.Data.QueryID
+			rows.version = respd.Data.Version
 			if sc.isMultiStmt(&respd.Data) {
 				err = sc.handleMultiQuery(ctx, respd.Data, rows)
 				if err != nil {
 					rows.errChannel <- err
-					close(errChannel)
 					return
 				}
 			} else {
+				if err = validateResultData(&respd.Data); err != nil {
+					rows.errChannel <- err
+					return
+				}
+				rows.truncated = rows.truncated || isResultTruncated(&respd.Data)
 				rows.addDownloader(populateChunkDownloader(ctx, sc, respd.Data))
 			}
 			rows.ChunkDownloader.start()
@@ -971,6 +1392,18 @@ func getFileStream(ctx context.Context) *bytes.Buffer {
 	return buf
 }
 
+func getSizedFileStream(ctx context.Context) (io.Reader, int64, bool) {
+	size, ok := ctx.Value(fileStreamSize).(int64)
+	if !ok || size < 0 {
+		return nil, 0, false
+	}
+	r, ok := ctx.Value(fileStreamFile).(io.Reader)
+	if !ok {
+		return nil, 0, false
+	}
+	return r, size, true
+}
+
 func getFileTransferOptions(ctx context.Context) *SnowflakeFileTransferOptions {
 	v := ctx.Value(fileTransferOptions)
 	if v == nil {
@@ -992,16 +1425,147 @@ func isDescribeOnly(ctx context.Context) bool {
 	return ok && d
 }
 
+func getTraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceID).(string)
+	return id
+}
+
+// queryTagWithTraceID adds a trace ID to a query tag, turning it into a JSON
+// object if it isn't one already
+func queryTagWithTraceID(tag string, id string) string {
+	var fields map[string]interface{}
+	if tag != "" && (json.Unmarshal([]byte(tag), &fields) != nil || fields == nil) {
+		fields = map[string]interface{}{queryTagFieldQueryTag: tag}
+	}
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+	fields[queryTagFieldTraceID] = id
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return tag
+	}
+	return string(b)
+}
+
+func isServerResultCacheDisabled(ctx context.Context) bool {
+	v := ctx.Value(disableServerResultCache)
+	if v == nil {
+		return false
+	}
+	d, ok := v.(bool)
+	return ok && d
+}
+
+func decimal128Enabled(ctx context.Context) bool {
+	v := ctx.Value(decimal128Mode)
+	if v == nil {
+		return false
+	}
+	d, ok := v.(bool)
+	return ok && d
+}
+
+func rawChunksEnabled(ctx context.Context) bool {
+	v := ctx.Value(rawChunks)
+	if v == nil {
+		return false
+	}
+	d, ok := v.(bool)
+	return ok && d
+}
+
+// getRowLimit returns the row limit set with WithRowLimit, or 0 if none is
+func getRowLimit(ctx context.Context) int64 {
+	if ctx == nil {
+		return 0
+	}
+	n, _ := ctx.Value(rowLimit).(int64)
+	return n
+}
+
+func chunkPanicsRecovered(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	v := ctx.Value(recoverChunkPanics)
+	if v == nil {
+		return false
+	}
+	d, ok := v.(bool)
+	return ok && d
+}
+
+func arrowRequired(ctx context.Context) bool {
+	v := ctx.Value(requireArrow)
+	if v == nil {
+		return false
+	}
+	d, ok := v.(bool)
+	return ok && d
+}
+
+func customJSONDecoderEnabled(ctx context.Context) bool {
+	if CustomJSONDecoderEnabled {
+		return true
+	}
+	v := ctx.Value(customJSONDecoder)
+	if v == nil {
+		return false
+	}
+	d, ok := v.(bool)
+	return ok && d
+}
+
+func rawTimestampsEnabled(ctx context.Context) bool {
+	v := ctx.Value(rawTimestampMode)
+	if v == nil {
+		return false
+	}
+	d, ok := v.(bool)
+	return ok && d
+}
+
+func getArrowSchemaTimestampUnit(ctx context.Context) arrow.TimeUnit {
+	if unit, ok := ctx.Value(arrowSchemaTimestampUnit).(arrow.TimeUnit); ok {
+		return unit
+	}
+	return arrow.Nanosecond
+}
+
+// isResultTruncated reports whether the server returned fewer rows than the
+// query produced, e.g. because the session set ROWS_PER_RESULTSET
+func isResultTruncated(data *execResponseData) bool {
+	return data.Returned > 0 && data.Returned < data.Total
+}
+
+// validateResultData fails a result that reports rows without carrying any
+// source to read them from, instead of silently returning empty rows
+func validateResultData(data *execResponseData) error {
+	if data.Total > 0 && len(data.RowSet) == 0 && data.RowSetBase64 == "" && len(data.Chunks) == 0 {
+		return &SnowflakeError{
+			Number:      ErrMissingResultData,
+			SQLState:    data.SQLState,
+			QueryID:     data.QueryID,
+			Message:     errMsgMissingResultData,
+			MessageArgs: []interface{}{data.Total},
+		}
+	}
+	return nil
+}
+
 // returns snowflake chunk downloader by default or stream based chunk
 // downloader if option provided through context
 func populateChunkDownloader(ctx context.Context, sc *snowflakeConn, data execResponseData) chunkDownloader {
 	if useStreamDownloader(ctx) {
 		fetcher := &httpStreamChunkFetcher{
-			ctx:      ctx,
-			client:   sc.rest.Client,
-			clientIP: sc.cfg.ClientIP,
-			headers:  data.ChunkHeaders,
-			qrmk:     data.Qrmk,
+			ctx:           ctx,
+			client:        sc.rest.Client,
+			clientIP:      sc.cfg.ClientIP,
+			headers:       data.ChunkHeaders,
+			qrmk:          data.Qrmk,
+			sseCAlgorithm: sc.cfg.SseCAlgorithm,
+			raw:           rawChunksEnabled(ctx),
 		}
 		return newStreamChunkDownloader(ctx, fetcher, data.Total, data.RowType, data.RowSet, data.Chunks)
 	}
@@ -1015,8 +1579,10 @@ func populateChunkDownloader(ctx context.Context, sc *snowflakeConn, data execRe
 		TotalRowIndex:      int64(-1),
 		CellCount:          len(data.RowType),
 		Qrmk:               data.Qrmk,
+		SseCAlgorithm:      sc.cfg.SseCAlgorithm,
 		QueryResultFormat:  data.QueryResultFormat,
 		ChunkHeader:        data.ChunkHeaders,
+		MaxRetries:         sc.cfg.MaxChunkDownloadRetries,
 		FuncDownload:       downloadChunk,
 		FuncDownloadHelper: downloadChunkHelper,
 		FuncGet:            getChunk,
@@ -1036,13 +1602,16 @@ func buildSnowflakeConn(ctx context.Context, config Config) (*snowflakeConn, err
 	}
 	var st http.RoundTripper = SnowflakeTransport
 	if sc.cfg.Transporter == nil {
+		if sc.cfg.OCSPFailClosed && sc.cfg.InsecureMode {
+			return nil, ErrInvalidOCSPMode
+		}
 		if sc.cfg.InsecureMode {
 			// no revocation check with OCSP. Think twice when you want to enable this option.
 			st = snowflakeInsecureTransport
 		} else {
 			// set OCSP fail open mode
 			ocspResponseCacheLock.Lock()
-			atomic.StoreUint32((*uint32)(&ocspFailOpen), uint32(sc.cfg.OCSPFailOpen))
+			atomic.StoreUint32((*uint32)(&ocspFailOpen), uint32(sc.cfg.ocspFailOpenMode()))
 			ocspResponseCacheLock.Unlock()
 		}
 	} else {
@@ -1068,6 +1637,7 @@ func buildSnowflakeConn(ctx context.Context, config Config) (*snowflakeConn, err
 		TokenAccessor:       tokenAccessor,
 		LoginTimeout:        sc.cfg.LoginTimeout,
 		RequestTimeout:      sc.cfg.RequestTimeout,
+		RetryObserver:       sc.cfg.RetryObserver,
 		FuncPost:            postRestful,
 		FuncGet:             getRestful,
 		FuncPostQuery:       postRestfulQuery,
@@ -1079,6 +1649,7 @@ func buildSnowflakeConn(ctx context.Context, config Config) (*snowflakeConn, err
 		FuncPostAuthSAML:    postAuthSAML,
 		FuncPostAuthOKTA:    postAuthOKTA,
 		FuncGetSSO:          getSSO,
+		Connection:          sc,
 	}
 	return sc, nil
 }
@@ -1103,3 +1674,132 @@ func (sc *snowflakeConn) FetchResult(ctx context.Context, qid string) (driver.Ro
 type ResultFetcher interface {
 	FetchResult(ctx context.Context, qid string) (driver.Rows, error)
 }
+
+// CancelQuery aborts a previously issued query, given the snowflake
+// query-id. This is useful for queries submitted in async mode which
+// the client no longer needs, so the warehouse can be freed early.
+// A query still running on the connection is aborted by the request it
+// was submitted with, any other with SYSTEM$CANCEL_QUERY.
+//
+// See the QueryCanceller interface.
+func (sc *snowflakeConn) CancelQuery(ctx context.Context, qid string) error {
+	logger.WithContext(ctx).Infof("CancelQuery: %v", qid)
+	if sc.rest == nil {
+		return driver.ErrBadConn
+	}
+	if _, err := uuid.Parse(qid); err != nil {
+		return &SnowflakeError{
+			Number:  ErrQueryIDFormat,
+			Message: "Invalid QID",
+			QueryID: qid,
+		}
+	}
+	if requestID, ok := sc.rest.runningQueryRequestID(qid); ok {
+		return sc.rest.FuncCancelQuery(ctx, sc.rest, requestID, sc.rest.RequestTimeout)
+	}
+	bindings := []driver.NamedValue{{Ordinal: 1, Value: qid}}
+	_, err := sc.exec(ctx, cancelQueryByIDStmt, false /* noResult */, false /* isInternal */, false /* describeOnly */, bindings)
+	return err
+}
+
+// cancelQueryByIDStmt cancels a query that was not submitted through the
+// connection, or has completed
+const cancelQueryByIDStmt = "SELECT SYSTEM$CANCEL_QUERY(?)"
+
+// QueryCanceller is an interface which allows a running query to be
+// cancelled given the corresponding snowflake query-id.
+//
+// The raw gosnowflake connection implements this interface.
+type QueryCanceller interface {
+	CancelQuery(ctx context.Context, qid string) error
+}
+
+// LastQueryID returns the query-id of the query that most recently completed
+// on the connection, or an empty string if none has. Queries running
+// concurrently on the connection overwrite it in the order they complete.
+//
+// See the QueryIDProvider interface.
+func (sc *snowflakeConn) LastQueryID() string {
+	sc.paramsMutex.Lock()
+	defer sc.paramsMutex.Unlock()
+	return sc.QueryID
+}
+
+// QueryIDProvider is an interface which allows the snowflake query-id of
+// the last query run on a connection to be retrieved, e.g. after an Exec.
+//
+// The raw gosnowflake connection implements this interface.
+type QueryIDProvider interface {
+	LastQueryID() string
+}
+
+// pingWarehouseQuery needs compute to run, unlike SELECT 1, and RANDOM keeps it
+// from being answered from the result cache.
+const pingWarehouseQuery = "SELECT RANDOM() FROM TABLE(GENERATOR(ROWCOUNT => 1))"
+
+// sqlStateCannotConnectNow is the SQL state of the errors returned when the
+// warehouse of the session cannot run queries.
+const sqlStateCannotConnectNow = "57P03"
+
+// PingWarehouse verifies that the warehouse of the session can execute queries.
+// Ping only verifies the session, and succeeds even if no warehouse is selected
+// or the warehouse is suspended and does not auto-resume. In that case
+// PingWarehouse returns a SnowflakeError with ErrCodeWarehouseUnavailable.
+//
+// See the WarehousePinger interface.
+func (sc *snowflakeConn) PingWarehouse(ctx context.Context) error {
+	logger.WithContext(ctx).Infoln("PingWarehouse")
+	if sc.rest == nil {
+		return driver.ErrBadConn
+	}
+	_, err := sc.exec(ctx, pingWarehouseQuery, false /* noResult */, false /* isInternal */, false /* describeOnly */, []driver.NamedValue{})
+	if err == nil {
+		return nil
+	}
+	se, ok := err.(*SnowflakeError)
+	if !ok || (se.Number != ErrNoActiveWarehouse && se.SQLState != sqlStateCannotConnectNow) {
+		return err
+	}
+	sc.paramsMutex.Lock()
+	warehouse := sc.cfg.Warehouse
+	sc.paramsMutex.Unlock()
+	if warehouse == "" {
+		warehouse = "<none>"
+	}
+	return &SnowflakeError{
+		Number:      ErrCodeWarehouseUnavailable,
+		SQLState:    se.SQLState,
+		QueryID:     se.QueryID,
+		Message:     errMsgWarehouseUnavailable,
+		MessageArgs: []interface{}{warehouse, se.Message},
+	}
+}
+
+// WarehousePinger is an interface which allows the availability of the
+// warehouse of a connection to be verified, beyond the session.
+//
+// The raw gosnowflake connection implements this interface.
+type WarehousePinger interface {
+	PingWarehouse(ctx context.Context) error
+}
+
+// SessionID returns the ID of the Snowflake session of the connection, e.g. to
+// correlate logs, without exposing its tokens. It returns -1 if the connection
+// is not authenticated.
+//
+// See the SessionIDProvider interface.
+func (sc *snowflakeConn) SessionID() int64 {
+	if sc.rest == nil || sc.rest.TokenAccessor == nil {
+		return -1
+	}
+	_, _, sessionID := sc.rest.TokenAccessor.GetTokens()
+	return sessionID
+}
+
+// SessionIDProvider is an interface which allows the ID of the Snowflake
+// session of a connection to be retrieved.
+//
+// The raw gosnowflake connection implements this interface.
+type SessionIDProvider interface {
+	SessionID() int64
+}
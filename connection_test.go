@@ -3,18 +3,27 @@
 package gosnowflake
 
 import (
+	"bytes"
 	"context"
 	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
 	"github.com/google/uuid"
 )
 
@@ -310,3 +319,1429 @@ func fetchResultByQueryID(t *testing.T, customget FuncGetType, expectedFetchErr
 	}
 	return nil
 }
+
+func TestCancelQueryByID(t *testing.T) {
+	qid := uuid.New().String()
+	requestID := uuid.New()
+	var abortBody []byte
+	release := make(chan struct{})
+	defer close(release)
+	postMock := func(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, body []byte, _ time.Duration, _ bool) (*http.Response, error) {
+		respBody := `{"success": true}`
+		switch fullURL.Path {
+		case queryRequestPath:
+			respBody = `{"code": "333334", "success": true, "data": {"queryId": "` + qid + `", "getResultUrl": "/queries/` + qid + `/result"}}`
+		case abortRequestPath:
+			abortBody = body
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(respBody))}, nil
+	}
+	getMock := func(ctx context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		// the query keeps running until the test is done
+		<-release
+		return nil, context.Canceled
+	}
+	var queries []string
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		queries = append(queries, req.SQLText)
+		return &execResponse{Code: "0", Success: true}, nil
+	}
+	sr := &snowflakeRestful{
+		Protocol:            "https",
+		Host:                "abc.snowflakecomputing.com",
+		Port:                443,
+		TokenAccessor:       getSimpleTokenAccessor(),
+		FuncPostQueryHelper: postRestfulQueryHelper,
+		FuncPost:            postMock,
+		FuncGet:             getMock,
+		FuncCancelQuery:     cancelQuery,
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: sr,
+	}
+
+	ctx := setResultType(WithAsyncMode(context.Background()), execResultType)
+	if _, err := sr.FuncPostQueryHelper(ctx, sr, &url.Values{}, map[string]string{}, nil, 0, requestID, sc.cfg); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var canceller QueryCanceller = sc
+	if err := canceller.CancelQuery(context.Background(), qid); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var abort map[string]string
+	if err := json.Unmarshal(abortBody, &abort); err != nil {
+		t.Fatalf("err: %v, body: %s", err, abortBody)
+	}
+	if abort[requestIDKey] != requestID.String() {
+		t.Fatalf("the abort request should name the request of the query. expected: %v, got: %v", requestID, abort[requestIDKey])
+	}
+	if len(queries) != 0 {
+		t.Fatalf("no query should have been run, got: %v", queries)
+	}
+
+	// a query that isn't running on the connection is cancelled with SQL
+	sr.FuncPostQuery = postQueryMock
+	other := uuid.New().String()
+	if err := sc.CancelQuery(context.Background(), other); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(queries) != 1 || queries[0] != cancelQueryByIDStmt {
+		t.Fatalf("expected %v, got: %v", cancelQueryByIDStmt, queries)
+	}
+
+	err := sc.CancelQuery(context.Background(), "not-a-query-id")
+	if err == nil {
+		t.Fatal("should have failed to cancel an invalid query ID")
+	}
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrQueryIDFormat {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestExecDefaultQueryTag(t *testing.T) {
+	var sentTag interface{}
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		sentTag = req.Parameters[string(queryTag)]
+		return &execResponse{
+			Data:    execResponseData{},
+			Message: "",
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}, DefaultQueryTag: "service:deployment"},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+
+	_, err := sc.exec(context.Background(), "", false /* noResult */, false /* isInternal */, false /* describeOnly */, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if sentTag != "service:deployment" {
+		t.Fatalf("default query tag was not sent. got: %v", sentTag)
+	}
+
+	ctx := WithQueryTag(context.Background(), "per-query")
+	_, err = sc.exec(ctx, "", false /* noResult */, false /* isInternal */, false /* describeOnly */, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if sentTag != "per-query" {
+		t.Fatalf("per-query tag should override the default. got: %v", sentTag)
+	}
+}
+
+func TestExecTraceID(t *testing.T) {
+	var sentTag interface{}
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		sentTag = req.Parameters[string(queryTag)]
+		return &execResponse{
+			Data:    execResponseData{QueryID: "01a2b3c4-0000-0000-0000-000000000001"},
+			Message: "",
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	getMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		// the query history returns the tag the query was run with
+		tag, err := json.Marshal(sentTag)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(`{"data": {"queries": [{"id": "01a2b3c4-0000-0000-0000-000000000001", ` +
+				`"status": "SUCCESS", "queryTag": ` + string(tag) + `}]}, "success": true}`)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}, DefaultQueryTag: "service:deployment"},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncPostQuery: postQueryMock,
+			FuncGet:       getMock,
+		},
+	}
+
+	for _, tc := range []struct {
+		ctx      context.Context
+		expected string
+	}{
+		{WithTraceID(context.Background(), "trace-1"), `{"query_tag":"service:deployment","trace_id":"trace-1"}`},
+		{WithTraceID(WithQueryTag(context.Background(), `{"team":"bi"}`), "trace-2"), `{"team":"bi","trace_id":"trace-2"}`},
+		{WithTraceID(WithQueryTag(context.Background(), ""), "trace-3"), `{"trace_id":"trace-3"}`},
+	} {
+		if _, err := sc.exec(tc.ctx, "", false /* noResult */, false /* isInternal */, false /* describeOnly */, nil); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if sentTag != tc.expected {
+			t.Fatalf("unexpected query tag. expected: %v, got: %v", tc.expected, sentTag)
+		}
+		var m monitoringResponse
+		if err := sc.getMonitoringResult(context.Background(), "01a2b3c4-0000-0000-0000-000000000001", &m); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if id := getTraceID(tc.ctx); m.Data.Queries[0].TraceID() != id {
+			t.Fatalf("trace ID should round-trip through monitoring. expected: %v, got: %v", id, m.Data.Queries[0].TraceID())
+		}
+	}
+
+	m := QueryMonitoringData{QueryTag: "service:deployment"}
+	if id := m.TraceID(); id != "" {
+		t.Fatalf("a plain query tag has no trace ID. got: %v", id)
+	}
+}
+
+func TestQueryIDObserver(t *testing.T) {
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(req.SQLText, "insert") {
+			inserted := "2"
+			return &execResponse{
+				Data: execResponseData{
+					QueryID:         "01a2b3c4-0000-0000-0000-000000000001",
+					StatementTypeID: statementTypeIDDml,
+					RowType:         []execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}},
+					RowSet:          [][]*string{{&inserted}},
+				},
+				Code:    "0",
+				Success: true,
+			}, nil
+		}
+		return &execResponse{
+			Data:    execResponseData{QueryID: "01a2b3c4-0000-0000-0000-000000000002"},
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	var observed [][2]string
+	sc := &snowflakeConn{
+		cfg: &Config{
+			Params: map[string]*string{},
+			QueryIDObserver: func(queryID string, sql string) {
+				observed = append(observed, [2]string{queryID, sql})
+			},
+		},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	if _, err := sc.ExecContext(context.Background(), "insert into t values (1), (2)", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := sc.QueryContext(context.Background(), "select 1", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := [][2]string{
+		{"01a2b3c4-0000-0000-0000-000000000001", "insert into t values (1), (2)"},
+		{"01a2b3c4-0000-0000-0000-000000000002", "select 1"},
+	}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Fatalf("unexpected observed queries. expected: %v, got: %v", expected, observed)
+	}
+}
+
+func TestQueryResultVersion(t *testing.T) {
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		return &execResponse{
+			Data: execResponseData{
+				QueryID: "01a2b3c4-0000-0000-0000-000000000001",
+				Version: 3,
+			},
+			Message: "",
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	rows, err := sc.QueryContext(context.Background(), "SELECT 1", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	sfRows, ok := rows.(SnowflakeRows)
+	if !ok {
+		t.Fatal("rows should implement SnowflakeRows")
+	}
+	if v := sfRows.GetResultVersion(); v != 3 {
+		t.Fatalf("unexpected result version. expected: %v, got: %v", 3, v)
+	}
+}
+
+func TestQueryResultTruncated(t *testing.T) {
+	returned := int64(2)
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		one, two := "1", "2"
+		return &execResponse{
+			Data: execResponseData{
+				QueryID:  "01a2b3c4-0000-0000-0000-000000000001",
+				RowType:  []execResponseRowType{{Name: "C1", Type: "fixed"}},
+				RowSet:   [][]*string{{&one}, {&two}},
+				Total:    10,
+				Returned: returned,
+			},
+			Message: "",
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	for _, tc := range []struct {
+		returned  int64
+		truncated bool
+	}{
+		{2, true},
+		{10, false},
+		{0, false}, // not reported by the server
+	} {
+		returned = tc.returned
+		rows, err := sc.QueryContext(context.Background(), "SELECT 1", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if truncated := rows.(SnowflakeRows).ResultTruncated(); truncated != tc.truncated {
+			t.Fatalf("returned %v of 10 rows. expected truncated: %v, got: %v", tc.returned, tc.truncated, truncated)
+		}
+	}
+}
+
+func TestOnSessionParametersChanged(t *testing.T) {
+	responses := [][]nameValueParameter{
+		{{Name: "TIMEZONE", Value: "America/Los_Angeles"}, {Name: "DATE_OUTPUT_FORMAT", Value: "YYYY-MM-DD"}},
+		{{Name: "TIMEZONE", Value: "UTC"}, {Name: "DATE_OUTPUT_FORMAT", Value: "YYYY-MM-DD"}},
+		{{Name: "TIMEZONE", Value: "UTC"}, {Name: "DATE_OUTPUT_FORMAT", Value: "YYYY-MM-DD"}},
+	}
+	i := 0
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		params := responses[i]
+		i++
+		return &execResponse{
+			Data: execResponseData{
+				Parameters: params,
+			},
+			Message: "",
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	var changes []map[string]string
+	sc := &snowflakeConn{
+		cfg: &Config{
+			Params: map[string]*string{},
+			OnSessionParametersChanged: func(changed map[string]string) {
+				changes = append(changes, changed)
+			},
+		},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	for range responses {
+		if _, err := sc.ExecContext(context.Background(), "ALTER SESSION SET TIMEZONE = 'UTC'", nil); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	expected := []map[string]string{
+		{"timezone": "America/Los_Angeles", "date_output_format": "YYYY-MM-DD"},
+		{"timezone": "UTC"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("unexpected parameter changes. expected: %v, got: %v", expected, changes)
+	}
+	if tz := *sc.cfg.Params["timezone"]; tz != "UTC" {
+		t.Fatalf("params should be updated. expected timezone: UTC, got: %v", tz)
+	}
+}
+
+func TestPollQueryCompletion(t *testing.T) {
+	statuses := []string{"QUEUED", "RUNNING", "RUNNING", "SUCCESS"}
+	checks := 0
+	getMock := func(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		if fullURL.Path != "/monitoring/queries/01a2b3c4-0000-0000-0000-000000000001" {
+			return nil, fmt.Errorf("unexpected path: %v", fullURL.Path)
+		}
+		status := statuses[checks]
+		checks++
+		queries := `[{"status": "` + status + `"}]`
+		if status == "" {
+			// no status yet
+			queries = `[]`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(
+				`{"data": {"queries": ` + queries + `}, "success": true}`)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncGet:       getMock,
+		},
+	}
+	backoff := backoffConfig{initial: time.Millisecond, max: 5 * time.Millisecond, multiplier: 2}
+	if err := sc.pollQueryCompletion(context.Background(), "01a2b3c4-0000-0000-0000-000000000001", backoff, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if checks != len(statuses) {
+		t.Fatalf("expected %v status checks, got: %v", len(statuses), checks)
+	}
+
+	statuses = []string{"RUNNING", "FAILED_WITH_ERROR"}
+	checks = 0
+	err := sc.pollQueryCompletion(context.Background(), "01a2b3c4-0000-0000-0000-000000000001", backoff, nil)
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrQueryReportedError {
+		t.Fatalf("should have failed with the query error. err: %v", err)
+	}
+
+	// a query has no status right after it was submitted
+	statuses = []string{"", "", "RUNNING", "SUCCESS"}
+	checks = 0
+	if err = sc.pollQueryCompletion(context.Background(), "01a2b3c4-0000-0000-0000-000000000001", backoff, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if checks != len(statuses) {
+		t.Fatalf("expected %v status checks, got: %v", len(statuses), checks)
+	}
+
+	// the intervals are 1, 2, 4 and 5ms, so the 4th check without a status fails
+	statuses = []string{"", "", "", "", ""}
+	checks = 0
+	err = sc.pollQueryCompletion(context.Background(), "01a2b3c4-0000-0000-0000-000000000001", backoff, nil)
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrQueryStatus {
+		t.Fatalf("should have failed without a status. err: %v", err)
+	}
+	if checks != 4 {
+		t.Fatalf("expected 4 status checks, got: %v", checks)
+	}
+}
+
+func TestPollQueryCompletionProgress(t *testing.T) {
+	responses := []string{
+		`{"status": "RUNNING", "stats": {"scanBytes": 100, "producedRows": 0}}`,
+		`{"status": "RUNNING", "stats": {"scanBytes": 2000, "producedRows": 10}}`,
+		`{"status": "SUCCESS", "stats": {"scanBytes": 4000, "producedRows": 25}}`,
+	}
+	checks := 0
+	getMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		resp := responses[checks]
+		checks++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(
+				`{"data": {"queries": [` + resp + `]}, "success": true}`)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncGet:       getMock,
+		},
+	}
+	var progress []SnowflakeQueryStatus
+	backoff := backoffConfig{initial: time.Millisecond, max: 5 * time.Millisecond, multiplier: 2}
+	err := sc.pollQueryCompletion(context.Background(), "01a2b3c4-0000-0000-0000-000000000001", backoff, func(status *SnowflakeQueryStatus) {
+		progress = append(progress, *status)
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := []SnowflakeQueryStatus{
+		{QueryID: "01a2b3c4-0000-0000-0000-000000000001", Status: "RUNNING", Phase: QueryPhaseRunning, ScanBytes: 100},
+		{QueryID: "01a2b3c4-0000-0000-0000-000000000001", Status: "RUNNING", Phase: QueryPhaseRunning, ScanBytes: 2000, ProducedRows: 10},
+		{QueryID: "01a2b3c4-0000-0000-0000-000000000001", Status: "SUCCESS", Phase: QueryPhaseSucceeded, ScanBytes: 4000, ProducedRows: 25},
+	}
+	if !reflect.DeepEqual(progress, expected) {
+		t.Fatalf("unexpected progress. expected: %+v, got: %+v", expected, progress)
+	}
+}
+
+func TestCheckQueryStatusNoData(t *testing.T) {
+	defer func(attempts int, interval time.Duration) {
+		noDataRetryAttempts = attempts
+		noDataRetryInterval = interval
+	}(noDataRetryAttempts, noDataRetryInterval)
+	noDataRetryAttempts = 3
+	noDataRetryInterval = time.Millisecond
+
+	var statuses []string
+	checks := 0
+	getMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		status := statuses[checks]
+		checks++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(
+				`{"data": {"queries": [{"status": "` + status + `"}]}, "success": true}`)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncGet:       getMock,
+		},
+	}
+
+	statuses = []string{"NO_DATA", "NO_DATA", "SUCCESS"}
+	if err := sc.checkQueryStatus(context.Background(), "01a2b3c4-0000-0000-0000-000000000001"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if checks != len(statuses) {
+		t.Fatalf("expected %v status checks, got: %v", len(statuses), checks)
+	}
+
+	statuses = []string{"NO_DATA", "NO_DATA", "NO_DATA", "NO_DATA", "SUCCESS"}
+	checks = 0
+	err := sc.checkQueryStatus(context.Background(), "01a2b3c4-0000-0000-0000-000000000001")
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrQueryIsRunning {
+		t.Fatalf("should have reported the query as running. err: %v", err)
+	}
+	if checks != noDataRetryAttempts+1 {
+		t.Fatalf("expected %v status checks, got: %v", noDataRetryAttempts+1, checks)
+	}
+}
+
+func TestGetQueryStatusPhase(t *testing.T) {
+	var status string
+	getMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(
+				`{"data": {"queries": [{"status": "` + status + `"}]}, "success": true}`)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncGet:       getMock,
+		},
+	}
+	for _, tc := range []struct {
+		status string
+		phase  QueryPhase
+	}{
+		{"RUNNING", QueryPhaseRunning},
+		{"NO_DATA", QueryPhaseRunning},
+		{"QUEUED", QueryPhaseQueued},
+		{"RESUMING_WAREHOUSE", QueryPhaseWaitingForWarehouse},
+		{"QUEUED_REPAIRING_WAREHOUSE", QueryPhaseWaitingForWarehouse},
+		{"BLOCKED", QueryPhaseBlocked},
+		{"SUCCESS", QueryPhaseSucceeded},
+		{"FAILED_WITH_ERROR", QueryPhaseFailed},
+		{"ABORTED", QueryPhaseFailed},
+		{"SOME_NEW_STATUS", QueryPhaseUnknown},
+	} {
+		status = tc.status
+		qs, err := sc.GetQueryStatus(context.Background(), "01a2b3c4-0000-0000-0000-000000000001")
+		if err != nil {
+			t.Fatalf("status: %v, err: %v", tc.status, err)
+		}
+		if qs.Status != tc.status || qs.Phase != tc.phase {
+			t.Fatalf("status: %v. expected phase: %v, got: %+v", tc.status, tc.phase, qs)
+		}
+	}
+}
+
+func TestExecDisableServerResultCache(t *testing.T) {
+	var sentParams map[string]interface{}
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		sentParams = req.Parameters
+		return &execResponse{
+			Data:    execResponseData{},
+			Message: "",
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+
+	ctx := WithDisableServerResultCache(context.Background())
+	if _, err := sc.exec(ctx, "", false /* noResult */, false /* isInternal */, false /* describeOnly */, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if v, ok := sentParams[useCachedResult]; !ok || v != false {
+		t.Fatalf("%v should have been set to false. params: %v", useCachedResult, sentParams)
+	}
+	if _, ok := sc.cfg.Params[strings.ToLower(useCachedResult)]; ok {
+		t.Fatalf("%v should not have been stored in the session parameters", useCachedResult)
+	}
+
+	if _, err := sc.exec(context.Background(), "", false /* noResult */, false /* isInternal */, false /* describeOnly */, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := sentParams[useCachedResult]; ok {
+		t.Fatalf("%v should not have been sent. params: %v", useCachedResult, sentParams)
+	}
+}
+
+func TestExecStatementTimeout(t *testing.T) {
+	var sentParams map[string]interface{}
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		sentParams = req.Parameters
+		return &execResponse{
+			Data:    execResponseData{},
+			Message: "",
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+
+	for _, d := range []time.Duration{0, -time.Second} {
+		if _, err := WithStatementTimeout(context.Background(), d); err == nil {
+			t.Fatalf("a timeout of %v should have been rejected", d)
+		}
+	}
+	ctx, err := WithStatementTimeout(context.Background(), 1500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err = sc.exec(ctx, "", false /* noResult */, false /* isInternal */, false /* describeOnly */, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	// the timeout is rounded up to whole seconds and decoded from JSON as a float
+	if v, ok := sentParams[string(statementTimeout)]; !ok || v != float64(2) {
+		t.Fatalf("%v should have been set to 2. params: %v", statementTimeout, sentParams)
+	}
+	if _, ok := sc.cfg.Params[strings.ToLower(string(statementTimeout))]; ok {
+		t.Fatalf("%v should not have been stored in the session parameters", statementTimeout)
+	}
+
+	if _, err = sc.exec(context.Background(), "", false /* noResult */, false /* isInternal */, false /* describeOnly */, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := sentParams[string(statementTimeout)]; ok {
+		t.Fatalf("%v should not have been sent. params: %v", statementTimeout, sentParams)
+	}
+}
+
+func TestQueryGraphMaterializedViewRewrite(t *testing.T) {
+	var requestedPath string
+	getMock := func(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		requestedPath = fullURL.Path
+		jsonStr := `{"data": {"steps": [{"step": 1, "description": "main", "graphData": {"nodes": [
+			{"id": 0, "logicalId": 0, "name": "Result", "title": "Result"},
+			{"id": 1, "logicalId": 1, "name": "MaterializedViewScan", "title": "MaterializedViewScan",
+				"attributes": {"table": "DB.SCHEMA.MV_ORDERS"}}]}}]},
+			"code": null, "message": null, "success": true}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(jsonStr)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncGet:       getMock,
+		},
+	}
+
+	g, err := sc.queryGraph("01a2b3c4-0000-0000-0000-000000000001", FetchQueryMonitoringDataThreshold)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if requestedPath != "/monitoring/query-plan-data/01a2b3c4-0000-0000-0000-000000000001" {
+		t.Fatalf("unexpected path: %v", requestedPath)
+	}
+	if !g.UsedMaterializedView() {
+		t.Fatal("the graph should report a materialized view rewrite")
+	}
+	if g.UsedSearchOptimization() {
+		t.Fatal("the graph should not report search optimization")
+	}
+
+	g, err = sc.queryGraph("01a2b3c4-0000-0000-0000-000000000001", 0)
+	if err != nil || g != nil {
+		t.Fatalf("fast queries should not fetch the graph. graph: %v, err: %v", g, err)
+	}
+	if g.UsedMaterializedView() {
+		t.Fatal("a missing graph should not report a materialized view rewrite")
+	}
+}
+
+func TestQueryGraphFetchedOnDemand(t *testing.T) {
+	defer func(threshold time.Duration) { FetchQueryMonitoringDataThreshold = threshold }(FetchQueryMonitoringDataThreshold)
+	FetchQueryMonitoringDataThreshold = 0
+
+	graphRequests := 0
+	getMock := func(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		jsonStr := `{"data": {"queries": []}, "success": true}`
+		if strings.HasPrefix(fullURL.Path, "/monitoring/query-plan-data/") {
+			graphRequests++
+			jsonStr = `{"data": {"steps": [{"step": 1, "description": "main", "graphData": {"nodes": [
+				{"id": 0, "logicalId": 0, "name": "Result", "title": "Result"}]}}]}, "success": true}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(jsonStr)),
+		}, nil
+	}
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		one := "1"
+		return &execResponse{Data: execResponseData{
+			QueryID:           "01a2b3c4-0000-0000-0000-000000000001",
+			RowType:           []execResponseRowType{{Name: "C1", Type: "fixed"}},
+			RowSet:            [][]*string{{&one}},
+			Total:             1,
+			Returned:          1,
+			QueryResultFormat: "json",
+		}, Code: "0", Success: true}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncPostQuery: postQueryMock,
+			FuncGet:       getMock,
+		},
+	}
+
+	rows, err := sc.QueryContext(context.Background(), "select 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if graphRequests != 0 {
+		t.Fatalf("the query graph should not be fetched by the query. requests: %v", graphRequests)
+	}
+	for i := 0; i < 2; i++ {
+		if g := rows.(QueryGraphProvider).QueryGraph(); g == nil || len(g.Steps) != 1 {
+			t.Fatalf("unexpected query graph: %v", g)
+		}
+	}
+	if graphRequests != 1 {
+		t.Fatalf("the query graph should be fetched once. requests: %v", graphRequests)
+	}
+}
+
+func TestQueryMissingResultData(t *testing.T) {
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		return &execResponse{
+			Data: execResponseData{
+				QueryID: "01a2b3c4-0000-0000-0000-000000000001",
+				RowType: []execResponseRowType{{Name: "C1", Type: "fixed"}},
+				Total:   5,
+			},
+			Message: "",
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	_, err := sc.QueryContext(context.Background(), "SELECT 1", nil)
+	driverErr, ok := err.(*SnowflakeError)
+	if !ok {
+		t.Fatalf("should have failed with a snowflake error. err: %v", err)
+	}
+	if driverErr.Number != ErrMissingResultData {
+		t.Fatalf("unexpected error code. expected: %v, got: %v", ErrMissingResultData, driverErr.Number)
+	}
+	if driverErr.QueryID != "01a2b3c4-0000-0000-0000-000000000001" {
+		t.Fatalf("the error should carry the query id. got: %v", driverErr.QueryID)
+	}
+}
+
+func TestMonitoringQueryAcceleration(t *testing.T) {
+	jsonStr := `{"data": {"queries": [{"id": "01a2b3c4-0000-0000-0000-000000000001", "status": "SUCCESS",
+		"stats": {"scanBytes": 4096, "queryAccelerationBytesScanned": 1024, "queryAccelerationPartitionsScanned": 3},
+		"queryAccelerationCredits": 0.25}]},
+		"code": null, "message": null, "success": true}`
+	getMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(jsonStr)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncGet:       getMock,
+		},
+	}
+
+	m, err := sc.monitoring("01a2b3c4-0000-0000-0000-000000000001", FetchQueryMonitoringDataThreshold)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !m.UsedQueryAcceleration() {
+		t.Fatal("the query should report query acceleration usage")
+	}
+	if c := m.QueryAccelerationCredits(); c != 0.25 {
+		t.Fatalf("unexpected query acceleration credits. expected: %v, got: %v", 0.25, c)
+	}
+
+	jsonStr = `{"data": {"queries": [{"id": "01a2b3c4-0000-0000-0000-000000000001", "status": "SUCCESS",
+		"stats": {"scanBytes": 4096}}]}, "code": null, "message": null, "success": true}`
+	if m, err = sc.monitoring("01a2b3c4-0000-0000-0000-000000000001", FetchQueryMonitoringDataThreshold); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if m.UsedQueryAcceleration() || m.QueryAccelerationCredits() != 0 {
+		t.Fatalf("the query should not report query acceleration usage. stats: %v", m.Stats)
+	}
+}
+
+func TestGetQueryMonitoringData(t *testing.T) {
+	var requestedPath string
+	getMock := func(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		requestedPath = fullURL.Path
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(`{"data": {"queries": [{"id": "01a2b3c4-0000-0000-0000-000000000001",
+				"status": "SUCCESS", "totalDuration": 42, "clusterNumber": 2, "warehouseId": 7,
+				"warehouseName": "REPORTING_WH", "warehouseServerType": "STANDARD"}]},
+				"code": null, "message": null, "success": true}`)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncGet:       getMock,
+		},
+	}
+
+	// a fast query has no monitoring data on its result
+	if m, err := sc.monitoring("01a2b3c4-0000-0000-0000-000000000001", 42*time.Millisecond); err != nil || m != nil {
+		t.Fatalf("monitoring data should not have been fetched for a fast query. data: %v, err: %v", m, err)
+	}
+	m, err := sc.GetQueryMonitoringData(context.Background(), "01a2b3c4-0000-0000-0000-000000000001")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if requestedPath != "/monitoring/queries/01a2b3c4-0000-0000-0000-000000000001" {
+		t.Fatalf("unexpected path: %v", requestedPath)
+	}
+	if m.WarehouseName != "REPORTING_WH" || m.ClusterNumber != 2 || m.WarehouseServerType != "STANDARD" || m.WarehouseID != 7 {
+		t.Fatalf("unexpected warehouse of the query: %+v", m)
+	}
+}
+
+func TestLastQueryID(t *testing.T) {
+	var n int
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		n++
+		return &execResponse{
+			Data: execResponseData{
+				QueryID: fmt.Sprintf("01a2b3c4-0000-0000-0000-00000000000%v", n),
+			},
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	var qp QueryIDProvider = sc
+	if qid := qp.LastQueryID(); qid != "" {
+		t.Fatalf("no query has run yet. got: %v", qid)
+	}
+	for i := 1; i <= 2; i++ {
+		if _, err := sc.ExecContext(context.Background(), "create table t(c int)", nil); err != nil {
+			t.Fatal(err)
+		}
+		expected := fmt.Sprintf("01a2b3c4-0000-0000-0000-00000000000%v", i)
+		if qid := qp.LastQueryID(); qid != expected {
+			t.Fatalf("unexpected query ID. expected: %v, got: %v", expected, qid)
+		}
+	}
+}
+
+func TestPingWarehouse(t *testing.T) {
+	warehouseDown := false
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if warehouseDown && req.SQLText == pingWarehouseQuery {
+			return &execResponse{
+				Data: execResponseData{
+					SQLState: sqlStateCannotConnectNow,
+					QueryID:  "01a2b3c4-0000-0000-0000-000000000001",
+				},
+				Message: "No active warehouse selected in the current session.",
+				Code:    "000606",
+				Success: false,
+			}, nil
+		}
+		return &execResponse{
+			Data:    execResponseData{FinalWarehouseName: "TEST_WH"},
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}, Warehouse: "TEST_WH"},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	var wp WarehousePinger = sc
+	ctx := context.Background()
+	if err := wp.PingWarehouse(ctx); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// the session is valid but the warehouse cannot run queries
+	warehouseDown = true
+	if err := sc.Ping(ctx); err != nil {
+		t.Fatalf("the session should still be valid. err: %v", err)
+	}
+	err := wp.PingWarehouse(ctx)
+	se, ok := err.(*SnowflakeError)
+	if !ok || se.Number != ErrCodeWarehouseUnavailable {
+		t.Fatalf("expected a warehouse unavailable error. got: %v", err)
+	}
+	if !strings.Contains(se.Error(), "TEST_WH") || se.QueryID != "01a2b3c4-0000-0000-0000-000000000001" {
+		t.Fatalf("unexpected error: %v, query ID: %v", se, se.QueryID)
+	}
+
+	sc.rest = nil
+	if err = wp.PingWarehouse(ctx); err != driver.ErrBadConn {
+		t.Fatalf("expected %v, got: %v", driver.ErrBadConn, err)
+	}
+}
+
+func TestQueryRowLimit(t *testing.T) {
+	var sentParams map[string]interface{}
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		sentParams = req.Parameters
+		// more rows than the limit, as if it was ignored
+		one, two, three := "1", "2", "3"
+		return &execResponse{
+			Data: execResponseData{
+				QueryID:           "qid",
+				RowType:           []execResponseRowType{{Name: "C1", Type: "fixed"}},
+				RowSet:            [][]*string{{&one}, {&two}, {&three}},
+				Total:             3,
+				QueryResultFormat: "json",
+			},
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+
+	if _, err := WithRowLimit(context.Background(), 0); err == nil {
+		t.Fatal("a row limit of 0 should have been rejected")
+	}
+	ctx, err := WithRowLimit(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	rows, err := sc.QueryContext(ctx, "select * from t", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer rows.Close()
+	if sentParams["ROWS_PER_RESULTSET"] != float64(2) {
+		t.Fatalf("the row limit should have been sent. params: %v", sentParams)
+	}
+	var n int
+	dest := make([]driver.Value, 1)
+	for rows.Next(dest) == nil {
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 rows, got: %v", n)
+	}
+
+	if _, err = sc.QueryContext(context.Background(), "select * from t", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := sentParams["ROWS_PER_RESULTSET"]; ok {
+		t.Fatalf("the row limit should not have been kept. params: %v", sentParams)
+	}
+}
+
+func TestSQLRewriter(t *testing.T) {
+	var sent []string
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		sent = append(sent, req.SQLText)
+		return &execResponse{Code: "0", Success: true}, nil
+	}
+	rewriteErr := errors.New("rejected")
+	sc := &snowflakeConn{
+		cfg: &Config{
+			Params: map[string]*string{},
+			SQLRewriter: func(_ context.Context, sql string) (string, error) {
+				if strings.HasPrefix(sql, "drop") {
+					return "", rewriteErr
+				}
+				return sql + " /* tenant=42 */", nil
+			},
+		},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+
+	if _, err := sc.exec(context.Background(), "select 1", false /* noResult */, false /* isInternal */, false /* describeOnly */, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := sc.exec(context.Background(), "drop table t", false /* noResult */, false /* isInternal */, false /* describeOnly */, nil); err != rewriteErr {
+		t.Fatalf("the rewriter error should have aborted the query, got: %v", err)
+	}
+	if _, err := sc.exec(context.Background(), "select 2", false /* noResult */, true /* isInternal */, false /* describeOnly */, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := []string{"select 1 /* tenant=42 */", "select 2"}
+	if !reflect.DeepEqual(sent, expected) {
+		t.Fatalf("unexpected queries. expected: %v, got: %v", expected, sent)
+	}
+}
+
+func TestQueryCompilationAndExecutionTime(t *testing.T) {
+	getMock := func(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		jsonStr := `{"data": {"queries": [{"id": "01a2b3c4-0000-0000-0000-000000000001", "status": "SUCCESS",
+			"totalDuration": 2750, "stats": {"scanBytes": 4096, "compilationTime": 2100, "executionTime": 620,
+			"queuedOverloadTime": 30}}]}, "code": null, "message": null, "success": true}`
+		if strings.HasPrefix(fullURL.Path, "/monitoring/query-plan-data/") {
+			jsonStr = `{"data": {"steps": [
+				{"step": 1, "description": "build", "timeInMs": 200, "graphData": {"nodes": []}},
+				{"step": 2, "description": "main", "timeInMs": 420, "graphData": {"nodes": [
+					{"id": 0, "logicalId": 0, "name": "Result", "title": "Result"}]}}]},
+				"code": null, "message": null, "success": true}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(jsonStr)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncGet:       getMock,
+		},
+	}
+
+	m, err := sc.monitoring("01a2b3c4-0000-0000-0000-000000000001", FetchQueryMonitoringDataThreshold)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if d := m.CompilationTime(); d != 2100*time.Millisecond {
+		t.Fatalf("unexpected compilation time: %v", d)
+	}
+	if d := m.ExecutionTime(); d != 620*time.Millisecond {
+		t.Fatalf("unexpected execution time: %v", d)
+	}
+	g, err := sc.queryGraph("01a2b3c4-0000-0000-0000-000000000001", FetchQueryMonitoringDataThreshold)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if d := g.ExecutionTime(); d != 620*time.Millisecond {
+		t.Fatalf("unexpected execution time of the steps: %v", d)
+	}
+
+	var missing *QueryMonitoringData
+	var missingGraph *QueryGraphData
+	if missing.CompilationTime() != 0 || missing.ExecutionTime() != 0 || missingGraph.ExecutionTime() != 0 {
+		t.Fatal("missing data should report no time")
+	}
+}
+
+func TestConcurrentExecOnConnection(t *testing.T) {
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		// the query text is "select <n>" and its query ID ends with n
+		n := strings.TrimPrefix(req.SQLText, "select ")
+		// lets the queries overlap
+		time.Sleep(time.Duration(len(n)) * time.Millisecond)
+		data := execResponseData{
+			QueryID:            fmt.Sprintf("01a2b3c4-0000-0000-0000-%012v", n),
+			FinalWarehouseName: "WH_" + n,
+			RowSet:             [][]*string{{&n}},
+			RowType:            []execResponseRowType{{Name: "C1", Type: "fixed"}},
+			Total:              1,
+			Returned:           1,
+			QueryResultFormat:  "json",
+		}
+		if strings.HasSuffix(n, "0") {
+			data.StatementTypeID = statementTypeIDDml
+			data.RowSet = [][]*string{{&n}}
+			data.RowType = []execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}}
+		}
+		return &execResponse{Data: data, Code: "0", Success: true}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	sc.ctx = context.Background()
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, 100)
+	for i := 1; i <= 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			query := fmt.Sprintf("select %v", i)
+			expected := fmt.Sprintf("01a2b3c4-0000-0000-0000-%012v", i)
+			var qid string
+			if i%10 == 0 {
+				res, err := sc.ExecContext(context.Background(), query, nil)
+				if err != nil {
+					errs <- err
+					return
+				}
+				qid = res.(SnowflakeResult).GetQueryID()
+			} else {
+				rows, err := sc.QueryContext(context.Background(), query, nil)
+				if err != nil {
+					errs <- err
+					return
+				}
+				qid = rows.(SnowflakeRows).GetQueryID()
+				rows.Close()
+			}
+			if qid != expected {
+				errs <- fmt.Errorf("%v got the query ID %v", query, qid)
+			}
+			if err := sc.Ping(context.Background()); err != nil {
+				errs <- err
+			}
+			sc.LastQueryID()
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestQueryRequireArrow(t *testing.T) {
+	format := "json"
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		data := execResponseData{
+			QueryID:           "01a2b3c4-0000-0000-0000-000000000001",
+			RowType:           []execResponseRowType{{Name: "C1", Type: "fixed"}},
+			QueryResultFormat: format,
+		}
+		if format == "json" {
+			one := "1"
+			data.RowSet = [][]*string{{&one}}
+			data.Total = 1
+			data.Returned = 1
+		}
+		return &execResponse{Data: data, Code: "0", Success: true}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+
+	rows, err := sc.QueryContext(context.Background(), "select 1", nil)
+	if err != nil {
+		t.Fatalf("a JSON result should be read without the flag. err: %v", err)
+	}
+	rows.Close()
+
+	_, err = sc.QueryContext(WithRequireArrow(context.Background()), "select 1", nil)
+	se, ok := err.(*SnowflakeError)
+	if !ok || se.Number != ErrNotArrowResult {
+		t.Fatalf("a JSON result should have been rejected. err: %v", err)
+	}
+	if se.QueryID != "01a2b3c4-0000-0000-0000-000000000001" || !strings.Contains(se.Error(), "json") {
+		t.Fatalf("unexpected error: %v, query ID: %v", se, se.QueryID)
+	}
+
+	// an empty arrow result
+	format = "arrow"
+	rows, err = sc.QueryContext(WithRequireArrow(context.Background()), "select 1", nil)
+	if err != nil {
+		t.Fatalf("an arrow result should be read with the flag. err: %v", err)
+	}
+	rows.Close()
+}
+
+func TestQueryArrowRequestIgnored(t *testing.T) {
+	format := "json"
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		one := "1"
+		data := execResponseData{
+			QueryID:           "01a2b3c4-0000-0000-0000-000000000001",
+			RowType:           []execResponseRowType{{Name: "C1", Type: "fixed"}},
+			QueryResultFormat: format,
+		}
+		if format == "json" {
+			data.RowSet = [][]*string{{&one}}
+			data.Total = 1
+			data.Returned = 1
+		}
+		return &execResponse{Data: data, Code: "0", Success: true}, nil
+	}
+	arrow := "ARROW"
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{sessionGoQueryResultFormat: &arrow}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+
+	// the server ignores the requested format
+	rows, err := sc.QueryContext(context.Background(), "select 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rows.(SnowflakeRows).ArrowRequestIgnored() {
+		t.Fatal("the JSON result should have been flagged")
+	}
+	dest := make([]driver.Value, 1)
+	if err = rows.Next(dest); err != nil || dest[0] != "1" {
+		t.Fatalf("the JSON result should still be read. value: %v, err: %v", dest[0], err)
+	}
+	rows.Close()
+
+	format = "arrow"
+	rows, err = sc.QueryContext(context.Background(), "select 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows.(SnowflakeRows).ArrowRequestIgnored() {
+		t.Fatal("an arrow result should not have been flagged")
+	}
+	rows.Close()
+
+	// JSON results aren't flagged when they are what the session asks for
+	format = "json"
+	jsonFormat := "JSON"
+	sc.cfg.Params[sessionGoQueryResultFormat] = &jsonFormat
+	rows, err = sc.QueryContext(context.Background(), "select 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows.(SnowflakeRows).ArrowRequestIgnored() {
+		t.Fatal("a requested JSON result should not have been flagged")
+	}
+	rows.Close()
+}
+
+func TestExecDmlRowsAffected(t *testing.T) {
+	str := func(s string) *string { return &s }
+	arrowRowSet := func(fields []arrow.Field, counts []int64) string {
+		pool := memory.NewGoAllocator()
+		schema := arrow.NewSchema(fields, nil)
+		cols := make([]array.Interface, len(counts))
+		for i, c := range counts {
+			b := array.NewInt64Builder(pool)
+			b.Append(c)
+			cols[i] = b.NewArray()
+			b.Release()
+		}
+		rec := array.NewRecord(schema, cols, 1)
+		var buf bytes.Buffer
+		w := ipc.NewWriter(&buf, ipc.WithSchema(schema), ipc.WithAllocator(pool))
+		if err := w.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		rec.Release()
+		for _, col := range cols {
+			col.Release()
+		}
+		return base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	mergeRowType := []execResponseRowType{
+		{Name: "number of rows inserted", Type: "fixed"},
+		{Name: "number of rows updated", Type: "fixed"},
+	}
+	mergeFields := []arrow.Field{
+		{Name: "number of rows inserted", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "number of rows updated", Type: arrow.PrimitiveTypes.Int64},
+	}
+
+	var data execResponseData
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		return &execResponse{Data: data, Code: "0", Success: true}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	for _, tc := range []struct {
+		name     string
+		data     execResponseData
+		expected int64
+	}{
+		{"merge json", execResponseData{
+			StatementTypeID: statementTypeIDMerge,
+			RowType:         mergeRowType,
+			RowSet:          [][]*string{{str("3"), str("4")}},
+		}, 7},
+		{"merge arrow", execResponseData{
+			StatementTypeID:   statementTypeIDMerge,
+			RowType:           mergeRowType,
+			QueryResultFormat: "arrow",
+			RowSetBase64:      arrowRowSet(mergeFields, []int64{3, 4}),
+		}, 7},
+		{"multi-table insert", execResponseData{
+			StatementTypeID: statementTypeIDMultiTableInsert,
+			RowType: []execResponseRowType{
+				{Name: "number of rows inserted into T1", Type: "fixed"},
+				{Name: "number of rows inserted into T2", Type: "fixed"},
+				{Name: "number of rows inserted into T3", Type: "fixed"},
+			},
+			RowSet: [][]*string{{str("1"), str("2"), str("5")}},
+		}, 8},
+		{"update without rows", execResponseData{
+			StatementTypeID: statementTypeIDUpdate,
+			RowType:         []execResponseRowType{{Name: "number of rows updated", Type: "fixed"}},
+		}, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data = tc.data
+			res, err := sc.ExecContext(context.Background(), "merge into t using s on t.id = s.id "+
+				"when matched then update set t.v = s.v when not matched then insert values (s.id, s.v)", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count, err := res.RowsAffected(); err != nil || count != tc.expected {
+				t.Fatalf("expected %v affected rows, got: %v, err: %v", tc.expected, count, err)
+			}
+		})
+	}
+}
+
+func TestMonitoringForbidden(t *testing.T) {
+	defer func(threshold time.Duration) { FetchQueryMonitoringDataThreshold = threshold }(FetchQueryMonitoringDataThreshold)
+	FetchQueryMonitoringDataThreshold = 0
+
+	gets := 0
+	getMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		gets++
+		return &http.Response{
+			StatusCode: http.StatusForbidden,
+			Body:       ioutil.NopCloser(strings.NewReader(`<html>Forbidden</html>`)),
+		}, nil
+	}
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		one := "1"
+		return &execResponse{Data: execResponseData{
+			QueryID:           "01a2b3c4-0000-0000-0000-000000000001",
+			RowType:           []execResponseRowType{{Name: "C1", Type: "fixed"}},
+			RowSet:            [][]*string{{&one}},
+			Total:             1,
+			Returned:          1,
+			QueryResultFormat: "json",
+		}, Code: "0", Success: true}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncPostQuery: postQueryMock,
+			FuncGet:       getMock,
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		rows, err := sc.QueryContext(context.Background(), "select 1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sr := rows.(SnowflakeRows)
+		if sr.Monitoring() != nil || rows.(QueryGraphProvider).QueryGraph() != nil {
+			t.Fatal("no monitoring data should have been returned")
+		}
+		monitoringErr := rows.(MonitoringErrProvider).MonitoringErr()
+		if se, ok := monitoringErr.(*SnowflakeError); !ok || se.Number != ErrMonitoringUnavailable {
+			t.Fatalf("expected error %v, got: %v", ErrMonitoringUnavailable, monitoringErr)
+		}
+		rows.Close()
+	}
+	if gets != 1 {
+		t.Fatalf("the monitoring endpoints should have been requested once, got: %v requests", gets)
+	}
+	_, err := sc.GetQueryMonitoringData(context.Background(), "01a2b3c4-0000-0000-0000-000000000001")
+	if se, ok := err.(*SnowflakeError); !ok || se.Number != ErrMonitoringUnavailable {
+		t.Fatalf("expected error %v, got: %v", ErrMonitoringUnavailable, err)
+	}
+}
@@ -4,6 +4,7 @@ package gosnowflake
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
 	"reflect"
 	"testing"
@@ -47,3 +48,22 @@ func TestConnector(t *testing.T) {
 		t.Fatalf("Missing driver")
 	}
 }
+
+func TestOpenDBWithConnector(t *testing.T) {
+	conn := snowflakeConn{
+		cfg:  &Config{KeepSessionAlive: true},
+		rest: &snowflakeRestful{},
+	}
+	mock := noopTestDriver{conn: &conn}
+	config := Config{Account: "a", User: "u", Password: "p"}
+	db := sql.OpenDB(NewConnector(&mock, config))
+	defer db.Close()
+	c, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get a connection. err: %v", err)
+	}
+	defer c.Close()
+	if mock.config.Account != "a" || mock.config.User != "u" || mock.config.Host != "a.snowflakecomputing.com" {
+		t.Fatalf("the connection should have been opened with the filled config. config: %+v", mock.config)
+	}
+}
@@ -3,8 +3,10 @@
 package gosnowflake
 
 import (
+	"context"
 	"database/sql/driver"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/big"
@@ -64,7 +66,7 @@ func snowflakeTypeToGo(dbtype snowflakeType, scale int64) reflect.Type {
 		return reflect.TypeOf(float64(0))
 	case realType:
 		return reflect.TypeOf(float64(0))
-	case textType, variantType, objectType, arrayType:
+	case textType, variantType, objectType, arrayType, geographyType, geometryType:
 		return reflect.TypeOf("")
 	case dateType, timeType, timestampLtzType, timestampNtzType, timestampTzType:
 		return reflect.TypeOf(time.Now())
@@ -77,6 +79,25 @@ func snowflakeTypeToGo(dbtype snowflakeType, scale int64) reflect.Type {
 	return reflect.TypeOf("")
 }
 
+// BindValueSerializer overrides how scalar bind values are serialized before
+// they are sent to Snowflake. It is consulted before the default conversion,
+// so an implementation only needs to handle the types it wants to change.
+type BindValueSerializer interface {
+	// SerializeBindValue returns the serialized form of v and true to override
+	// the default conversion, or false to fall back to it. typ is the
+	// Snowflake type the default conversion binds v as, e.g. "TIMESTAMP_NTZ".
+	// Overridden values are bound as TEXT and cast by Snowflake to the type
+	// they are used as.
+	SerializeBindValue(v driver.Value, typ string) (string, bool, error)
+}
+
+func serializeBindValue(serializer BindValueSerializer, v driver.Value, tsmode snowflakeType) (string, bool, error) {
+	if serializer == nil {
+		return "", false, nil
+	}
+	return serializer.SerializeBindValue(v, tsmode.String())
+}
+
 // valueToString converts arbitrary golang type to a string. This is mainly used in binding data with placeholders
 // in queries.
 func valueToString(v driver.Value, tsmode snowflakeType) (*string, error) {
@@ -127,8 +148,7 @@ func valueToString(v driver.Value, tsmode snowflakeType) (*string, error) {
 				s := fmt.Sprintf("%d", tm.UnixNano())
 				return &s, nil
 			case timestampTzType:
-				_, offset := tm.Zone()
-				s := fmt.Sprintf("%v %v", tm.UnixNano(), offset/60+1440)
+				s := fmt.Sprintf("%v %v", tm.UnixNano(), TimestampTZOffset(tm)+1440)
 				return &s, nil
 			}
 		}
@@ -167,17 +187,72 @@ func extractTimestamp(srcValue *string) (sec int64, nsec int64, err error) {
 	return sec, nsec, nil
 }
 
+// stringToRawTimestamp converts a JSON date, time or timestamp value to the
+// int64 returned with WithRawTimestamps. Time and timestamp values are sent as
+// decimal seconds, so the fraction is scaled to the column scale.
+func stringToRawTimestamp(srcColumnMeta execResponseRowType, srcValue string) (int64, error) {
+	if srcColumnMeta.Type == "date" {
+		return strconv.ParseInt(srcValue, 10, 64)
+	}
+	if srcColumnMeta.Type == "timestamp_tz" {
+		tm := strings.Split(srcValue, " ")
+		if len(tm) != 2 {
+			return 0, &SnowflakeError{
+				Number:   ErrInvalidTimestampTz,
+				SQLState: SQLStateInvalidDataTimeFormat,
+				Message:  fmt.Sprintf("invalid TIMESTAMP_TZ data. The value doesn't consist of two numeric values separated by a space: %v", srcValue),
+			}
+		}
+		srcValue = tm[0]
+	}
+	sec, frac := srcValue, ""
+	if i := strings.IndexByte(srcValue, '.'); i >= 0 {
+		sec, frac = srcValue[:i], srcValue[i+1:]
+	}
+	scale := int(srcColumnMeta.Scale)
+	if len(frac) > scale {
+		frac = frac[:scale]
+	} else {
+		frac += strings.Repeat("0", scale-len(frac))
+	}
+	return strconv.ParseInt(sec+frac, 10, 64)
+}
+
 // stringToValue converts a pointer of string data to an arbitrary golang variable. This is mainly used in fetching
 // data.
-func stringToValue(dest *driver.Value, srcColumnMeta execResponseRowType, srcValue *string) error {
+func stringToValue(ctx context.Context, dest *driver.Value, srcColumnMeta execResponseRowType, srcValue *string) error {
 	if srcValue == nil {
 		logger.Debugf("snowflake data type: %v, raw value: nil", srcColumnMeta.Type)
 		*dest = nil
 		return nil
 	}
 	logger.Debugf("snowflake data type: %v, raw value: %v", srcColumnMeta.Type, *srcValue)
+	if rawTimestampsEnabled(ctx) {
+		switch srcColumnMeta.Type {
+		case "date", "time", "timestamp_ntz", "timestamp_ltz", "timestamp_tz":
+			v, err := stringToRawTimestamp(srcColumnMeta, *srcValue)
+			if err != nil {
+				return err
+			}
+			*dest = v
+			return nil
+		}
+	}
 	switch srcColumnMeta.Type {
-	case "text", "fixed", "real", "variant", "object":
+	case "fixed":
+		if decimal128Enabled(ctx) {
+			num, ok := stringToDecimal(*srcValue, srcColumnMeta.Scale)
+			if !ok {
+				return fmt.Errorf("failed to convert %v to decimal with scale %v", *srcValue, srcColumnMeta.Scale)
+			}
+			*dest = num
+			return nil
+		}
+		*dest = *srcValue
+		return nil
+	case "text", "real", "variant", "object", "geography", "geometry":
+		// spatial values are already rendered in GEOGRAPHY_OUTPUT_FORMAT/GEOMETRY_OUTPUT_FORMAT,
+		// with WKB and EWKB as hex strings
 		*dest = *srcValue
 		return nil
 	case "date":
@@ -254,12 +329,27 @@ func stringToValue(dest *driver.Value, srcColumnMeta execResponseRowType, srcVal
 
 var decimalShift = new(big.Int).Exp(big.NewInt(2), big.NewInt(64), nil)
 
-func intToBigFloat(val int64, scale int64) *big.Float {
-	f := new(big.Float).SetInt64(val)
-	s := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(scale), nil))
+// pow10 returns 10 to the power of the absolute value of n
+func pow10(n int64) *big.Int {
+	if n < 0 {
+		n = -n
+	}
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(n), nil)
+}
+
+// scaleBigFloat divides f by 10^scale, multiplying it for a negative scale
+func scaleBigFloat(f *big.Float, scale int64) *big.Float {
+	s := new(big.Float).SetInt(pow10(scale))
+	if scale < 0 {
+		return new(big.Float).Mul(f, s)
+	}
 	return new(big.Float).Quo(f, s)
 }
 
+func intToBigFloat(val int64, scale int64) *big.Float {
+	return scaleBigFloat(new(big.Float).SetInt64(val), scale)
+}
+
 func decimalToBigInt(num decimal128.Num) *big.Int {
 	high := new(big.Int).SetInt64(num.HighBits())
 	low := new(big.Int).SetUint64(num.LowBits())
@@ -267,9 +357,36 @@ func decimalToBigInt(num decimal128.Num) *big.Int {
 }
 
 func decimalToBigFloat(num decimal128.Num, scale int64) *big.Float {
-	f := new(big.Float).SetInt(decimalToBigInt(num))
-	s := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(scale), nil))
-	return new(big.Float).Quo(f, s)
+	return scaleBigFloat(new(big.Float).SetInt(decimalToBigInt(num)), scale)
+}
+
+func bigIntToDecimal(b *big.Int) decimal128.Num {
+	var high, low big.Int
+	high.DivMod(b, decimalShift, &low)
+	return decimal128.New(high.Int64(), low.Uint64())
+}
+
+// rescaleDecimal converts the unscaled value num of scale from into the
+// unscaled value of scale to. Digits dropped by a smaller scale are truncated.
+func rescaleDecimal(num decimal128.Num, from, to int64) decimal128.Num {
+	if from == to {
+		return num
+	}
+	b := decimalToBigInt(num)
+	if to > from {
+		b.Mul(b, pow10(to-from))
+	} else {
+		b.Quo(b, pow10(from-to))
+	}
+	return bigIntToDecimal(b)
+}
+
+// arrowDecimalScale returns the scale declared by a decimal arrow column
+func arrowDecimalScale(srcValue array.Interface) int64 {
+	if t, ok := srcValue.DataType().(*arrow.Decimal128Type); ok {
+		return int64(t.Scale)
+	}
+	return 0
 }
 
 func stringIntToDecimal(src string) (decimal128.Num, bool) {
@@ -277,9 +394,7 @@ func stringIntToDecimal(src string) (decimal128.Num, bool) {
 	if !ok {
 		return decimal128.Num{}, ok
 	}
-	var high, low big.Int
-	high.QuoRem(b, decimalShift, &low)
-	return decimal128.New(high.Int64(), low.Uint64()), ok
+	return bigIntToDecimal(b), ok
 }
 
 func stringFloatToDecimal(src string, scale int64) (decimal128.Num, bool) {
@@ -292,15 +407,80 @@ func stringFloatToDecimal(src string, scale int64) (decimal128.Num, bool) {
 	if !n.IsInt() {
 		return decimal128.Num{}, false
 	}
-	var high, low, z big.Int
+	var z big.Int
 	n.Int(&z)
-	high.QuoRem(&z, decimalShift, &low)
-	return decimal128.New(high.Int64(), low.Uint64()), ok
+	return bigIntToDecimal(&z), ok
+}
+
+// stringToDecimal converts the decimal string representation of a fixed-point
+// value into its unscaled decimal128 form without going through a float.
+func stringToDecimal(src string, scale int64) (decimal128.Num, bool) {
+	intPart, fracPart := src, ""
+	if i := strings.IndexByte(src, '.'); i >= 0 {
+		intPart, fracPart = src[:i], src[i+1:]
+	}
+	if int64(len(fracPart)) > scale {
+		return decimal128.Num{}, false
+	}
+	b, ok := new(big.Int).SetString(intPart+fracPart+strings.Repeat("0", int(scale)-len(fracPart)), 10)
+	if !ok {
+		return decimal128.Num{}, false
+	}
+	return bigIntToDecimal(b), true
+}
+
+// arrowToRawTimestamp converts an Arrow date, time or timestamp column to the
+// int64 values returned with WithRawTimestamps. Values that Snowflake splits
+// into epoch seconds and nanoseconds are recombined at the column scale.
+func arrowToRawTimestamp(destcol *[]snowflakeValue, snowflakeType snowflakeType, srcColumnMeta execResponseRowType, srcValue array.Interface) error {
+	data := srcValue.Data()
+	scale := int(srcColumnMeta.Scale)
+	switch srcValue.DataType().ID() {
+	case arrow.DATE32:
+		for i, date32 := range array.NewDate32Data(data).Date32Values() {
+			if !srcValue.IsNull(i) {
+				(*destcol)[i] = int64(date32)
+			}
+		}
+	case arrow.INT32:
+		for i, v := range array.NewInt32Data(data).Int32Values() {
+			if !srcValue.IsNull(i) {
+				(*destcol)[i] = int64(v)
+			}
+		}
+	case arrow.INT64:
+		for i, v := range array.NewInt64Data(data).Int64Values() {
+			if !srcValue.IsNull(i) {
+				(*destcol)[i] = v
+			}
+		}
+	case arrow.STRUCT:
+		structData := array.NewStructData(data)
+		epoch := array.NewInt64Data(structData.Field(0).Data()).Int64Values()
+		if snowflakeType == timestampTzType && structData.NumField() == 2 {
+			// epoch is already at the column scale; the second field is the time zone
+			for i := range *destcol {
+				if !srcValue.IsNull(i) {
+					(*destcol)[i] = epoch[i]
+				}
+			}
+			return nil
+		}
+		fraction := array.NewInt32Data(structData.Field(1).Data()).Int32Values()
+		for i := range *destcol {
+			if !srcValue.IsNull(i) {
+				(*destcol)[i] = epoch[i]*int64(math.Pow10(scale)) + int64(fraction[i])/int64(math.Pow10(9-scale))
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported arrow data type %v for %v", srcValue.DataType(), srcColumnMeta.Type)
+	}
+	return nil
 }
 
 // Arrow Interface (Column) converter. This is called when Arrow chunks are downloaded to convert to the corresponding
 // row type.
-func arrowToValue(destcol *[]snowflakeValue, srcColumnMeta execResponseRowType, srcValue array.Interface) error {
+func arrowToValue(ctx context.Context, destcol *[]snowflakeValue, srcColumnMeta execResponseRowType, srcValue array.Interface) error {
 	data := srcValue.Data()
 	var err error
 	if len(*destcol) != srcValue.Data().Len() {
@@ -308,12 +488,30 @@ func arrowToValue(destcol *[]snowflakeValue, srcColumnMeta execResponseRowType,
 	}
 	logger.Debugf("snowflake data type: %v, arrow data type: %v", srcColumnMeta.Type, srcValue.DataType())
 
-	switch getSnowflakeType(strings.ToUpper(srcColumnMeta.Type)) {
+	snowflakeType := getSnowflakeType(strings.ToUpper(srcColumnMeta.Type))
+	if rawTimestampsEnabled(ctx) {
+		switch snowflakeType {
+		case dateType, timeType, timestampNtzType, timestampLtzType, timestampTzType:
+			if rawErr := arrowToRawTimestamp(destcol, snowflakeType, srcColumnMeta, srcValue); rawErr != nil {
+				return rawErr
+			}
+			return err
+		}
+	}
+
+	switch snowflakeType {
 	case fixedType:
+		if decimal128Enabled(ctx) {
+			return arrowToDecimal(destcol, srcColumnMeta, srcValue)
+		}
 		switch srcValue.DataType().ID() {
 		case arrow.DECIMAL:
+			// the values are unscaled at the scale of the arrow column, which
+			// may differ from the scale of the result metadata
+			arrowScale := arrowDecimalScale(srcValue)
 			for i, num := range array.NewDecimal128Data(data).Values() {
 				if !srcValue.IsNull(i) {
+					num = rescaleDecimal(num, arrowScale, srcColumnMeta.Scale)
 					if srcColumnMeta.Scale == 0 {
 						(*destcol)[i] = decimalToBigInt(num)
 					} else {
@@ -383,6 +581,24 @@ func arrowToValue(destcol *[]snowflakeValue, srcColumnMeta execResponseRowType,
 		}
 		return err
 	case textType, arrayType, variantType, objectType:
+		switch srcValue.DataType().ID() {
+		case arrow.STRUCT, arrow.LIST:
+			// structured OBJECT and ARRAY columns are sent as arrow structs
+			// and lists instead of JSON text
+			for i := range *destcol {
+				if !srcValue.IsNull(i) {
+					v, structErr := arrowToStructuredValue(srcValue, i)
+					if structErr != nil {
+						return structErr
+					}
+					(*destcol)[i] = v
+				}
+			}
+			return err
+		case arrow.MAP:
+			// the vendored arrow package has no map arrays to read them with
+			return fmt.Errorf("unsupported arrow data type %v for %v", srcValue.DataType(), srcColumnMeta.Type)
+		}
 		strings := array.NewStringData(data)
 		for i := range *destcol {
 			if !srcValue.IsNull(i) {
@@ -390,6 +606,24 @@ func arrowToValue(destcol *[]snowflakeValue, srcColumnMeta execResponseRowType,
 			}
 		}
 		return err
+	case geographyType, geometryType:
+		// WKB and EWKB are sent as binary; render them as hex strings like the JSON results
+		if srcValue.DataType().ID() == arrow.BINARY {
+			binaryData := array.NewBinaryData(data)
+			for i := range *destcol {
+				if !srcValue.IsNull(i) {
+					(*destcol)[i] = strings.ToUpper(hex.EncodeToString(binaryData.Value(i)))
+				}
+			}
+			return err
+		}
+		stringData := array.NewStringData(data)
+		for i := range *destcol {
+			if !srcValue.IsNull(i) {
+				(*destcol)[i] = stringData.Value(i)
+			}
+		}
+		return err
 	case binaryType:
 		binaryData := array.NewBinaryData(data)
 		for i := range *destcol {
@@ -408,10 +642,10 @@ func arrowToValue(destcol *[]snowflakeValue, srcColumnMeta execResponseRowType,
 		return err
 	case timeType:
 		if srcValue.DataType().ID() == arrow.INT64 {
-			for i, int64 := range array.NewInt64Data(data).Int64Values() {
+			for i, v := range array.NewInt64Data(data).Int64Values() {
 				if !srcValue.IsNull(i) {
 					t0 := time.Time{}
-					(*destcol)[i] = t0.Add(time.Duration(int64))
+					(*destcol)[i] = t0.Add(time.Duration(v * int64(math.Pow10(9-int(srcColumnMeta.Scale)))))
 				}
 			}
 		} else {
@@ -492,6 +726,109 @@ func arrowToValue(destcol *[]snowflakeValue, srcColumnMeta execResponseRowType,
 	return err
 }
 
+// arrowToStructuredValue converts the element i of a structured OBJECT or
+// ARRAY column, or of one of their fields, into a map[string]interface{},
+// []interface{} or the Go value of a scalar field.
+func arrowToStructuredValue(srcValue array.Interface, i int) (interface{}, error) {
+	if srcValue.IsNull(i) {
+		return nil, nil
+	}
+	switch a := srcValue.(type) {
+	case *array.Struct:
+		st := a.DataType().(*arrow.StructType)
+		m := make(map[string]interface{}, a.NumField())
+		for f := 0; f < a.NumField(); f++ {
+			v, err := arrowToStructuredValue(a.Field(f), i)
+			if err != nil {
+				return nil, err
+			}
+			m[st.Field(f).Name] = v
+		}
+		return m, nil
+	case *array.List:
+		j := i + a.Data().Offset()
+		offsets := a.Offsets()
+		l := make([]interface{}, 0, offsets[j+1]-offsets[j])
+		for k := offsets[j]; k < offsets[j+1]; k++ {
+			v, err := arrowToStructuredValue(a.ListValues(), int(k))
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, v)
+		}
+		return l, nil
+	case *array.Int8:
+		return int64(a.Value(i)), nil
+	case *array.Int16:
+		return int64(a.Value(i)), nil
+	case *array.Int32:
+		return int64(a.Value(i)), nil
+	case *array.Int64:
+		return a.Value(i), nil
+	case *array.Decimal128:
+		if scale := arrowDecimalScale(a); scale != 0 {
+			return decimalToBigFloat(a.Value(i), scale), nil
+		}
+		return decimalToBigInt(a.Value(i)), nil
+	case *array.Float32:
+		return float64(a.Value(i)), nil
+	case *array.Float64:
+		return a.Value(i), nil
+	case *array.Boolean:
+		return a.Value(i), nil
+	case *array.String:
+		return a.Value(i), nil
+	case *array.Binary:
+		return a.Value(i), nil
+	case *array.Date32:
+		return time.Unix(int64(a.Value(i))*86400, 0).UTC(), nil
+	}
+	return nil, fmt.Errorf("unsupported arrow data type in a structured type: %v", srcValue.DataType())
+}
+
+// arrowToDecimal converts a fixed-point arrow column into decimal128.Num
+// values unscaled at the scale of the column metadata, matching the JSON path
+// when WithDecimal128 is set.
+func arrowToDecimal(destcol *[]snowflakeValue, srcColumnMeta execResponseRowType, srcValue array.Interface) error {
+	data := srcValue.Data()
+	switch srcValue.DataType().ID() {
+	case arrow.DECIMAL:
+		arrowScale := arrowDecimalScale(srcValue)
+		for i, num := range array.NewDecimal128Data(data).Values() {
+			if !srcValue.IsNull(i) {
+				(*destcol)[i] = rescaleDecimal(num, arrowScale, srcColumnMeta.Scale)
+			}
+		}
+	case arrow.INT64:
+		for i, val := range array.NewInt64Data(data).Int64Values() {
+			if !srcValue.IsNull(i) {
+				(*destcol)[i] = decimal128.FromI64(val)
+			}
+		}
+	case arrow.INT32:
+		for i, val := range array.NewInt32Data(data).Int32Values() {
+			if !srcValue.IsNull(i) {
+				(*destcol)[i] = decimal128.FromI64(int64(val))
+			}
+		}
+	case arrow.INT16:
+		for i, val := range array.NewInt16Data(data).Int16Values() {
+			if !srcValue.IsNull(i) {
+				(*destcol)[i] = decimal128.FromI64(int64(val))
+			}
+		}
+	case arrow.INT8:
+		for i, val := range array.NewInt8Data(data).Int8Values() {
+			if !srcValue.IsNull(i) {
+				(*destcol)[i] = decimal128.FromI64(int64(val))
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported arrow data type for fixed column: %v", srcValue.DataType())
+	}
+	return nil
+}
+
 type (
 	intArray          []int
 	int32Array        []int32
@@ -509,7 +846,9 @@ type (
 )
 
 // Array takes in a column of a row to be inserted via array binding, bulk or
-// otherwise, and converts it into a native snowflake type for binding
+// otherwise, and converts it into a native snowflake type for binding.
+// A *[]string, *[]int64 or *[]float64 wrapped by Array can also be passed to
+// Scan to read an ARRAY column into the slice.
 func Array(a interface{}, typ ...snowflakeType) interface{} {
 	switch t := a.(type) {
 	case []int:
@@ -586,6 +925,82 @@ func Array(a interface{}, typ ...snowflakeType) interface{} {
 	}
 }
 
+// Scan parses the JSON text of an ARRAY column into the slice wrapped by
+// Array. Elements that aren't strings are kept in their JSON form, and null
+// elements become empty strings.
+func (a *stringArray) Scan(src interface{}) error {
+	var elems []json.RawMessage
+	if err := scanJSONArray(src, &elems); err != nil || elems == nil {
+		*a = nil
+		return err
+	}
+	arr := make(stringArray, len(elems))
+	for i, e := range elems {
+		if err := json.Unmarshal(e, &arr[i]); err != nil {
+			// not a string or null
+			arr[i] = string(e)
+		}
+	}
+	*a = arr
+	return nil
+}
+
+// Scan parses the JSON text of an ARRAY column into the slice wrapped by
+// Array. Null elements become 0.
+func (a *int64Array) Scan(src interface{}) error {
+	var elems []*int64
+	if err := scanJSONArray(src, &elems); err != nil || elems == nil {
+		*a = nil
+		return err
+	}
+	arr := make(int64Array, len(elems))
+	for i, e := range elems {
+		if e != nil {
+			arr[i] = *e
+		}
+	}
+	*a = arr
+	return nil
+}
+
+// Scan parses the JSON text of an ARRAY column into the slice wrapped by
+// Array. Null elements become 0.
+func (a *float64Array) Scan(src interface{}) error {
+	var elems []*float64
+	if err := scanJSONArray(src, &elems); err != nil || elems == nil {
+		*a = nil
+		return err
+	}
+	arr := make(float64Array, len(elems))
+	for i, e := range elems {
+		if e != nil {
+			arr[i] = *e
+		}
+	}
+	*a = arr
+	return nil
+}
+
+// scanJSONArray unmarshals the JSON text of an ARRAY column into elems,
+// leaving it nil if the column is NULL
+func scanJSONArray(src interface{}, elems interface{}) error {
+	var text []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case string:
+		text = []byte(v)
+	case []byte:
+		text = v
+	default:
+		return fmt.Errorf("cannot scan %T into an array", src)
+	}
+	if err := json.Unmarshal(text, elems); err != nil {
+		return fmt.Errorf("cannot scan %q into an array: %v", text, err)
+	}
+	return nil
+}
+
 // snowflakeArrayToString converts the array binding to snowflake's native
 // string type. The string value differs whether it's directly bound or
 // uploaded via stream.
@@ -646,6 +1061,10 @@ func snowflakeArrayToString(nv *driver.NamedValue, stream bool) (snowflakeType,
 		t = binaryType
 		a := nv.Value.(*byteArray)
 		for _, x := range *a {
+			if x == nil {
+				arr = append(arr, nil) // a nil slice binds NULL
+				continue
+			}
 			v := hex.EncodeToString(x)
 			arr = append(arr, &v)
 		}
@@ -671,8 +1090,7 @@ func snowflakeArrayToString(nv *driver.NamedValue, stream bool) (snowflakeType,
 			if stream {
 				v = x.Format(format)
 			} else {
-				_, offset := x.Zone()
-				v = fmt.Sprintf("%v %v", x.UnixNano(), offset/60+1440)
+				v = fmt.Sprintf("%v %v", x.UnixNano(), TimestampTZOffset(x)+1440)
 			}
 			arr = append(arr, &v)
 		}
@@ -3,14 +3,20 @@
 package gosnowflake
 
 import (
+	"context"
+	"database/sql"
 	"database/sql/driver"
+	"encoding/hex"
 	"fmt"
 	"github.com/apache/arrow/go/arrow"
 	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/decimal128"
 	"github.com/apache/arrow/go/arrow/memory"
+	"math"
 	"math/big"
 	"math/cmplx"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -77,6 +83,8 @@ func TestSnowflakeTypeToGo(t *testing.T) {
 		{in: arrayType, scale: 0, out: reflect.TypeOf("")},
 		{in: binaryType, scale: 0, out: reflect.TypeOf([]byte{})},
 		{in: booleanType, scale: 0, out: reflect.TypeOf(true)},
+		{in: geographyType, scale: 0, out: reflect.TypeOf("")},
+		{in: geometryType, scale: 0, out: reflect.TypeOf("")},
 	}
 	for _, test := range testcases {
 		a := snowflakeTypeToGo(test.in, test.scale)
@@ -149,7 +157,7 @@ func TestStringToValue(t *testing.T) {
 		rowType = &execResponseRowType{
 			Type: tt,
 		}
-		err = stringToValue(&dest, *rowType, &source)
+		err = stringToValue(context.Background(), &dest, *rowType, &source)
 		if err == nil {
 			t.Errorf("should raise error. type: %v, value:%v", tt, source)
 		}
@@ -169,7 +177,7 @@ func TestStringToValue(t *testing.T) {
 			rowType = &execResponseRowType{
 				Type: tt,
 			}
-			err = stringToValue(&dest, *rowType, &ss)
+			err = stringToValue(context.Background(), &dest, *rowType, &ss)
 			if err == nil {
 				t.Errorf("should raise error. type: %v, value:%v", tt, source)
 			}
@@ -177,13 +185,28 @@ func TestStringToValue(t *testing.T) {
 	}
 
 	src := "1549491451.123456789"
-	if err = stringToValue(&dest, execResponseRowType{Type: "timestamp_ltz"}, &src); err != nil {
+	if err = stringToValue(context.Background(), &dest, execResponseRowType{Type: "timestamp_ltz"}, &src); err != nil {
 		t.Errorf("unexpected error: %v", err)
 	} else if ts, ok := dest.(time.Time); !ok {
 		t.Errorf("expected type: 'time.Time', got '%v'", reflect.TypeOf(dest))
 	} else if ts.UnixNano() != 1549491451123456789 {
 		t.Errorf("expected unix timestamp: 1549491451123456789, got %v", ts.UnixNano())
 	}
+
+	// literal results of TO_GEOGRAPHY('POINT(-122.35 37.55)') in the GEOJSON, WKT and WKB output formats
+	for _, geo := range []string{
+		"{\n  \"coordinates\": [\n    -122.35,\n    37.55\n  ],\n  \"type\": \"Point\"\n}",
+		"POINT(-122.35 37.55)",
+		"01010000006666666666965EC06666666666C64240",
+	} {
+		for _, tt := range []string{"geography", "geometry"} {
+			if err = stringToValue(context.Background(), &dest, execResponseRowType{Type: tt}, &geo); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if dest != geo {
+				t.Errorf("expected %v value: %v, got: %v", tt, geo, dest)
+			}
+		}
+	}
 }
 
 type tcArrayToString struct {
@@ -199,6 +222,7 @@ func TestArrayToString(t *testing.T) {
 		{in: driver.NamedValue{Value: &float64Array{6.7}}, typ: realType, out: []string{"6.7"}},
 		{in: driver.NamedValue{Value: &boolArray{true, false}}, typ: booleanType, out: []string{"true", "false"}},
 		{in: driver.NamedValue{Value: &stringArray{"foo", "bar", "baz"}}, typ: textType, out: []string{"foo", "bar", "baz"}},
+		{in: driver.NamedValue{Value: &byteArray{{0x01, 0xab}, {}}}, typ: binaryType, out: []string{"01ab", ""}},
 	}
 	for _, test := range testcases {
 		s, a := snowflakeArrayToString(&test.in, false)
@@ -250,7 +274,7 @@ func TestArrowToValue(t *testing.T) {
 			logical:  "fixed",
 			physical: "number(38,0)",
 			values:   []string{"10000000000000000000000000000000000000", "-12345678901234567890123456789012345678"},
-			builder:  array.NewDecimal128Builder(pool, &arrow.Decimal128Type{Precision: 30, Scale: 2}),
+			builder:  array.NewDecimal128Builder(pool, &arrow.Decimal128Type{Precision: 38, Scale: 0}),
 			append: func(b array.Builder, vs interface{}) {
 				for _, s := range vs.([]string) {
 					num, ok := stringIntToDecimal(s)
@@ -388,6 +412,44 @@ func TestArrowToValue(t *testing.T) {
 			builder: array.NewBinaryBuilder(pool, arrow.BinaryTypes.Binary),
 			append:  func(b array.Builder, vs interface{}) { b.(*array.BinaryBuilder).AppendValues(vs.([][]byte), valids) },
 		},
+		{
+			logical:  "geography",
+			physical: "geojson",
+			values:   []string{`{"coordinates":[-122.35,37.55],"type":"Point"}`, `{"coordinates":[0,0],"type":"Point"}`},
+			builder:  array.NewStringBuilder(pool),
+			append:   func(b array.Builder, vs interface{}) { b.(*array.StringBuilder).AppendValues(vs.([]string), valids) },
+			compare: func(src interface{}, dst []snowflakeValue) int {
+				for i, s := range src.([]string) {
+					if dst[i] != s {
+						return i
+					}
+				}
+				return -1
+			},
+		},
+		{
+			logical:  "geometry",
+			physical: "wkb",
+			values:   []string{"01010000006666666666965EC06666666666C64240", "010100000000000000000000000000000000000000"},
+			builder:  array.NewBinaryBuilder(pool, arrow.BinaryTypes.Binary),
+			append: func(b array.Builder, vs interface{}) {
+				for _, s := range vs.([]string) {
+					wkb, err := hex.DecodeString(s)
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.(*array.BinaryBuilder).Append(wkb)
+				}
+			},
+			compare: func(src interface{}, dst []snowflakeValue) int {
+				for i, s := range src.([]string) {
+					if dst[i] != s {
+						return i
+					}
+				}
+				return -1
+			},
+		},
 		{
 			logical: "date",
 			values:  []time.Time{time.Now(), localTime},
@@ -533,7 +595,7 @@ func TestArrowToValue(t *testing.T) {
 			meta := tc.rowType
 			meta.Type = tc.logical
 
-			err := arrowToValue(&dest, meta, arr)
+			err := arrowToValue(context.Background(), &dest, meta, arr)
 			if err != nil {
 				t.Fatalf("error: %s", err)
 			}
@@ -555,3 +617,723 @@ func TestArrowToValue(t *testing.T) {
 
 	}
 }
+
+func TestArrowToValueRescalesDecimal(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	for _, tc := range []struct {
+		arrowScale int32
+		metaScale  int64
+		unscaled   []string
+		expected   []string
+	}{
+		{arrowScale: 2, metaScale: 4, unscaled: []string{"12345", "-12345"}, expected: []string{"123.4500", "-123.4500"}},
+		{arrowScale: 4, metaScale: 2, unscaled: []string{"1234500", "-1234500"}, expected: []string{"123.45", "-123.45"}},
+		{arrowScale: 2, metaScale: 0, unscaled: []string{"4200", "-4200"}, expected: []string{"42", "-42"}},
+		{arrowScale: -2, metaScale: 0, unscaled: []string{"42", "-42"}, expected: []string{"4200", "-4200"}},
+		{arrowScale: 0, metaScale: 2, unscaled: []string{"100000000000000000000000000000000000", "-1"}, expected: []string{"100000000000000000000000000000000000.00", "-1.00"}},
+	} {
+		t.Run(fmt.Sprintf("arrow scale %v, metadata scale %v", tc.arrowScale, tc.metaScale), func(t *testing.T) {
+			meta := execResponseRowType{Type: "fixed", Precision: 38, Scale: tc.metaScale}
+			b := array.NewDecimal128Builder(pool, &arrow.Decimal128Type{Precision: 38, Scale: tc.arrowScale})
+			for _, s := range tc.unscaled {
+				num, ok := stringIntToDecimal(s)
+				if !ok {
+					t.Fatalf("failed to convert %v to decimal", s)
+				}
+				b.Append(num)
+			}
+			arr := b.NewArray()
+			defer arr.Release()
+
+			dest := make([]snowflakeValue, arr.Len())
+			if err := arrowToValue(context.Background(), &dest, meta, arr); err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			for i, v := range dest {
+				var got string
+				switch n := v.(type) {
+				case *big.Int:
+					got = n.String()
+				case *big.Float:
+					got = n.Text('f', int(tc.metaScale))
+				default:
+					t.Fatalf("unexpected type %v", reflect.TypeOf(v))
+				}
+				if got != tc.expected[i] {
+					t.Fatalf("unexpected value at index %v. expected: %v, got: %v", i, tc.expected[i], got)
+				}
+			}
+
+			dest = make([]snowflakeValue, arr.Len())
+			if err := arrowToValue(WithDecimal128(context.Background()), &dest, meta, arr); err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			for i, v := range dest {
+				expected := strings.Replace(tc.expected[i], ".", "", 1)
+				if got := decimalToBigInt(v.(decimal128.Num)).String(); got != expected {
+					t.Fatalf("unexpected unscaled value at index %v. expected: %v, got: %v", i, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestStringIntToDecimalNegative(t *testing.T) {
+	for _, s := range []string{"-1", "-5", "-18446744073709551616", "-12345678901234567890123456789012345678"} {
+		num, ok := stringIntToDecimal(s)
+		if !ok {
+			t.Fatalf("failed to convert %v to decimal", s)
+		}
+		if got := decimalToBigInt(num).String(); got != s {
+			t.Fatalf("unexpected value. expected: %v, got: %v", s, got)
+		}
+	}
+}
+
+func TestDecimal128JSONMatchesArrow(t *testing.T) {
+	ctx := WithDecimal128(context.Background())
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+
+	meta := execResponseRowType{Type: "fixed", Precision: 38, Scale: 2}
+	values := []string{"123456789012345678901234567890123456.78", "-0.05", "10.00", "0"}
+	unscaled := []string{"12345678901234567890123456789012345678", "-5", "1000", "0"}
+
+	b := array.NewDecimal128Builder(pool, &arrow.Decimal128Type{Precision: 38, Scale: 2})
+	for _, s := range unscaled {
+		num, ok := stringToDecimal(s, 0)
+		if !ok {
+			t.Fatalf("failed to convert %v to decimal", s)
+		}
+		b.Append(num)
+	}
+	b.AppendNull()
+	arr := b.NewArray()
+	defer arr.Release()
+
+	arrowDest := make([]snowflakeValue, arr.Len())
+	if err := arrowToValue(ctx, &arrowDest, meta, arr); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	for i, v := range values {
+		var jsonDest driver.Value
+		if err := stringToValue(ctx, &jsonDest, meta, &v); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		jsonNum, ok := jsonDest.(decimal128.Num)
+		if !ok {
+			t.Fatalf("expected type decimal128.Num, got %v", reflect.TypeOf(jsonDest))
+		}
+		arrowNum, ok := arrowDest[i].(decimal128.Num)
+		if !ok {
+			t.Fatalf("expected type decimal128.Num, got %v", reflect.TypeOf(arrowDest[i]))
+		}
+		if jsonNum != arrowNum {
+			t.Fatalf("value mismatch at index %v. json: %v, arrow: %v", i, decimalToBigInt(jsonNum), decimalToBigInt(arrowNum))
+		}
+		if decimalToBigInt(jsonNum).String() != unscaled[i] {
+			t.Fatalf("unexpected unscaled value. expected: %v, got: %v", unscaled[i], decimalToBigInt(jsonNum))
+		}
+	}
+	if arrowDest[len(values)] != nil {
+		t.Fatalf("expected nil for null value, got %v", arrowDest[len(values)])
+	}
+
+	var dest driver.Value
+	tooPrecise := "1.234"
+	if err := stringToValue(ctx, &dest, meta, &tooPrecise); err == nil {
+		t.Fatalf("should have failed to convert %v with scale %v", tooPrecise, meta.Scale)
+	}
+}
+
+func TestRawTimestamps(t *testing.T) {
+	ctx := WithRawTimestamps(context.Background())
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+
+	// 2019-02-06 14:17:31.123456789 UTC
+	const epochSec, sinceMidnight, days, nanos = int64(1549462651), int64(51451), int64(17933), int64(123456789)
+	const tzOffset = 1440 - 480
+	epochStruct := arrow.StructOf(
+		arrow.Field{Name: "epoch", Type: &arrow.Int64Type{}},
+		arrow.Field{Name: "fraction", Type: &arrow.Int32Type{}})
+	tzStruct := arrow.StructOf(
+		arrow.Field{Name: "epoch", Type: &arrow.Int64Type{}},
+		arrow.Field{Name: "timezone", Type: &arrow.Int32Type{}})
+	tzFractionStruct := arrow.StructOf(
+		arrow.Field{Name: "epoch", Type: &arrow.Int64Type{}},
+		arrow.Field{Name: "fraction", Type: &arrow.Int32Type{}},
+		arrow.Field{Name: "timezone", Type: &arrow.Int32Type{}})
+
+	newInt64Array := func(v int64) array.Interface {
+		b := array.NewInt64Builder(pool)
+		defer b.Release()
+		b.Append(v)
+		b.AppendNull()
+		return b.NewArray()
+	}
+	newStructArray := func(typ *arrow.StructType, fields ...int64) array.Interface {
+		b := array.NewStructBuilder(pool, typ)
+		defer b.Release()
+		b.Append(true)
+		b.FieldBuilder(0).(*array.Int64Builder).Append(fields[0])
+		for i, f := range fields[1:] {
+			b.FieldBuilder(i + 1).(*array.Int32Builder).Append(int32(f))
+		}
+		b.AppendNull()
+		for i := range fields {
+			b.FieldBuilder(i).AppendNull()
+		}
+		return b.NewArray()
+	}
+
+	check := func(meta execResponseRowType, json string, arr array.Interface, expected int64) {
+		defer arr.Release()
+		var dest driver.Value
+		if err := stringToValue(ctx, &dest, meta, &json); err != nil {
+			t.Fatalf("%v(%v): error: %v", meta.Type, meta.Scale, err)
+		}
+		if dest != expected {
+			t.Fatalf("%v(%v): json value mismatch. expected: %v, got: %v", meta.Type, meta.Scale, expected, dest)
+		}
+		arrowDest := make([]snowflakeValue, arr.Len())
+		if err := arrowToValue(ctx, &arrowDest, meta, arr); err != nil {
+			t.Fatalf("%v(%v): error: %v", meta.Type, meta.Scale, err)
+		}
+		if arrowDest[0] != expected {
+			t.Fatalf("%v(%v): arrow value mismatch. expected: %v, got: %v", meta.Type, meta.Scale, expected, arrowDest[0])
+		}
+		if arrowDest[1] != nil {
+			t.Fatalf("%v(%v): expected nil for null value, got %v", meta.Type, meta.Scale, arrowDest[1])
+		}
+	}
+
+	for scale := int64(0); scale <= 9; scale++ {
+		unit := int64(math.Pow10(int(scale)))
+		fraction := nanos / int64(math.Pow10(9-int(scale)))
+		frac := fmt.Sprintf(".%09d", nanos)[:scale+1]
+		if scale == 0 {
+			frac = ""
+		}
+		expected := epochSec*unit + fraction
+		for _, typ := range []string{"timestamp_ntz", "timestamp_ltz"} {
+			meta := execResponseRowType{Type: typ, Scale: scale}
+			json := fmt.Sprintf("%v%v", epochSec, frac)
+			check(meta, json, newInt64Array(expected), expected)
+			check(meta, json, newStructArray(epochStruct, epochSec, nanos), expected)
+		}
+
+		meta := execResponseRowType{Type: "timestamp_tz", Scale: scale}
+		json := fmt.Sprintf("%v%v %v", epochSec, frac, tzOffset)
+		check(meta, json, newStructArray(tzStruct, expected, tzOffset), expected)
+		check(meta, json, newStructArray(tzFractionStruct, epochSec, nanos, tzOffset), expected)
+
+		meta = execResponseRowType{Type: "time", Scale: scale}
+		expected = sinceMidnight*unit + fraction
+		json = fmt.Sprintf("%v%v", sinceMidnight, frac)
+		if scale <= 4 {
+			b := array.NewInt32Builder(pool)
+			b.Append(int32(expected))
+			b.AppendNull()
+			check(meta, json, b.NewArray(), expected)
+			b.Release()
+		} else {
+			check(meta, json, newInt64Array(expected), expected)
+		}
+	}
+
+	b := array.NewDate32Builder(pool)
+	b.Append(arrow.Date32(days))
+	b.AppendNull()
+	check(execResponseRowType{Type: "date"}, fmt.Sprint(days), b.NewArray(), days)
+	b.Release()
+
+	var dest driver.Value
+	beforeEpoch := "-1.5"
+	if err := stringToValue(ctx, &dest, execResponseRowType{Type: "timestamp_ntz", Scale: 3}, &beforeEpoch); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if dest != int64(-1500) {
+		t.Fatalf("unexpected value before epoch. expected: -1500, got: %v", dest)
+	}
+}
+
+func TestArrowToValueTimestampTzNull(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	const epochSec, nanos, tzOffset = int64(1549462651), int32(123456789), int32(1440 - 480)
+	tzStruct := arrow.StructOf(
+		arrow.Field{Name: "epoch", Type: &arrow.Int64Type{}},
+		arrow.Field{Name: "timezone", Type: &arrow.Int32Type{}})
+	tzFractionStruct := arrow.StructOf(
+		arrow.Field{Name: "epoch", Type: &arrow.Int64Type{}},
+		arrow.Field{Name: "fraction", Type: &arrow.Int32Type{}},
+		arrow.Field{Name: "timezone", Type: &arrow.Int32Type{}})
+	loc := Location(int(tzOffset) - 1440)
+
+	for _, tc := range []struct {
+		typ      *arrow.StructType
+		fields   []int64
+		expected time.Time
+	}{
+		{typ: tzStruct, fields: []int64{epochSec, int64(tzOffset)}, expected: time.Unix(epochSec, 0).In(loc)},
+		{typ: tzFractionStruct, fields: []int64{epochSec, int64(nanos), int64(tzOffset)}, expected: time.Unix(epochSec, int64(nanos)).In(loc)},
+	} {
+		b := array.NewStructBuilder(pool, tc.typ)
+		// valid, null, valid, null
+		for row := 0; row < 4; row++ {
+			if row%2 == 1 {
+				b.AppendNull() // appends a null to every field too
+				continue
+			}
+			b.Append(true)
+			b.FieldBuilder(0).(*array.Int64Builder).Append(tc.fields[0])
+			for i := 1; i < len(tc.fields); i++ {
+				b.FieldBuilder(i).(*array.Int32Builder).Append(int32(tc.fields[i]))
+			}
+		}
+		arr := b.NewArray()
+		b.Release()
+
+		dest := make([]snowflakeValue, arr.Len())
+		if err := arrowToValue(context.Background(), &dest, execResponseRowType{Type: "timestamp_tz", Scale: 9}, arr); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		arr.Release()
+		for i, v := range dest {
+			if i%2 == 1 {
+				if v != nil {
+					t.Fatalf("expected nil for the null value at index %v, got %v", i, v)
+				}
+				continue
+			}
+			tm, ok := v.(time.Time)
+			if !ok || !tm.Equal(tc.expected) || tm.Location() != loc {
+				t.Fatalf("unexpected value at index %v. expected: %v, got: %v", i, tc.expected, v)
+			}
+		}
+	}
+
+	var dest driver.Value = time.Now()
+	if err := stringToValue(context.Background(), &dest, execResponseRowType{Type: "timestamp_tz", Scale: 9}, nil); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if dest != nil {
+		t.Fatalf("expected nil for a null value, got %v", dest)
+	}
+}
+
+func TestArrowToValueStructuredTypes(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	// OBJECT(a INT, b TEXT)
+	objType := arrow.StructOf(
+		arrow.Field{Name: "a", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		arrow.Field{Name: "b", Type: arrow.BinaryTypes.String, Nullable: true})
+	ob := array.NewStructBuilder(pool, objType)
+	ob.Append(true)
+	ob.FieldBuilder(0).(*array.Int64Builder).Append(1)
+	ob.FieldBuilder(1).(*array.StringBuilder).Append("x")
+	ob.AppendNull()
+	ob.Append(true)
+	ob.FieldBuilder(0).(*array.Int64Builder).AppendNull()
+	ob.FieldBuilder(1).(*array.StringBuilder).Append("y")
+	obj := ob.NewArray()
+	ob.Release()
+	defer obj.Release()
+
+	dest := make([]snowflakeValue, obj.Len())
+	if err := arrowToValue(context.Background(), &dest, execResponseRowType{Type: "object"}, obj); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if m, ok := dest[0].(map[string]interface{}); !ok || m["a"] != int64(1) || m["b"] != "x" {
+		t.Fatalf("unexpected object: %#v", dest[0])
+	}
+	if dest[1] != nil {
+		t.Fatalf("expected nil for a null object, got %#v", dest[1])
+	}
+	if m, ok := dest[2].(map[string]interface{}); !ok || m["a"] != nil || m["b"] != "y" {
+		t.Fatalf("unexpected object with a null field: %#v", dest[2])
+	}
+
+	// ARRAY(OBJECT(a INT, b TEXT))
+	lb := array.NewListBuilder(pool, objType)
+	vb := lb.ValueBuilder().(*array.StructBuilder)
+	lb.Append(true)
+	for i, b := range []string{"p", "q"} {
+		vb.Append(true)
+		vb.FieldBuilder(0).(*array.Int64Builder).Append(int64(i))
+		vb.FieldBuilder(1).(*array.StringBuilder).Append(b)
+	}
+	lb.Append(true) // empty array
+	lb.AppendNull()
+	lst := lb.NewArray()
+	lb.Release()
+	defer lst.Release()
+
+	dest = make([]snowflakeValue, lst.Len())
+	if err := arrowToValue(context.Background(), &dest, execResponseRowType{Type: "array"}, lst); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	l, ok := dest[0].([]interface{})
+	if !ok || len(l) != 2 || l[1].(map[string]interface{})["b"] != "q" || l[1].(map[string]interface{})["a"] != int64(1) {
+		t.Fatalf("unexpected array: %#v", dest[0])
+	}
+	if l, ok := dest[1].([]interface{}); !ok || len(l) != 0 {
+		t.Fatalf("unexpected empty array: %#v", dest[1])
+	}
+	if dest[2] != nil {
+		t.Fatalf("expected nil for a null array, got %#v", dest[2])
+	}
+}
+
+// mapDataType stands for the arrow map type, which the vendored arrow
+// package doesn't implement
+type mapDataType struct{}
+
+func (mapDataType) ID() arrow.Type { return arrow.MAP }
+func (mapDataType) Name() string   { return "map" }
+
+// mapArray is an array reporting the map type
+type mapArray struct {
+	array.Interface
+}
+
+func (mapArray) DataType() arrow.DataType { return mapDataType{} }
+
+func TestArrowToValueMapUnsupported(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	b := array.NewStringBuilder(pool)
+	b.Append(`{"k": "v"}`)
+	arr := b.NewArray()
+	b.Release()
+	defer arr.Release()
+
+	dest := make([]snowflakeValue, arr.Len())
+	err := arrowToValue(context.Background(), &dest, execResponseRowType{Type: "object"}, mapArray{arr})
+	if err == nil || !strings.HasPrefix(err.Error(), "unsupported arrow data type") {
+		t.Fatalf("expected an unsupported type error for a map column, got: %v", err)
+	}
+	if dest[0] != nil {
+		t.Fatalf("the map should not have been read as a string, got: %#v", dest[0])
+	}
+}
+
+func TestScanArray(t *testing.T) {
+	strs := []string{"stale"}
+	for _, tc := range []struct {
+		src interface{}
+		out []string
+	}{
+		{src: "[\n  \"a\",\n  \"b, c\",\n  \"\"\n]", out: []string{"a", "b, c", ""}},
+		{src: []byte(`["a", null, 1.5, {"k": "v"}]`), out: []string{"a", "", "1.5", `{"k": "v"}`}},
+		{src: "[null, null]", out: []string{"", ""}},
+		{src: "[]", out: []string{}},
+		{src: nil, out: nil},
+	} {
+		if err := Array(&strs).(*stringArray).Scan(tc.src); err != nil {
+			t.Fatalf("failed to scan %v. err: %v", tc.src, err)
+		}
+		if !reflect.DeepEqual(strs, tc.out) {
+			t.Fatalf("unexpected strings scanned from %v. expected: %#v, got: %#v", tc.src, tc.out, strs)
+		}
+	}
+
+	var ints []int64
+	if err := Array(&ints).(*int64Array).Scan("[1, null, -9223372036854775808]"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int64{1, 0, math.MinInt64}; !reflect.DeepEqual(ints, expected) {
+		t.Fatalf("unexpected ints. expected: %v, got: %v", expected, ints)
+	}
+	if err := Array(&ints).(*int64Array).Scan(`[1.5]`); err == nil {
+		t.Fatal("a fraction should not have been scanned into an int64")
+	}
+
+	var floats []float64
+	if err := Array(&floats).(*float64Array).Scan("[\n  1.5,\n  null,\n  2e3\n]"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []float64{1.5, 0, 2000}; !reflect.DeepEqual(floats, expected) {
+		t.Fatalf("unexpected floats. expected: %v, got: %v", expected, floats)
+	}
+	if err := Array(&floats).(*float64Array).Scan(`{"a": 1}`); err == nil {
+		t.Fatal("an object should not have been scanned into an array")
+	}
+}
+
+func TestScanNullTypes(t *testing.T) {
+	str := func(s string) *string { return &s }
+	ntz := time.Unix(1600000000, 123000000).UTC()
+	date := time.Unix(18000*86400, 0).UTC()
+	scan := func(t *testing.T, v driver.Value, expected interface{}) {
+		// a NULL scans into the invalid zero value of every sql.Null* type
+		for _, src := range []driver.Value{v, nil} {
+			dest := reflect.New(reflect.TypeOf(expected))
+			if err := dest.Interface().(sql.Scanner).Scan(src); err != nil {
+				t.Fatalf("failed to scan %#v into %T. err: %v", src, expected, err)
+			}
+			want := expected
+			if src == nil {
+				want = reflect.Zero(reflect.TypeOf(expected)).Interface()
+			}
+			if got := dest.Elem().Interface(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("unexpected value scanned from %#v. expected: %#v, got: %#v", src, want, got)
+			}
+		}
+	}
+
+	jsonTestcases := []struct {
+		rowType  execResponseRowType
+		value    *string
+		expected []interface{}
+	}{
+		{execResponseRowType{Type: "fixed"}, str("42"), []interface{}{
+			sql.NullInt64{Int64: 42, Valid: true}, sql.NullInt32{Int32: 42, Valid: true},
+			sql.NullFloat64{Float64: 42, Valid: true}, sql.NullString{String: "42", Valid: true}}},
+		{execResponseRowType{Type: "fixed", Scale: 2}, str("1.50"), []interface{}{
+			sql.NullFloat64{Float64: 1.5, Valid: true}, sql.NullString{String: "1.50", Valid: true}}},
+		{execResponseRowType{Type: "real"}, str("1.5"), []interface{}{
+			sql.NullFloat64{Float64: 1.5, Valid: true}}},
+		{execResponseRowType{Type: "boolean"}, str("1"), []interface{}{
+			sql.NullBool{Bool: true, Valid: true}}},
+		{execResponseRowType{Type: "text"}, str("abc"), []interface{}{
+			sql.NullString{String: "abc", Valid: true}}},
+		{execResponseRowType{Type: "date"}, str("18000"), []interface{}{
+			sql.NullTime{Time: date, Valid: true}}},
+		{execResponseRowType{Type: "timestamp_ntz", Scale: 9}, str("1600000000.123000000"), []interface{}{
+			sql.NullTime{Time: ntz, Valid: true}}},
+	}
+	for _, tc := range jsonTestcases {
+		t.Run("json "+tc.rowType.Type, func(t *testing.T) {
+			var v, null driver.Value
+			if err := stringToValue(context.Background(), &v, tc.rowType, tc.value); err != nil {
+				t.Fatal(err)
+			}
+			if err := stringToValue(context.Background(), &null, tc.rowType, nil); err != nil {
+				t.Fatal(err)
+			}
+			if null != nil {
+				t.Fatalf("a NULL should have been converted to nil. got: %#v", null)
+			}
+			for _, expected := range tc.expected {
+				scan(t, v, expected)
+			}
+		})
+	}
+
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	arrowTestcases := []struct {
+		rowType  execResponseRowType
+		build    func() array.Interface
+		expected []interface{}
+	}{
+		{execResponseRowType{Type: "fixed"}, func() array.Interface {
+			b := array.NewInt64Builder(pool)
+			b.AppendValues([]int64{42}, nil)
+			b.AppendNull()
+			return b.NewArray()
+		}, []interface{}{
+			sql.NullInt64{Int64: 42, Valid: true}, sql.NullInt32{Int32: 42, Valid: true},
+			sql.NullFloat64{Float64: 42, Valid: true}}},
+		{execResponseRowType{Type: "fixed", Precision: 38}, func() array.Interface {
+			b := array.NewDecimal128Builder(pool, &arrow.Decimal128Type{Precision: 38, Scale: 0})
+			b.Append(decimal128.FromI64(42))
+			b.AppendNull()
+			return b.NewArray()
+		}, []interface{}{
+			sql.NullInt64{Int64: 42, Valid: true}, sql.NullFloat64{Float64: 42, Valid: true}}},
+		{execResponseRowType{Type: "fixed", Scale: 2}, func() array.Interface {
+			b := array.NewInt64Builder(pool)
+			b.AppendValues([]int64{150}, nil)
+			b.AppendNull()
+			return b.NewArray()
+		}, []interface{}{
+			sql.NullFloat64{Float64: 1.5, Valid: true}}},
+		{execResponseRowType{Type: "real"}, func() array.Interface {
+			b := array.NewFloat64Builder(pool)
+			b.AppendValues([]float64{1.5}, nil)
+			b.AppendNull()
+			return b.NewArray()
+		}, []interface{}{
+			sql.NullFloat64{Float64: 1.5, Valid: true}}},
+		{execResponseRowType{Type: "boolean"}, func() array.Interface {
+			b := array.NewBooleanBuilder(pool)
+			b.AppendValues([]bool{true}, nil)
+			b.AppendNull()
+			return b.NewArray()
+		}, []interface{}{
+			sql.NullBool{Bool: true, Valid: true}}},
+		{execResponseRowType{Type: "text"}, func() array.Interface {
+			b := array.NewStringBuilder(pool)
+			b.AppendValues([]string{"abc"}, nil)
+			b.AppendNull()
+			return b.NewArray()
+		}, []interface{}{
+			sql.NullString{String: "abc", Valid: true}}},
+		{execResponseRowType{Type: "date"}, func() array.Interface {
+			b := array.NewDate32Builder(pool)
+			b.AppendValues([]arrow.Date32{18000}, nil)
+			b.AppendNull()
+			return b.NewArray()
+		}, []interface{}{
+			sql.NullTime{Time: date, Valid: true}}},
+		{execResponseRowType{Type: "timestamp_ntz", Scale: 9}, func() array.Interface {
+			b := array.NewInt64Builder(pool)
+			b.AppendValues([]int64{ntz.UnixNano()}, nil)
+			b.AppendNull()
+			return b.NewArray()
+		}, []interface{}{
+			sql.NullTime{Time: ntz, Valid: true}}},
+	}
+	for _, tc := range arrowTestcases {
+		t.Run("arrow "+tc.rowType.Type, func(t *testing.T) {
+			arr := tc.build()
+			defer arr.Release()
+			dest := make([]snowflakeValue, arr.Len())
+			if err := arrowToValue(context.Background(), &dest, tc.rowType, arr); err != nil {
+				t.Fatal(err)
+			}
+			if dest[1] != nil {
+				t.Fatalf("a NULL should have been converted to nil. got: %#v", dest[1])
+			}
+			for _, expected := range tc.expected {
+				scan(t, dest[0], expected)
+			}
+		})
+	}
+}
+
+func TestScanDateInZones(t *testing.T) {
+	defer func(loc *time.Location) { time.Local = loc }(time.Local)
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	testcases := []struct {
+		days     int32
+		expected Date
+	}{
+		{-1, Date{1969, time.December, 31}},
+		{0, Date{1970, time.January, 1}},
+		{18261, Date{2019, time.December, 31}},
+		{18262, Date{2020, time.January, 1}},
+		{19417, Date{2023, time.March, 1}},
+	}
+	for _, zone := range []string{"America/Los_Angeles", "Pacific/Kiritimati", "Asia/Kolkata"} {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			t.Skipf("time zone %v is unavailable: %v", zone, err)
+		}
+		time.Local = loc
+		for _, tc := range testcases {
+			var jsonDest driver.Value
+			src := fmt.Sprint(tc.days)
+			if err = stringToValue(context.Background(), &jsonDest, execResponseRowType{Type: "date"}, &src); err != nil {
+				t.Fatal(err)
+			}
+			b := array.NewDate32Builder(pool)
+			b.Append(arrow.Date32(tc.days))
+			arr := b.NewArray()
+			arrowDest := make([]snowflakeValue, 1)
+			if err = arrowToValue(context.Background(), &arrowDest, execResponseRowType{Type: "date"}, arr); err != nil {
+				t.Fatal(err)
+			}
+			arr.Release()
+			b.Release()
+			if jsonDest != arrowDest[0] {
+				t.Fatalf("zone: %v, days: %v. the json and arrow values differ: %v, %v", zone, tc.days, jsonDest, arrowDest[0])
+			}
+
+			var d Date
+			if err = d.Scan(jsonDest); err != nil {
+				t.Fatal(err)
+			}
+			if d != tc.expected {
+				t.Fatalf("zone: %v, days: %v. expected: %v, got: %v", zone, tc.days, tc.expected, d)
+			}
+			if d.String() != tc.expected.In(time.UTC).Format("2006-01-02") {
+				t.Fatalf("unexpected date string: %v", d)
+			}
+		}
+
+		// a date bound or built in the local zone keeps its date
+		var d Date
+		if err = d.Scan(time.Date(2020, time.January, 1, 0, 0, 0, 0, loc)); err != nil || d != (Date{2020, time.January, 1}) {
+			t.Fatalf("zone: %v. unexpected date of local midnight: %v, err: %v", zone, d, err)
+		}
+	}
+
+	var d Date
+	if err := d.Scan("2020-02-29"); err != nil || d != (Date{2020, time.February, 29}) {
+		t.Fatalf("unexpected date of a string: %v, err: %v", d, err)
+	}
+	if err := d.Scan(nil); err == nil {
+		t.Fatal("scanning NULL into a Date should fail")
+	}
+}
+
+func TestScanTimeOfDay(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	// 14:17:31.123456789
+	const sinceMidnight, nanos = int64(51451), int64(123456789)
+	for _, scale := range []int{0, 3, 6, 9} {
+		fraction := nanos / int64(math.Pow10(9-scale))
+		frac := fmt.Sprintf(".%09d", nanos)[:scale+1]
+		if scale == 0 {
+			frac = ""
+		}
+		meta := execResponseRowType{Type: "time", Scale: int64(scale)}
+
+		var jsonDest driver.Value
+		src := fmt.Sprintf("%v%v", sinceMidnight, frac)
+		if err := stringToValue(context.Background(), &jsonDest, meta, &src); err != nil {
+			t.Fatal(err)
+		}
+		var arr array.Interface
+		scaled := sinceMidnight*int64(math.Pow10(scale)) + fraction
+		if scale <= 4 {
+			b := array.NewInt32Builder(pool)
+			b.Append(int32(scaled))
+			arr = b.NewArray()
+			b.Release()
+		} else {
+			b := array.NewInt64Builder(pool)
+			b.Append(scaled)
+			arr = b.NewArray()
+			b.Release()
+		}
+		arrowDest := make([]snowflakeValue, 1)
+		if err := arrowToValue(context.Background(), &arrowDest, meta, arr); err != nil {
+			t.Fatal(err)
+		}
+		arr.Release()
+		if jsonDest != arrowDest[0] {
+			t.Fatalf("scale: %v. the json and arrow values differ: %v, %v", scale, jsonDest, arrowDest[0])
+		}
+
+		var tod TimeOfDay
+		if err := tod.Scan(jsonDest); err != nil {
+			t.Fatal(err)
+		}
+		expected := sinceMidnight*int64(time.Second) + fraction*int64(math.Pow10(9-scale))
+		if tod.Nanoseconds != expected {
+			t.Fatalf("scale: %v. expected %v nanoseconds, got: %v", scale, expected, tod.Nanoseconds)
+		}
+
+		str := "14:17:31" + frac
+		if err := tod.Scan(str); err != nil {
+			t.Fatal(err)
+		}
+		if tod.Nanoseconds != expected || tod.String() != str {
+			t.Fatalf("scale: %v. unexpected time of day of %v: %+v", scale, str, tod)
+		}
+	}
+
+	var tod TimeOfDay
+	if err := tod.Scan(nil); err == nil {
+		t.Fatal("should have failed to scan NULL")
+	}
+	tod = TimeOfDay{Nanoseconds: sinceMidnight*int64(time.Second) + 120*int64(time.Millisecond)}
+	if str := tod.String(); str != "14:17:31.12" {
+		t.Fatalf("the trailing zeros of the fraction should have been trimmed, got: %v", str)
+	}
+}
@@ -1,8 +1,10 @@
 package gosnowflake
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -45,3 +47,26 @@ func TestLogEntryCtx(t *testing.T) {
 	//log.WithContext(ctx2).Info("new log text 2")
 
 }
+
+func TestWithLogFields(t *testing.T) {
+	log := CreateDefaultLogger()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+
+	ctx := WithLogFields(context.Background(), map[string]interface{}{"request_id": "req-1", "tenant": "acme"})
+	ctx = WithLogFields(ctx, map[string]interface{}{"tenant": "globex", "LOG_SESSION_ID": "overridden"})
+	ctx = context.WithValue(ctx, SFSessionIDKey, "sessID1")
+	log.WithContext(ctx).Info("hello")
+
+	for _, field := range []string{"request_id=req-1", "tenant=globex", "LOG_SESSION_ID=sessID1"} {
+		if !strings.Contains(buf.String(), field) {
+			t.Fatalf("expected %v in the log entry. got: %v", field, buf.String())
+		}
+	}
+
+	buf.Reset()
+	log.WithContext(context.Background()).Info("hello")
+	if strings.Contains(buf.String(), "request_id") {
+		t.Fatalf("fields should only be logged with the context they were set on. got: %v", buf.String())
+	}
+}
@@ -25,6 +25,8 @@ const (
 	binaryType
 	timeType
 	booleanType
+	geographyType
+	geometryType
 	// the following are not snowflake types per se but internal types
 	nullType
 	sliceType
@@ -34,7 +36,8 @@ const (
 
 var snowflakeTypes = [...]string{"FIXED", "REAL", "TEXT", "DATE", "VARIANT",
 	"TIMESTAMP_LTZ", "TIMESTAMP_NTZ", "TIMESTAMP_TZ", "OBJECT", "ARRAY",
-	"BINARY", "TIME", "BOOLEAN", "NULL", "SLICE", "CHANGE_TYPE", "NOT_SUPPORTED"}
+	"BINARY", "TIME", "BOOLEAN", "GEOGRAPHY", "GEOMETRY", "NULL", "SLICE", "CHANGE_TYPE",
+	"NOT_SUPPORTED"}
 
 func (st snowflakeType) String() string {
 	return snowflakeTypes[st]
@@ -100,6 +103,10 @@ func dataTypeMode(v driver.Value) (tsmode snowflakeType, err error) {
 			tsmode = timestampTzType
 		case bytes.Equal(bd, DataTypeBinary):
 			tsmode = binaryType
+		case bytes.Equal(bd, DataTypeVariant):
+			tsmode = variantType
+		case bytes.Equal(bd, DataTypeObject):
+			tsmode = objectType
 		default:
 			return nullType, fmt.Errorf(errMsgInvalidByteArray, v)
 		}
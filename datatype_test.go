@@ -45,3 +45,15 @@ func TestDataTypeMode(t *testing.T) {
 		}
 	}
 }
+
+func TestGetSnowflakeTypeSpatial(t *testing.T) {
+	if tp := getSnowflakeType("GEOGRAPHY"); tp != geographyType {
+		t.Errorf("wrong data type: %v", tp)
+	}
+	if tp := getSnowflakeType("GEOMETRY"); tp != geometryType {
+		t.Errorf("wrong data type: %v", tp)
+	}
+	if tp := getSnowflakeType("NOT_SUPPORTED"); tp != nullType {
+		t.Errorf("wrong data type: %v", tp)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"fmt"
+	"time"
+)
+
+// Date is a calendar date without a time zone. Scanning a DATE column into a
+// Date avoids the off-by-one dates of a time.Time at midnight UTC that is
+// converted to a time zone west of UTC. Scan into a **Date for nullable
+// columns.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the date of t in the location of t.
+func DateOf(t time.Time) Date {
+	var d Date
+	d.Year, d.Month, d.Day = t.Date()
+	return d
+}
+
+// In returns the time at midnight of d in loc.
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// String returns d in the YYYY-MM-DD format.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// Scan sets d to the date of a DATE column, which the driver returns as a
+// time.Time at midnight UTC, or of a string in the YYYY-MM-DD format.
+func (d *Date) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case time.Time:
+		*d = DateOf(v)
+	case string:
+		return d.parse(v)
+	case []byte:
+		return d.parse(string(v))
+	case nil:
+		return fmt.Errorf("cannot scan NULL into a Date")
+	default:
+		return fmt.Errorf("cannot scan %T into a Date", src)
+	}
+	return nil
+}
+
+func (d *Date) parse(s string) error {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into a Date: %v", s, err)
+	}
+	*d = DateOf(t)
+	return nil
+}
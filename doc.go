@@ -38,6 +38,13 @@ mydb, schema is testschema, and warehouse is mywh:
 
 	db, err := sql.Open("snowflake", "jsmith:mypassword@myaccount/mydb/testschema?warehouse=mywh")
 
+To open a database handle with a Config built in code instead of a DSN, pass
+a Connector to sql.OpenDB. The connection pool then opens every connection
+with the Config as is, without formatting and parsing a DSN:
+
+	cfg := gosnowflake.Config{Account: "myaccount", User: "jsmith", Password: "mypassword", Warehouse: "mywh"}
+	db := sql.OpenDB(gosnowflake.NewConnector(gosnowflake.SnowflakeDriver{}, cfg))
+
 Connection Parameters
 
 The connection string (DSN) can contain both connection parameters (described below) and session parameters
@@ -85,7 +92,11 @@ The following connection parameters are supported:
 
 	* loginTimeout: Specifies the timeout, in seconds, for login. The default
 		is 60 seconds. The login request gives up after the timeout length if the
-		HTTP response is success.
+		HTTP response is success. Config.LoginMaxRetries sets how many times a login that failed because of the
+		network or the unavailability of Snowflake or the IdP, e.g. with HTTP 503, is tried again, waiting
+		Config.LoginRetryBackoff (1 second by default) before the first retry and twice as long before each next
+		one. Invalid credentials are not retried, nor are logins with an MFA passcode or through externalbrowser,
+		whose credentials can only be sent once.
 
 	* authenticator: Specifies the authenticator to use for authenticating user credentials:
 		- To use the internal Snowflake authenticator, specify snowflake (Default).
@@ -103,9 +114,16 @@ The following connection parameters are supported:
 
 	* client_session_keep_alive: Set to true have a heartbeat in the background every hour to keep the connection alive
 		such that the connection session will never expire. Care should be taken in using this option as it opens up
-		the access forever as long as the process is alive.
+		the access forever as long as the process is alive. Config.HeartbeatInterval changes the interval of
+		the heartbeat: 0 keeps the hourly heartbeat of client_session_keep_alive, a positive interval
+		heartbeats even without client_session_keep_alive and a negative interval disables the heartbeat.
 
-	* ocspFailOpen: true by default. Set to false to make OCSP check fail closed mode.
+	* ocspFailOpen: true by default. Set to false to make OCSP check fail closed mode. In fail open mode, a
+		connection is only rejected if a certificate is known to be revoked; if its status cannot be fetched,
+		e.g. because the OCSP responder is down or blocked, the connection proceeds. Fail closed mode also
+		rejects those connections, which is safer but makes connections fail during OCSP responder outages.
+		Config.OCSPFailClosed sets fail closed mode regardless of ocspFailOpen. It cannot be combined with
+		insecureMode.
 
 	* validateDefaultParameters: true by default. Set to false to disable checks on existence and privileges check for
 								 Database, Schema, Warehouse and Role when setting up the connection
@@ -125,6 +143,10 @@ A complete connection string looks similar to the following:
 Session-level parameters can also be set by using the SQL command "ALTER SESSION"
 (https://docs.snowflake.com/en/sql-reference/sql/alter-session.html).
 
+To be notified of those changes, set Config.OnSessionParametersChanged. Whenever the server returns session
+parameters, including their initial values at login, it is called with the lower-cased names and new values of the
+ones that changed, after Config.Params has been updated.
+
 Alternatively, use OpenWithConfig() function to create a database handle with the specified Config.
 
 Proxy
@@ -152,6 +174,11 @@ Users can use SetLogger in driver.go to set a customized logger for gosnowflake
 In order to enable debug logging for the driver, user could use SetLogLevel("debug") in SFLogger interface
 as shown in demo code at cmd/logger.go. To redirect the logs SFlogger.SetOutput method could do the work.
 
+To monitor the retries of requests to Snowflake and of result chunk downloads, set Config.RetryObserver. It is called
+before each retry with the number of the failed attempt, starting at 1, the request ID, the requested URL, the error
+or HTTP status the attempt failed with and how long the driver sleeps before retrying. The request ID of a chunk
+download is the one set with WithRequestID, if any, since the chunk URLs carry none.
+
 
 Query request ID
 
@@ -162,6 +189,19 @@ in place of the default randomized request ID. For example:
 	ctxWithID := WithRequestID(ctx, requestID)
 	rows, err := db.QueryContext(ctxWithID, query)
 
+Query ID
+
+Config.QueryIDObserver is called with the query ID and SQL text of every query that succeeds on the connection,
+including the ones the driver runs itself, such as the PUT of bind values. A channel set in the context with
+WithQueryIDChan receives the ID of a query first, as soon as it is submitted; the observer is called once the query
+has completed.
+
+Rewriting Queries
+
+Config.SQLRewriter transforms the SQL text of every query on the connection before it is sent, e.g. to add tags,
+hints or row filters. An error it returns aborts the query. It also sees the queries the driver runs itself, such as
+BEGIN, COMMIT and the creation of the stage of bind uploads, except for the PUT commands that upload the binds.
+
 Canceling Query by CtrlC
 
 From 0.5.0, a signal handling responsibility has moved to the applications. If you want to cancel a
@@ -267,6 +307,10 @@ The default value is 'JSON'. The default will change in the future.
 
 If the user attempts to set the parameter to an invalid value, an error is returned.
 
+If the parameter is ARROW but the server returns a result in the JSON format, e.g. because Arrow is disabled for the
+account, the driver logs a warning and ArrowRequestIgnored() of the rows returns true. To fail such queries instead,
+run them with a context from WithRequireArrow().
+
 The parameter name and the parameter value are case-insensitive.
 
 This parameter can be set only at the session level.
@@ -334,6 +378,18 @@ The table below lists the supported Snowflake SQL data types and the correspondi
 
 Note: SQL NULL values are converted to Golang nil values, and vice-versa.
 
+To read columns that may contain NULL values, Scan() into the sql.Null* type that matches the column: sql.NullInt64 or
+sql.NullInt32 for INTEGER, sql.NullFloat64 for NUMBER(P, S) and DOUBLE, sql.NullBool for BOOLEAN, sql.NullString for
+VARCHAR and sql.NullTime for DATE, TIME and TIMESTAMP columns. This works with both the ARROW and the JSON data formats.
+\*big.Int and \*big.Float values cannot be scanned into sql.NullString.
+
+A DATE column is returned as a time.Time at midnight UTC, whose date changes when it is converted to a time zone west
+of UTC. To read the date without a time zone, Scan() into a gosnowflake.Date, or a *gosnowflake.Date if the column may
+contain NULL values. Both data formats return the same dates.
+
+A TIME column is returned as a time.Time on January 1 of year 1. To read the time of day alone, Scan() into a
+gosnowflake.TimeOfDay, or a *gosnowflake.TimeOfDay if the column may contain NULL values.
+
 The following example shows how to retrieve very large values using the math/big package. This example retrieves a large
 INTEGER value to an interface and then extracts a big.Int value from that interface. If the value
 fits into an int64, then the code also copies the value to a variable of type int64.
@@ -417,9 +473,45 @@ binds arrays to the parameters in the INSERT statement.
 	// Insert the data from the arrays and wrap in an Array() function into the table.
 	_, err = db.Exec("insert into my_table values (?, ?, ?, ?)", Array(&intArray), Array(&fltArray), Array(&boolArray), Array(&strArray))
 
+Large array binds are uploaded to a temporary stage as CSV files and loaded from there. Config.BindUploadChunkSize
+sets the size in bytes of those files, 10MB by default. Smaller sizes produce more files and larger sizes fewer.
+For data that the default comma delimited, double quoted dialect doesn't suit, Config.BindCSVDialect sets the
+delimiter, quote and escape characters of the files, which the file format of the bind stage follows.
+
 Note: For alternative ways to load data into the Snowflake database (including bulk loading using the COPY command), see
 Loading Data Into Snowflake (https://docs.snowflake.com/en/user-guide-data-load.html).
 
+Scanning ARRAY Columns Into Slices
+
+ARRAY columns are returned as the JSON text of the array. To read one into a slice instead, wrap a pointer to a []string,
+[]int64 or []float64 in Array() and pass it to Scan. A SQL NULL leaves the slice nil, and null elements of the array become
+the zero value of the element type.
+
+	var tags []string
+	err = db.QueryRow("select array_construct('a', 'b', null)").Scan(Array(&tags))
+	// tags is []string{"a", "b", ""}
+
+Structured Types
+
+When Snowflake returns a structured OBJECT or ARRAY column, such as OBJECT(a INT, b TEXT) or ARRAY(INT), in Arrow
+format, its values are returned as map[string]interface{} and []interface{} instead of JSON text. Fields and elements are
+converted like columns of their type: integers to int64, decimals to *big.Int or *big.Float, floats to float64 and text to
+string. Scan them into an interface{}. Semi-structured columns are still returned as JSON text. Structured MAP columns are
+not supported.
+
+Binding Parameters by Name
+
+A parameter passed with sql.Named is bound under its name and is referenced as
+:name in the SQL text. A named parameter can be referenced more than once:
+
+	rows, err := db.Query("select * from orders where id = :id or parent_id = :id", sql.Named("id", 42))
+
+Parameters without a name are still bound by position, counting the unnamed
+parameters only: in "where id = :id and status = ?", the status is the first
+positional parameter. Array binds with a named parameter are always sent with
+the query, as the files uploaded to the bind stage for large array binds only
+have positional columns.
+
 Binding a Parameter to a Time Type
 
 Go's database/sql package supports the ability to bind a parameter in a SQL statement to a time.Time variable.
@@ -449,6 +541,11 @@ or BINARY data type. The above example could be rewritten as follows:
 	// ...
 	_, err = stmt.Exec(sf.DataTypeTimestampNtz, tmValue, sf.DataTypeTimestampLtz, tmValue)
 
+To control how bind values are serialized, for example to send time.Time
+values in a custom format, set Config.BindValueSerializer. It is consulted for
+every scalar bind value before the default conversion; the values it
+serializes are bound as TEXT, and all others fall back to the default.
+
 Timestamps with Time Zones
 
 The driver fetches TIMESTAMP_TZ (timestamp with time zone) data using the
@@ -460,6 +557,10 @@ is not in the cache, it is generated dynamically.
 
 Currently, Snowflake does not support the name-based Location types (e.g. "America/Los_Angeles").
 
+TimestampTZOffset returns the offset in minutes of a scanned TIMESTAMP_TZ
+value, which is the offset the database sent. Binding the value back keeps
+the same offset.
+
 For more information about Location types, see the Go documentation for https://golang.org/pkg/time/#Location.
 
 Binary Data
@@ -471,6 +572,16 @@ example, sf is an alias for the gosnowflake package:
 	var b = []byte{0x01, 0x02, 0x03}
 	_, err = stmt.Exec(sf.DataTypeBinary, b)
 
+Semi-structured Data
+
+A map or a struct is bound as JSON after the DataTypeVariant or DataTypeObject
+binding parameter flag, which applies to the maps and structs that follow it:
+
+	tags := map[string]string{"env": "prod"}
+	_, err = db.Exec("INSERT INTO t(v) SELECT ?", sf.DataTypeVariant, tags)
+
+Binding a map or a struct before either flag returns an error.
+
 Maximum number of Result Set Chunk Downloader
 
 The driver directly downloads a result set from the cloud storage if the size is large. It is
@@ -486,6 +597,9 @@ memory footprint by itself. Consider Custom JSON Decoder.
 	)
 	sf.MaxChunkDownloadWorkers = 2
 
+A failed chunk download is retried up to Config.MaxChunkDownloadRetries times before the query fails, counted from
+the last chunk that was downloaded successfully. 0 uses the default of 5 retries and a negative value disables them.
+
 
 Experimental: Custom JSON Decoder for parsing Result Set
 
@@ -497,6 +611,10 @@ The application may have the driver use a custom JSON decoder that incrementally
 	sf.CustomJSONDecoderEnabled = true
 	...
 
+To use the custom JSON decoder for a single query only, pass a context created with WithCustomJSONDecoder:
+
+	rows, err := db.QueryContext(sf.WithCustomJSONDecoder(ctx), query)
+
 This option will reduce the memory footprint to half or even quarter, but it can significantly degrade the
 performance depending on the environment. The test cases running on Travis Ubuntu box show five times less memory
 footprint while four times slower. Be cautious when using the option.
@@ -541,6 +659,28 @@ Note: As of February 2020, Golang's official library does not support passcode-e
 For security purposes, Snowflake highly recommends that you store the passcode-encrypted private key on the disk and
 decrypt the key in your application using a library you trust.
 
+Session token renewal
+
+The driver renews the session token once a request fails because the token has expired. With
+Config.ProactiveTokenRenewal set, it renews the token in the background before it expires instead, until the
+connection is closed.
+
+OAuth token refresh
+
+With the oauth authenticator, Config.TokenProvider can supply the access token instead of Config.Token. It is called
+for a fresh token each time the driver logs in:
+
+	config := &Config{
+		...
+		Authenticator: AuthTypeOAuth,
+		TokenProvider: func(ctx context.Context) (string, error) { return myIdP.AccessToken(ctx) },
+	}
+
+Once the master token of the session expires, the session can no longer be renewed, and the driver logs in again with
+a token from TokenProvider. This starts a new session: the temporary tables, session variables and any open
+transaction of the expired session are lost, and a warning is logged. Without TokenProvider, queries fail once the
+master token expires.
+
 
 Executing Multiple Statements in One Call
 
@@ -717,6 +857,14 @@ snowflakeResult are blocking calls, and wait if results are not yet available.
 (Examples of other synchronous calls include: snowflakeRows.Err(), snowflakeRows.Columns(),
 snowflakeRows.columnTypes(), snowflakeRows.Scan(), and snowflakeResult.RowsAffected().)
 
+A synchronous query can also stop waiting after a while with a SubmitPolicy, set for all
+queries with Config.SubmitPolicy or for one query with WithSubmitPolicy(). Once the query
+has run longer than MaxSyncDuration(), db.QueryContext() and db.ExecContext() return a
+result in progress that is filled in later in the background, like the one of an
+asynchronous query. The policy also sets how long to wait before the first request for
+the result and between the following ones. Without a SubmitPolicy, each query is waited
+on until it completes.
+
 Because the example code above executes only one query and no other activity, there is
 no significant difference in behavior between asynchronous and synchronous behavior.
 The differences become significant if, for example, you want to perform some other
@@ -824,6 +972,20 @@ to handle backslashes in the path to the file.)
     dbt.mustExecContext(WithFileStream(context.Background(), fileStream),
                         sqlText)
 
+The stream above is read into memory before it is uploaded. If the size of the
+stream is known up front, also pass it with WithFileStreamSize so that the
+stream is encrypted and uploaded as it is read. Sized streams are never
+compressed by the driver.
+
+    ctx := WithFileStream(context.Background(), fileStream)
+    ctx = WithFileStreamSize(ctx, fileInfo.Size())
+    dbt.mustExecContext(ctx, sqlText)
+
+To upload the files of PUT commands with your own storage client instead of the
+clients of the AWS, Azure and GCS SDKs, e.g. to route uploads through a storage
+gateway, set Config.StorageClientFactory. The factory receives the location and
+the temporary credentials of the stage and returns a StorageClient.
+
 
 Limitations
 
@@ -37,6 +37,7 @@ func (d SnowflakeDriver) OpenWithConfig(ctx context.Context, config Config) (dri
 		return nil, err
 	}
 	sc.startHeartBeat()
+	sc.startTokenRenewal()
 	sc.internal = &httpClient{sr: sc.rest}
 	return sc, nil
 }
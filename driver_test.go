@@ -1607,6 +1607,51 @@ func testArray(t *testing.T, arrow bool) {
 	})
 }
 
+func TestGeography(t *testing.T) {
+	testGeography(t, false)
+}
+
+func TestArrowGeography(t *testing.T) {
+	testGeography(t, true)
+}
+
+func testGeography(t *testing.T, arrow bool) {
+	runTests(t, dsn, func(dbt *DBTest) {
+		if arrow {
+			dbt.mustExec(forceArrow)
+		}
+		for _, tc := range []struct {
+			format   string
+			expected string
+		}{
+			{"WKT", "POINT(-122.35 37.55)"},
+			{"WKB", "01010000006666666666965EC06666666666C64240"},
+		} {
+			dbt.mustExec("alter session set GEOGRAPHY_OUTPUT_FORMAT='" + tc.format + "'")
+			rows := dbt.mustQuery("select TO_GEOGRAPHY('POINT(-122.35 37.55)')")
+			ct, err := rows.ColumnTypes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ct[0].DatabaseTypeName() != "GEOGRAPHY" {
+				t.Fatalf("unexpected database type name: %v", ct[0].DatabaseTypeName())
+			}
+			var v string
+			if rows.Next() {
+				if err = rows.Scan(&v); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				t.Fatal("no rows")
+			}
+			rows.Close()
+			if v != tc.expected {
+				t.Fatalf("unexpected %v value. expected: %v, got: %v", tc.format, tc.expected, v)
+			}
+		}
+	})
+}
+
 func TestLargeSetResult(t *testing.T) {
 	CustomJSONDecoderEnabled = false
 	testLargeSetResult(t, 100000, false)
@@ -1830,6 +1875,23 @@ func TestCancelQuery(t *testing.T) {
 	})
 }
 
+func TestStatementTimeout(t *testing.T) {
+	runTests(t, dsn, func(dbt *DBTest) {
+		ctx, err := WithStatementTimeout(context.Background(), time.Second)
+		if err != nil {
+			dbt.Fatal(err)
+		}
+		_, err = dbt.db.QueryContext(ctx, "SELECT DISTINCT 1 FROM TABLE(GENERATOR(TIMELIMIT=> 100))")
+		if err == nil {
+			dbt.Fatal("No timeout error returned")
+		}
+		driverErr, ok := err.(*SnowflakeError)
+		if !ok || driverErr.Number != ErrStatementTimeout {
+			dbt.Fatalf("Timeout error mismatch: expect %v, receive %v", ErrStatementTimeout, err)
+		}
+	})
+}
+
 func TestInvalidConnection(t *testing.T) {
 	var db *sql.DB
 	var err error
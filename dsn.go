@@ -3,6 +3,7 @@
 package gosnowflake
 
 import (
+	"context"
 	"crypto/rsa"
 	"encoding/base64"
 	"fmt"
@@ -20,6 +21,10 @@ const (
 	defaultRequestTimeout = 0 * time.Second   // Timeout for retry for request EXCLUDING clientTimeout
 	defaultJWTTimeout     = 60 * time.Second
 	defaultDomain         = ".snowflakecomputing.com"
+
+	defaultBindUploadChunkSize = 1024 * 1024 * 10 // size of the stage files of bind uploads (10MB) as per JDBC specs
+
+	defaultLoginRetryBackoff = 1 * time.Second // wait before the first retry of a failed login
 )
 
 // ConfigBool is a type to represent true or false in the Config
@@ -71,6 +76,8 @@ type Config struct {
 	InsecureMode bool             // driver doesn't check certificate revocation status
 	OCSPFailOpen OCSPFailOpenMode // OCSP Fail Open
 
+	OCSPFailClosed bool // rejects certificates whose revocation status cannot be confirmed, overriding OCSPFailOpen
+
 	Token            string        // Token to use for OAuth other forms of token based auth
 	TokenAccessor    TokenAccessor // Optional token accessor to use
 	KeepSessionAlive bool          // Enables the session to persist even after the connection is closed
@@ -78,19 +85,65 @@ type Config struct {
 	PrivateKey *rsa.PrivateKey // Private key used to sign JWT
 
 	Transporter http.RoundTripper // RoundTripper to intercept HTTP requests and responses
+
+	DefaultQueryTag string // QUERY_TAG applied to every query unless overridden with WithQueryTag
+
+	SseCAlgorithm string // SSE-C algorithm for result chunk downloads when the server doesn't provide chunk headers (default AES256)
+
+	HeartbeatInterval time.Duration // interval between session heartbeats, negative to disable them
+
+	BindValueSerializer BindValueSerializer // overrides the serialization of scalar bind values
+
+	EmptyStringAsNull         bool // binds empty strings as NULL, except in array binds
+	EmptyStringAsNullInArrays bool // binds empty strings in array binds as NULL
+
+	MaxChunkDownloadRetries int // retries of failed result chunk downloads (default 5), negative to disable them
+
+	OnSessionParametersChanged func(changed map[string]string) // called with the session parameters that changed
+
+	TokenProvider func(ctx context.Context) (string, error) // returns a fresh OAuth access token at each login
+
+	QueryIDObserver func(queryID string, sql string) // called with the ID and SQL text of every query that succeeds
+
+	LoginMaxRetries   int           // retries of logins that failed for a transient reason
+	LoginRetryBackoff time.Duration // wait before the first retry of a login (default 1 second)
+
+	SQLRewriter func(ctx context.Context, sql string) (string, error) // transforms the SQL text of every query
+
+	RetryObserver func(attempt int, requestID string, url string, err error, nextSleep time.Duration) // called before each retry of a request
+
+	BindUploadChunkSize int // size in bytes of the stage files of large array binds (default 10MB)
+
+	BindCSVDialect CSVDialect // CSV dialect of the stage files of large array binds
+
+	ProactiveTokenRenewal bool // renews the session token in the background before it expires
+
+	StorageClientFactory StorageClientFactory // creates the clients that PUT uploads files with
+
+	SubmitPolicy SubmitPolicy // how long queries are waited on before returning a result in progress
 }
 
 // ocspMode returns the OCSP mode in string INSECURE, FAIL_OPEN, FAIL_CLOSED
 func (c *Config) ocspMode() string {
 	if c.InsecureMode {
 		return ocspModeInsecure
-	} else if c.OCSPFailOpen == ocspFailOpenNotSet || c.OCSPFailOpen == OCSPFailOpenTrue {
-		// by default or set to true
+	} else if c.ocspFailOpenMode() == OCSPFailOpenTrue {
 		return ocspModeFailOpen
 	}
 	return ocspModeFailClosed
 }
 
+// ocspFailOpenMode returns the OCSP fail open mode certificates are checked with
+func (c *Config) ocspFailOpenMode() OCSPFailOpenMode {
+	if c.OCSPFailClosed {
+		return OCSPFailOpenFalse
+	} else if c.OCSPFailOpen == ocspFailOpenNotSet {
+		// fail open by default
+		return OCSPFailOpenTrue
+	}
+	return c.OCSPFailOpen
+}
+
 // DSN constructs a DSN for Snowflake db.
 func DSN(cfg *Config) (dsn string, err error) {
 	hasHost := true
@@ -186,7 +239,7 @@ func DSN(cfg *Config) (dsn string, err error) {
 		params.Add("insecureMode", strconv.FormatBool(cfg.InsecureMode))
 	}
 
-	params.Add("ocspFailOpen", strconv.FormatBool(cfg.OCSPFailOpen != OCSPFailOpenFalse))
+	params.Add("ocspFailOpen", strconv.FormatBool(cfg.ocspFailOpenMode() != OCSPFailOpenFalse))
 
 	params.Add("validateDefaultParameters", strconv.FormatBool(cfg.ValidateDefaultParameters != ConfigBoolFalse))
 
@@ -406,14 +459,25 @@ func fillMissingConfigParameters(cfg *Config) error {
 		cfg.Application = clientType
 	}
 
-	if cfg.OCSPFailOpen == ocspFailOpenNotSet {
-		cfg.OCSPFailOpen = OCSPFailOpenTrue
+	if cfg.OCSPFailClosed && cfg.InsecureMode {
+		return ErrInvalidOCSPMode
 	}
+	cfg.OCSPFailOpen = cfg.ocspFailOpenMode()
 
 	if cfg.ValidateDefaultParameters == configBoolNotSet {
 		cfg.ValidateDefaultParameters = ConfigBoolTrue
 	}
 
+	if cfg.BindUploadChunkSize < 0 {
+		return ErrInvalidBindUploadChunkSize
+	}
+	if cfg.BindUploadChunkSize == 0 {
+		cfg.BindUploadChunkSize = defaultBindUploadChunkSize
+	}
+	if !cfg.BindCSVDialect.valid() {
+		return ErrInvalidBindCSVDialect
+	}
+
 	if strings.HasSuffix(cfg.Host, defaultDomain) && len(cfg.Host) == len(defaultDomain) {
 		return &SnowflakeError{
 			Number:      ErrCodeFailedToParseHost,
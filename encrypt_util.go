@@ -111,6 +111,87 @@ func encryptStream(
 	}, nil
 }
 
+// encryptedSize returns the length of size bytes of plaintext once encrypted
+// by encryptStream or newEncryptReader
+func encryptedSize(size int64) int64 {
+	return (size/aes.BlockSize + 1) * aes.BlockSize
+}
+
+// encryptReader encrypts its source block by block as it is read, using the
+// same AES CBC and PKCS5 padding scheme as encryptStream
+type encryptReader struct {
+	src    io.Reader
+	mode   cipher.BlockMode
+	chunk  []byte
+	out    []byte
+	closed bool
+}
+
+// newEncryptReader returns a reader producing the encrypted contents of src
+// without buffering more than a single chunk in memory
+func newEncryptReader(
+	sfe *snowflakeFileEncryption,
+	src io.Reader,
+	chunkSize int) (*encryptMetadata, io.Reader, error) {
+	if chunkSize < aes.BlockSize {
+		chunkSize = aes.BlockSize * 4 * 1024
+	}
+	decodedKey, _ := base64.StdEncoding.DecodeString(sfe.QueryStageMasterKey)
+	keySize := len(decodedKey)
+
+	fileKey := getSecureRandom(keySize)
+	block, err := aes.NewCipher(fileKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	ivData := getSecureRandom(block.BlockSize())
+
+	// encrypt key with ECB
+	paddedFileKey := padBytesLength(fileKey, block.BlockSize())
+	encryptedFileKey := make([]byte, len(paddedFileKey))
+	if err = encryptECB(encryptedFileKey, paddedFileKey, decodedKey); err != nil {
+		return nil, nil, err
+	}
+
+	matDesc := materialDescriptor{
+		strconv.Itoa(int(sfe.SMKID)),
+		sfe.QueryID,
+		strconv.Itoa(keySize * 8),
+	}
+	meta := &encryptMetadata{
+		base64.StdEncoding.EncodeToString(encryptedFileKey),
+		base64.StdEncoding.EncodeToString(ivData),
+		matdescToUnicode(matDesc),
+	}
+	return meta, &encryptReader{
+		src:   src,
+		mode:  cipher.NewCBCEncrypter(block, ivData),
+		chunk: make([]byte, chunkSize-chunkSize%aes.BlockSize+aes.BlockSize),
+	}, nil
+}
+
+func (er *encryptReader) Read(p []byte) (int, error) {
+	for len(er.out) == 0 {
+		if er.closed {
+			return 0, io.EOF
+		}
+		// keep one block of headroom so the final chunk can be padded in place
+		buf := er.chunk[:len(er.chunk)-aes.BlockSize]
+		n, err := io.ReadFull(er.src, buf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			buf = padBytesLength(er.chunk[:n], aes.BlockSize)
+			er.closed = true
+		} else if err != nil {
+			return 0, err
+		}
+		er.mode.CryptBlocks(buf, buf)
+		er.out = buf
+	}
+	n := copy(p, er.out)
+	er.out = er.out[n:]
+	return n, nil
+}
+
 func encryptECB(encrypted []byte, fileKey []byte, decodedKey []byte) error {
 	block, _ := aes.NewCipher(decodedKey)
 	if len(fileKey)%block.BlockSize() != 0 {
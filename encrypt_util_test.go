@@ -4,6 +4,7 @@ package gosnowflake
 
 import (
 	"bufio"
+	"bytes"
 	"compress/gzip"
 	"fmt"
 	"io"
@@ -149,3 +150,41 @@ func generateKLinesOfNFiles(k int, n int, compress bool, tmpDir string) string {
 	}
 	return tmpDir
 }
+
+func TestEncryptReaderDecryptFile(t *testing.T) {
+	encMat := snowflakeFileEncryption{
+		"ztke8tIdVt1zmlQIZm0BMA==",
+		"123873c7-3a66-40c4-ab89-e3722fbccce1",
+		3112,
+	}
+	tmpDir := t.TempDir()
+	for _, size := range []int{0, 1, 15, 16, 17, 1024, 65536, 65537, 200000} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		metadata, r, err := newEncryptReader(&encMat, bytes.NewReader(data), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypted, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(encrypted)) != encryptedSize(int64(size)) {
+			t.Fatalf("size %v: expected %v encrypted bytes, got: %v", size, encryptedSize(int64(size)), len(encrypted))
+		}
+
+		encryptedFile := filepath.Join(tmpDir, "test_encrypt_reader_"+strconv.Itoa(size))
+		if err = ioutil.WriteFile(encryptedFile, encrypted, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		decryptedFile, err := decryptFile(metadata, &encMat, encryptedFile, 0, tmpDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadFile(decryptedFile)
+		if !bytes.Equal(content, data) {
+			t.Fatalf("size %v: decrypted content did not match", size)
+		}
+	}
+}
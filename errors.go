@@ -60,6 +60,16 @@ const (
 	ErrCodePrivateKeyParseError = 260010
 	// ErrCodeFailedToParseAuthenticator is an error code for the case where a DNS includes an invalid authenticator
 	ErrCodeFailedToParseAuthenticator = 260011
+	// ErrCodeInvalidBindUploadChunkSize is an error code for the case where the bind upload chunk size is not positive
+	ErrCodeInvalidBindUploadChunkSize = 260012
+	// ErrCodeWarehouseUnavailable is an error code for the case where the session is valid but its warehouse
+	// cannot execute queries, e.g. because none is selected or it is suspended and does not auto-resume
+	ErrCodeWarehouseUnavailable = 260013
+	// ErrCodeInvalidOCSPMode is an error code for the case where both fail closed OCSP and the insecure mode are set
+	ErrCodeInvalidOCSPMode = 260014
+	// ErrCodeInvalidBindCSVDialect is an error code for the case where the CSV dialect of bind uploads reuses a
+	// character or uses a line break
+	ErrCodeInvalidBindCSVDialect = 260015
 
 	/* network */
 
@@ -89,7 +99,24 @@ const (
 	/* rows */
 
 	// ErrFailedToGetChunk is an error code for the case where it failed to get chunk of result set
+	// When returned while reading rows, MessageArgs holds the failing chunk index, the number
+	// of rows consumed before the failure and the underlying error.
 	ErrFailedToGetChunk = 262000
+	// ErrMissingResultData is an error code for the case where a result reports rows but carries no
+	// row set nor chunks to read them from
+	ErrMissingResultData = 262001
+	// ErrNoResultIDs is an error code for the case where a multi-statement query returns no result IDs
+	// for its statements
+	ErrNoResultIDs = 262002
+	// ErrInvalidChunkRange is an error code for the case where a range of result chunks to fetch is empty or
+	// out of the chunks of the result set
+	ErrInvalidChunkRange = 262003
+	// ErrNotArrowResult is an error code for the case where Arrow records are requested from, or
+	// WithRequireArrow is set for, a result set that is not in the Arrow format
+	ErrNotArrowResult = 262004
+	// ErrChunkPanic is an error code for the case where reading the rows of a query panicked and
+	// WithRecoverChunkPanics is set. MessageArgs holds the recovered value.
+	ErrChunkPanic = 262006
 
 	/* transaction*/
 
@@ -114,6 +141,8 @@ const (
 	ErrCompressionNotSupported = 264007
 	// ErrInternalNotMatchEncryptMaterial is an error code denoting the encryption material specified does not match
 	ErrInternalNotMatchEncryptMaterial = 264008
+	// ErrFileStreamSizeMismatch is an error code denoting the file stream did not match its declared size
+	ErrFileStreamSizeMismatch = 264009
 
 	/* binding */
 
@@ -121,6 +150,8 @@ const (
 	ErrBindSerialization = 265001
 	// ErrBindUpload is an error code for the uploading process of bind elements to the stage
 	ErrBindUpload = 265002
+	// ErrInvalidLoadCSVArgument is an error code for an invalid table name or option of LoadCSV
+	ErrInvalidLoadCSVArgument = 265003
 
 	/* converter */
 
@@ -153,9 +184,16 @@ const (
 	ErrQueryReportedError = 279201
 	// ErrQueryIsRunning the query is still running
 	ErrQueryIsRunning = 279301
+	// ErrMonitoringUnavailable the monitoring data of queries is unavailable, e.g. because the role may not read it
+	ErrMonitoringUnavailable = 279401
 
 	/* GS error code */
 
+	// ErrNoActiveWarehouse is a GS error code for the case that no warehouse is selected or the warehouse
+	// cannot be resumed
+	ErrNoActiveWarehouse = 606
+	// ErrStatementTimeout is a GS error code for the case that a statement reached its statement or warehouse timeout
+	ErrStatementTimeout = 630
 	// ErrSessionGone is an GS error code for the case that session is already closed
 	ErrSessionGone = 390111
 	// ErrRoleNotExist is a GS error code for the case that the role specified does not exist
@@ -173,6 +211,16 @@ const (
 	errMsgIdpConnectionError                 = "failed to verify URLs. authenticator: %v, token URL:%v, SSO URL:%v"
 	errMsgSSOURLNotMatch                     = "SSO URL didn't match. expected: %v, got: %v"
 	errMsgFailedToGetChunk                   = "failed to get a chunk of result sets. idx: %v"
+	errMsgFileStreamSizeMismatch             = "file stream size did not match the declared size. expected: %v, got at least: %v"
+	errMsgMissingResultData                  = "result has %v rows in total but no row set or chunks"
+	errMsgNoResultIDs                        = "multi-statement query returned no result IDs for its statements"
+	errMsgFailedToFetchChunk                 = "failed to fetch a chunk of result sets. idx: %v, err: %v"
+	errMsgInvalidChunkRange                  = "invalid chunk range [%v, %v). result set has %v chunks"
+	errMsgNotArrowResult                     = "result set is in the %v format, not arrow"
+	errMsgChunkPanic                         = "panic while reading the result set: %v"
+	errMsgNoQueryStatus                      = "status query returned not-success or no status returned. Please retry"
+	errMsgMonitoringUnavailable              = "monitoring data is unavailable. HTTP: %v, URL: %v"
+	errMsgFailedToGetChunkAfterRows          = "failed to get a chunk of result sets. idx: %v, rows consumed: %v, err: %v"
 	errMsgFailedToPostQuery                  = "failed to POST. HTTP: %v, URL: %v"
 	errMsgFailedToRenew                      = "failed to renew session. HTTP: %v, URL: %v"
 	errMsgFailedToCancelQuery                = "failed to cancel query. HTTP: %v, URL: %v"
@@ -191,7 +239,12 @@ const (
 	errMsgOCSPStatusUnknown                  = "OCSP unknown"
 	errMsgOCSPInvalidValidity                = "invalid validity: producedAt: %v, thisUpdate: %v, nextUpdate: %v"
 	errMsgOCSPNoOCSPResponderURL             = "no OCSP server is attached to the certificate. %v"
+	errMsgWarehouseUnavailable               = "warehouse %v is unavailable: %v"
 	errMsgBindColumnMismatch                 = "column %v has a different number of binds (%v) than column 1 (%v)"
+	errMsgArrayBindLengthMismatch            = "array bind of column %v has %v values but the one of column %v has %v"
+	errMsgJSONBindWithoutDataType            = "bind value of type %T is bound as JSON only after DataTypeVariant or DataTypeObject"
+	errMsgInvalidTableName                   = "invalid table name: %v"
+	errMsgInvalidCopyOnError                 = "invalid ON_ERROR option: %v"
 )
 
 var (
@@ -214,4 +267,19 @@ var (
 	ErrInvalidRegion = &SnowflakeError{
 		Number:  ErrCodeRegionOverlap,
 		Message: "two regions specified"}
+
+	// ErrInvalidBindUploadChunkSize is returned if a Config has a negative BindUploadChunkSize.
+	ErrInvalidBindUploadChunkSize = &SnowflakeError{
+		Number:  ErrCodeInvalidBindUploadChunkSize,
+		Message: "bind upload chunk size must be positive"}
+
+	// ErrInvalidOCSPMode is returned if a Config sets both OCSPFailClosed and InsecureMode.
+	ErrInvalidOCSPMode = &SnowflakeError{
+		Number:  ErrCodeInvalidOCSPMode,
+		Message: "OCSPFailClosed cannot be combined with InsecureMode"}
+
+	// ErrInvalidBindCSVDialect is returned if the BindCSVDialect of a Config uses a character twice or a line break.
+	ErrInvalidBindCSVDialect = &SnowflakeError{
+		Number:  ErrCodeInvalidBindCSVDialect,
+		Message: "bind CSV dialect must use distinct characters other than line breaks"}
 )
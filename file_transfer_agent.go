@@ -23,6 +23,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/smithy-go"
@@ -43,8 +44,8 @@ const (
 	uploadCommand   commandType = "UPLOAD"
 	downloadCommand commandType = "DOWNLOAD" // TODO SNOW-294151
 	unknownCommand  commandType = "UNKNOWN"
-	putRegexp       string      = `(?i)^(?:/\*.*\*/\s*)*put\s+`
-	getRegexp       string      = `(?i)^(?:/\*.*\*/\s*)*get\s+`
+	putRegexp       string      = `(?i)^put\s+`
+	getRegexp       string      = `(?i)^get\s+`
 )
 
 const (
@@ -107,6 +108,8 @@ type snowflakeFileTransferAgent struct {
 	stageInfo                   *execResponseStageInfo
 	results                     []*fileMetadata
 	sourceStream                *bytes.Buffer
+	sourceReader                io.Reader
+	sourceReaderSize            int64
 	srcLocations                []string
 	autoCompress                bool
 	srcCompression              string
@@ -202,7 +205,7 @@ func (sfa *snowflakeFileTransferAgent) parseCommand() error {
 	sfa.srcLocations = sfa.data.SrcLocations
 
 	if sfa.commandType == uploadCommand {
-		if sfa.sourceStream != nil {
+		if sfa.sourceStream != nil || sfa.sourceReader != nil {
 			sfa.srcFiles = sfa.srcLocations // streaming PUT
 		} else {
 			sfa.srcFiles = sfa.expandFilenames(sfa.srcLocations)
@@ -312,7 +315,17 @@ func (sfa *snowflakeFileTransferAgent) initFileMetadata() error {
 					fileName),
 			}
 		}
-		if sfa.sourceStream != nil {
+		if sfa.sourceReader != nil {
+			fileName := sfa.srcFiles[0]
+			sfa.fileMetadata = append(sfa.fileMetadata, &fileMetadata{
+				name:              baseName(fileName),
+				srcFileName:       fileName,
+				srcReader:         sfa.sourceReader,
+				srcFileSize:       int(sfa.sourceReaderSize),
+				stageLocationType: sfa.stageLocationType,
+				stageInfo:         sfa.stageInfo,
+			})
+		} else if sfa.sourceStream != nil {
 			fileName := sfa.srcFiles[0]
 			srcFileSize := sfa.sourceStream.Len()
 			sfa.fileMetadata = append(sfa.fileMetadata, &fileMetadata{
@@ -414,7 +427,7 @@ func (sfa *snowflakeFileTransferAgent) processFileCompressionType() error {
 		if autoDetect {
 			currentFileCompressionType = lookupByExtension(filepath.Ext(fileName))
 			test := make([]byte, 4)
-			if currentFileCompressionType == nil {
+			if currentFileCompressionType == nil && meta.srcReader == nil {
 				if meta.srcStream != nil {
 					r := getReaderFromBuffer(&meta.srcStream)
 					if _, err := r.Read(test); err != nil {
@@ -522,10 +535,14 @@ func (sfa *snowflakeFileTransferAgent) updateFileMetadataWithPresignedURL() erro
 }
 
 func (sfa *snowflakeFileTransferAgent) transferAccelerateConfig() error {
-	if sfa.stageLocationType == s3Client {
+	if sfa.stageLocationType == s3Client && sfa.storageClientFactory() == nil {
 		s3Util := new(snowflakeS3Util)
 		s3Loc := s3Util.extractBucketNameAndPath(sfa.stageInfo.Location)
-		client, ok := s3Util.createClient(sfa.stageInfo, false).(*s3.Client)
+		s3Cli, err := s3Util.createClient(sfa.stageInfo, false)
+		if err != nil {
+			return err
+		}
+		client, ok := s3Cli.(*s3.Client)
 		if !ok {
 			return &SnowflakeError{
 				Message: "failed to convert interface to s3 client",
@@ -549,6 +566,7 @@ func (sfa *snowflakeFileTransferAgent) transferAccelerateConfig() error {
 }
 
 func (sfa *snowflakeFileTransferAgent) getLocalFilePathFromCommand(command string) string {
+	command = stripLeadingComments(command)
 	if len(command) == 0 || !strings.Contains(command, fileProtocol) {
 		return ""
 	}
@@ -594,8 +612,11 @@ func (sfa *snowflakeFileTransferAgent) getLocalFilePathFromCommand(command strin
 func (sfa *snowflakeFileTransferAgent) upload(
 	largeFileMetadata []*fileMetadata,
 	smallFileMetadata []*fileMetadata) error {
-	client := sfa.getStorageClient(sfa.stageLocationType).
+	client, err := sfa.getStorageClient(sfa.stageLocationType).
 		createClient(sfa.stageInfo, sfa.useAccelerateEndpoint)
+	if err != nil {
+		return err
+	}
 	for _, meta := range smallFileMetadata {
 		meta.client = client
 	}
@@ -603,7 +624,6 @@ func (sfa *snowflakeFileTransferAgent) upload(
 		meta.client = client
 	}
 
-	var err error
 	if len(smallFileMetadata) > 0 {
 		if err = sfa.uploadFilesParallel(smallFileMetadata); err != nil {
 			return err
@@ -662,7 +682,10 @@ func (sfa *snowflakeFileTransferAgent) uploadFilesParallel(fileMetas []*fileMeta
 			}
 
 			if needRenewToken {
-				client := sfa.renewExpiredClient()
+				client, err := sfa.renewExpiredClient()
+				if err != nil {
+					return err
+				}
 				for _, result := range retryMeta {
 					result.client = client
 				}
@@ -699,7 +722,10 @@ func (sfa *snowflakeFileTransferAgent) uploadFilesSequential(fileMetas []*fileMe
 		}
 
 		if res.resStatus == renewToken {
-			client := sfa.renewExpiredClient()
+			client, err := sfa.renewExpiredClient()
+			if err != nil {
+				return err
+			}
 			for i := idx; i < fileMetaLen; i++ {
 				fileMetas[i].client = client
 			}
@@ -734,7 +760,11 @@ func (sfa *snowflakeFileTransferAgent) uploadOneFile(meta *fileMetadata) (*fileM
 	}
 
 	var err error
-	if meta.srcStream != nil {
+	if meta.srcReader != nil {
+		// the digest would need a full pass over the reader; leave it empty
+		meta.uploadSize = int64(meta.srcFileSize)
+		meta.srcReader = &sizedReader{src: meta.srcReader, size: meta.uploadSize}
+	} else if meta.srcStream != nil {
 		if meta.realSrcStream != nil {
 			meta.sha256Digest, meta.uploadSize = fileUtil.getDigestAndSizeForStream(&meta.realSrcStream)
 		} else {
@@ -771,12 +801,19 @@ func (sfa *snowflakeFileTransferAgent) getStorageClient(stageLocationType cloudT
 	if stageLocationType == local {
 		return &localUtil{}
 	} else if stageLocationType == s3Client || stageLocationType == azureClient || stageLocationType == gcsClient {
-		return &remoteStorageUtil{}
+		return &remoteStorageUtil{factory: sfa.storageClientFactory()}
 	}
 	return nil
 }
 
-func (sfa *snowflakeFileTransferAgent) renewExpiredClient() cloudClient {
+func (sfa *snowflakeFileTransferAgent) storageClientFactory() StorageClientFactory {
+	if sfa.sc == nil || sfa.sc.cfg == nil {
+		return nil
+	}
+	return sfa.sc.cfg.StorageClientFactory
+}
+
+func (sfa *snowflakeFileTransferAgent) renewExpiredClient() (cloudClient, error) {
 	data, err := sfa.sc.exec(
 		sfa.sc.ctx,
 		sfa.command,
@@ -785,7 +822,7 @@ func (sfa *snowflakeFileTransferAgent) renewExpiredClient() cloudClient {
 		false,
 		[]driver.NamedValue{})
 	if err != nil {
-		return nil
+		return nil, err
 	}
 	storageClient := sfa.getStorageClient(sfa.stageLocationType)
 	return storageClient.createClient(&data.Data.StageInfo, sfa.useAccelerateEndpoint)
@@ -870,6 +907,8 @@ func (sfa *snowflakeFileTransferAgent) result() (*execResponse, error) {
 				{Name: "message", ByteLength: 10000, Length: 10000, Type: "TEXT", Scale: 0, Nullable: false},
 			}
 			data.RowType = rt
+			// keep the parallelism granted for the upload for follow-up PUTs
+			data.Parallel = sfa.parallel
 			return &execResponse{Data: *data}, nil
 		}
 	}
@@ -880,11 +919,36 @@ func (sfa *snowflakeFileTransferAgent) result() (*execResponse, error) {
 }
 
 func isFileTransfer(query string) bool {
+	query = stripLeadingComments(query)
 	putRe := regexp.MustCompile(putRegexp)
 	getRe := regexp.MustCompile(getRegexp)
 	return putRe.Match([]byte(query)) || getRe.Match([]byte(query))
 }
 
+// stripLeadingComments removes the whitespace and the -- and // line comments
+// and /* */ block comments that precede the first statement of query
+func stripLeadingComments(query string) string {
+	for {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		switch {
+		case strings.HasPrefix(query, "--"), strings.HasPrefix(query, "//"):
+			end := strings.IndexByte(query, '\n')
+			if end < 0 {
+				return ""
+			}
+			query = query[end+1:]
+		case strings.HasPrefix(query, "/*"):
+			end := strings.Index(query[2:], "*/")
+			if end < 0 {
+				return ""
+			}
+			query = query[end+4:]
+		default:
+			return query
+		}
+	}
+}
+
 type snowflakeProgressPercentage struct {
 	filename        string
 	fileSize        float64
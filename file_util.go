@@ -111,6 +111,10 @@ type fileMetadata struct {
 	srcStream     *bytes.Buffer
 	realSrcStream *bytes.Buffer
 
+	/* sized streaming PUT */
+	srcReader     io.Reader
+	realSrcReader io.Reader
+
 	/* GCS */
 	presignedURL                *url.URL
 	gcsFileHeaderDigest         string
@@ -140,6 +144,28 @@ type fileHeader struct {
 	encryptionMetadata *encryptMetadata
 }
 
+// sizedReader fails the read that makes its source deviate from the
+// declared size, so a truncated or oversized stream is never reported as
+// uploaded
+type sizedReader struct {
+	src  io.Reader
+	size int64
+	read int64
+}
+
+func (r *sizedReader) Read(p []byte) (int, error) {
+	n, err := r.src.Read(p)
+	r.read += int64(n)
+	if r.read > r.size || (err == io.EOF && r.read != r.size) {
+		return n, &SnowflakeError{
+			Number:      ErrFileStreamSizeMismatch,
+			Message:     errMsgFileStreamSizeMismatch,
+			MessageArgs: []interface{}{r.size, r.read},
+		}
+	}
+	return n, err
+}
+
 func getReaderFromBuffer(src **bytes.Buffer) io.Reader {
 	var b bytes.Buffer
 	tee := io.TeeReader(*src, &b) // read src to buf
@@ -3,6 +3,7 @@
 package gosnowflake
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -29,7 +30,7 @@ type gcsLocation struct {
 	path       string
 }
 
-func (util *snowflakeGcsUtil) createClient(info *execResponseStageInfo, _ bool) cloudClient {
+func (util *snowflakeGcsUtil) createClient(info *execResponseStageInfo, _ bool) (cloudClient, error) {
 	securityToken := info.Creds.GcsAccessToken
 	var client cloudClient
 	if securityToken != "" {
@@ -37,7 +38,7 @@ func (util *snowflakeGcsUtil) createClient(info *execResponseStageInfo, _ bool)
 	} else {
 		client = ""
 	}
-	return client
+	return client, nil
 }
 
 // cloudUtil implementation
@@ -159,11 +160,18 @@ func (util *snowflakeGcsUtil) uploadFile(dataFile string, meta *fileMetadata, en
 	}
 
 	var uploadSrc io.Reader
-	if meta.srcStream != nil {
-		uploadSrc = meta.srcStream
+	if meta.srcReader != nil {
+		uploadSrc = meta.srcReader
+		if meta.realSrcReader != nil {
+			uploadSrc = meta.realSrcReader
+		}
+	} else if meta.srcStream != nil {
+		uploadStream := meta.srcStream
 		if meta.realSrcStream != nil {
-			uploadSrc = meta.realSrcStream
+			uploadStream = meta.realSrcStream
 		}
+		// read a copy so that the stream can be uploaded again on retry
+		uploadSrc = bytes.NewReader(uploadStream.Bytes())
 	} else {
 		uploadSrc, _ = os.OpenFile(dataFile, os.O_RDONLY, os.ModePerm)
 	}
@@ -172,11 +180,20 @@ func (util *snowflakeGcsUtil) uploadFile(dataFile string, meta *fileMetadata, en
 	if err != nil {
 		return err
 	}
+	if meta.srcReader != nil {
+		req.ContentLength = meta.uploadSize
+	}
 	for k, v := range gcsHeaders {
 		req.Header.Add(k, v)
 	}
 	client := &http.Client{}
-	resp, _ := client.Do(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		meta.lastError = err
+		meta.resStatus = needRetry
+		return err
+	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		meta.lastError = fmt.Errorf(resp.Status)
 		if resp.StatusCode == 403 || resp.StatusCode == 408 || resp.StatusCode == 429 || resp.StatusCode == 500 || resp.StatusCode == 503 {
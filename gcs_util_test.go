@@ -0,0 +1,129 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testStageEncryption returns the encryption material of a PUT to an
+// encrypted stage
+func testStageEncryption() *snowflakeFileEncryption {
+	return &snowflakeFileEncryption{
+		QueryStageMasterKey: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)),
+		QueryID:             "01a2b3c4-0000-0000-0000-000000000001",
+		SMKID:               1234,
+	}
+}
+
+// decryptUploadedFile decrypts a file uploaded to a stage with the
+// encryption data stored in the metadata of the file
+func decryptUploadedFile(t *testing.T, sfe *snowflakeFileEncryption, body []byte, encryptionDataJSON string) string {
+	var ed encryptionData
+	if err := json.Unmarshal([]byte(encryptionDataJSON), &ed); err != nil {
+		t.Fatalf("failed to parse the encryption data %q. err: %v", encryptionDataJSON, err)
+	}
+	tmpDir, err := ioutil.TempDir("", "decrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	encrypted := filepath.Join(tmpDir, "encrypted")
+	if err = ioutil.WriteFile(encrypted, body, 0600); err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := decryptFile(&encryptMetadata{key: ed.WrappedContentKey.EncryptionKey, iv: ed.ContentEncryptionIV},
+		sfe, encrypted, 0, tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(decrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestUploadStreamToGCS(t *testing.T) {
+	contents := "123,test1\n456,test2\n"
+	var requests int
+	var body []byte
+	var header http.Header
+	var contentLength int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if requests == 1 {
+			// the first attempt fails and is retried with the whole stream
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, header, contentLength = b, r.Header, r.ContentLength
+	}))
+	defer server.Close()
+	presignedURL, _ := url.Parse(server.URL + "/bucket/stage/data.csv")
+
+	for _, sized := range []bool{false, true} {
+		requests = 0
+		sfe := testStageEncryption()
+		meta := fileMetadata{
+			name:               "data.csv",
+			stageLocationType:  "GCS",
+			noSleepingTime:     true,
+			parallel:           1,
+			stageInfo:          &execResponseStageInfo{Location: "bucket/stage/", LocationType: "GCS"},
+			dstFileName:        "data.csv",
+			srcFileName:        "data.csv",
+			realSrcFileName:    "data.csv",
+			overwrite:          true,
+			encryptionMaterial: sfe,
+			presignedURL:       presignedURL,
+			options:            &SnowflakeFileTransferOptions{},
+		}
+		if sized {
+			meta.srcReader = strings.NewReader(contents)
+			meta.srcFileSize = len(contents)
+		} else {
+			meta.srcStream = bytes.NewBufferString(contents)
+			meta.sha256Digest, meta.uploadSize = new(snowflakeFileUtil).getDigestAndSizeForStream(&meta.srcStream)
+		}
+		err := new(remoteStorageUtil).uploadOneFile(&meta)
+		if sized {
+			// a sized stream is consumed by the first attempt and can't be retried
+			if err == nil || meta.resStatus != needRetry {
+				t.Fatalf("the upload of a sized stream should have failed. status: %v, err: %v", meta.resStatus, err)
+			}
+			meta.srcReader = strings.NewReader(contents)
+			meta.realSrcReader = nil
+			err = new(remoteStorageUtil).uploadOneFile(&meta)
+		}
+		if err != nil {
+			t.Fatalf("sized: %v, err: %v", sized, err)
+		}
+		if meta.resStatus != uploaded {
+			t.Fatalf("sized: %v. the stream should have been uploaded. status: %v", sized, meta.resStatus)
+		}
+		if contentLength != int64(len(body)) || contentLength != encryptedSize(int64(len(contents))) {
+			t.Fatalf("sized: %v. unexpected content length %v of a body of %v bytes", sized, contentLength, len(body))
+		}
+		if header.Get(gcsMetadataMatdescKey) == "" {
+			t.Fatalf("sized: %v. the material descriptor should have been sent", sized)
+		}
+		if decrypted := decryptUploadedFile(t, sfe, body, header.Get(gcsMetadataEncryptionDataProp)); decrypted != contents {
+			t.Fatalf("sized: %v. unexpected contents uploaded. expected: %q, got: %q", sized, contents, decrypted)
+		}
+	}
+}
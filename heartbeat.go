@@ -10,8 +10,6 @@ import (
 	"net/http"
 	"net/url"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 const (
@@ -21,11 +19,16 @@ const (
 
 type heartbeat struct {
 	restful      *snowflakeRestful
+	interval     time.Duration
 	shutdownChan chan bool
 }
 
 func (hc *heartbeat) run() {
-	hbTicker := time.NewTicker(heartBeatInterval)
+	interval := hc.interval
+	if interval <= 0 {
+		interval = heartBeatInterval
+	}
+	hbTicker := time.NewTicker(interval)
 	defer hbTicker.Stop()
 	for {
 		select {
@@ -56,8 +59,8 @@ func (hc *heartbeat) stop() {
 func (hc *heartbeat) heartbeatMain() error {
 	logger.Info("Heartbeating!")
 	params := &url.Values{}
-	params.Add(requestIDKey, uuid.New().String())
-	params.Add(requestGUIDKey, uuid.New().String())
+	params.Add(requestIDKey, UUIDGenerator().String())
+	params.Add(requestGUIDKey, UUIDGenerator().String())
 	headers := getHeaders()
 	token, _, _ := hc.restful.TokenAccessor.GetTokens()
 	headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, token)
@@ -0,0 +1,78 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatCustomInterval(t *testing.T) {
+	var beats int32
+	postMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ []byte, _ time.Duration, _ bool) (*http.Response, error) {
+		atomic.AddInt32(&beats, 1)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"code": null, "message": null, "success": true}`)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{
+			Params:            map[string]*string{},
+			HeartbeatInterval: 10 * time.Millisecond,
+		},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncPost:      postMock,
+		},
+	}
+	sc.startHeartBeat()
+	if sc.rest.HeartBeat == nil {
+		t.Fatal("a positive interval should start the heartbeat")
+	}
+	time.Sleep(200 * time.Millisecond)
+	sc.stopHeartBeat()
+	if n := atomic.LoadInt32(&beats); n < 3 {
+		t.Fatalf("expected the heartbeat to tick every %v. got %v beats in 200ms", sc.cfg.HeartbeatInterval, n)
+	}
+	if sc.rest.HeartBeat != nil {
+		t.Fatal("the heartbeat should have been cleared once stopped")
+	}
+}
+
+func TestHeartbeatDisabled(t *testing.T) {
+	keepAlive := "true"
+	for _, tc := range []struct {
+		name     string
+		interval time.Duration
+		params   map[string]*string
+	}{
+		{"default interval without keep alive", 0, map[string]*string{}},
+		{"negative interval with keep alive", -1, map[string]*string{sessionClientSessionKeepAlive: &keepAlive}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sc := &snowflakeConn{
+				cfg: &Config{
+					Params:            tc.params,
+					KeepSessionAlive:  false,
+					HeartbeatInterval: tc.interval,
+				},
+				rest: &snowflakeRestful{},
+			}
+			sc.startHeartBeat()
+			if sc.rest.HeartBeat != nil {
+				t.Fatal("the heartbeat should not have been started")
+			}
+			sc.stopHeartBeat()
+		})
+	}
+}
@@ -12,11 +12,14 @@ import (
 type localUtil struct {
 }
 
-func (util *localUtil) createClient(info *execResponseStageInfo, useAccelerateEndpoint bool) cloudClient {
-	return nil
+func (util *localUtil) createClient(info *execResponseStageInfo, useAccelerateEndpoint bool) (cloudClient, error) {
+	return nil, nil
 }
 
 func (util *localUtil) uploadOneFileWithRetry(meta *fileMetadata) error {
+	if meta.srcReader != nil {
+		return util.uploadReader(meta)
+	}
 	var frd *bufio.Reader
 	if meta.srcStream != nil {
 		b := meta.srcStream
@@ -61,6 +64,34 @@ func (util *localUtil) uploadOneFileWithRetry(meta *fileMetadata) error {
 	return nil
 }
 
+// uploadReader copies a sized stream to the stage without buffering it
+func (util *localUtil) uploadReader(meta *fileMetadata) error {
+	dstFileName := filepath.Join(expandUser(meta.stageInfo.Location), meta.dstFileName)
+	if !meta.overwrite {
+		if _, err := os.Stat(dstFileName); err == nil {
+			meta.dstFileSize = 0
+			meta.resStatus = skipped
+			return nil
+		}
+	}
+	output, err := os.OpenFile(dstFileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(output, meta.srcReader); err != nil {
+		// don't leave a truncated file on the stage
+		output.Close()
+		os.Remove(dstFileName)
+		return err
+	}
+	if err = output.Close(); err != nil {
+		return err
+	}
+	meta.dstFileSize = meta.uploadSize
+	meta.resStatus = uploaded
+	return nil
+}
+
 func (util *localUtil) downloadOneFile() {
 	// TODO SNOW-206124
 }
@@ -25,6 +25,14 @@ func Location(offset int) *time.Location {
 	return loc
 }
 
+// TimestampTZOffset returns the offset (minutes) of the time zone of t. For a
+// TIMESTAMP_TZ value scanned into a time.Time, it is the offset sent by the
+// database, which is also the offset the value is bound with.
+func TimestampTZOffset(t time.Time) int {
+	_, offset := t.Zone()
+	return offset / 60
+}
+
 // LocationWithOffsetString returns an offset based Location object. The offset string must consist of sHHMI where one sign
 // character '+'/'-' followed by zero filled hours and minutes.
 func LocationWithOffsetString(offsets string) (loc *time.Location, err error) {
@@ -3,8 +3,12 @@
 package gosnowflake
 
 import (
+	"context"
+	"database/sql/driver"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 )
 
 type tcLocation struct {
@@ -97,3 +101,36 @@ func TestWithOffsetString(t *testing.T) {
 		}
 	}
 }
+
+func TestTimestampTZOffset(t *testing.T) {
+	rowType := execResponseRowType{Type: "timestamp_tz", Scale: 9}
+	for _, tc := range []struct {
+		src    string
+		offset int
+	}{
+		{src: "1609502400.123456789 1860", offset: 420},
+		{src: "1609502400.123456789 2025", offset: 585},
+		{src: "1609502400.123456789 1110", offset: -330},
+		{src: "1609502400.123456789 720", offset: -720},
+		{src: "1609502400.123456789 1440", offset: 0},
+	} {
+		var dest driver.Value
+		if err := stringToValue(context.Background(), &dest, rowType, &tc.src); err != nil {
+			t.Fatal(err)
+		}
+		tm := dest.(time.Time)
+		if offset := TimestampTZOffset(tm); offset != tc.offset {
+			t.Fatalf("unexpected offset of %v. expected: %v, got: %v", tc.src, tc.offset, offset)
+		}
+		if !tm.Equal(time.Unix(1609502400, 123456789)) {
+			t.Fatalf("unexpected time of %v: %v", tc.src, tm)
+		}
+		s, err := valueToString(tm, timestampTzType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := "1609502400123456789 " + strings.Fields(tc.src)[1]; *s != expected {
+			t.Fatalf("the value should be bound with the scanned offset. expected: %v, got: %v", expected, *s)
+		}
+	}
+}
@@ -19,6 +19,26 @@ const SFSessionUserKey contextKey = "LOG_USER"
 //LogKeys these keys in context should be included in logging messages when using logger.WithContext
 var LogKeys = [...]contextKey{SFSessionIDKey, SFSessionUserKey}
 
+// context key of the fields set with WithLogFields
+const logFields contextKey = "LOG_FIELDS"
+
+// WithLogFields returns a context whose fields are included in the messages the
+// driver logs with it, in addition to the fields of the parent context, e.g. to
+// correlate them with the request of the application. The session and user
+// fields of the driver take precedence over fields with the same name.
+func WithLogFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	merged := make(map[string]interface{})
+	if parent, ok := ctx.Value(logFields).(map[string]interface{}); ok {
+		for k, v := range parent {
+			merged[k] = v
+		}
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, logFields, merged)
+}
+
 //SFLogger Snowflake logger interface to expose FieldLogger defined in logrus
 type SFLogger interface {
 	rlog.Ext1FieldLogger
@@ -303,6 +323,11 @@ func context2Fields(ctx context.Context) *rlog.Fields {
 		return &fields
 	}
 
+	if custom, ok := ctx.Value(logFields).(map[string]interface{}); ok {
+		for k, v := range custom {
+			fields[k] = v
+		}
+	}
 	for i := 0; i < len(LogKeys); i++ {
 		if ctx.Value(LogKeys[i]) != nil {
 			fields[string(LogKeys[i])] = ctx.Value(LogKeys[i])
@@ -5,7 +5,15 @@ package gosnowflake
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestMultiStatementExecuteNoResultSet(t *testing.T) {
@@ -491,3 +499,162 @@ func TestMultiStatementVaryingColumnCount(t *testing.T) {
 	}
 	db.Exec("drop table if exists test_tbl")
 }
+
+func TestMultiStatementChildResultStats(t *testing.T) {
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		return &execResponse{
+			Data: execResponseData{
+				QueryID:         "parent",
+				StatementTypeID: statementTypeIDMulti,
+				RowType:         []execResponseRowType{{Name: "multiple statement execution"}},
+				ResultIDs:       "child1,child2",
+				ResultTypes:     "12544,4096",
+			},
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	responses := map[string]string{
+		"/queries/child1/result": `{"data": {"queryId": "child1", "statementTypeId": 12544,
+			"rowtype": [{"name": "number of rows inserted"}], "rowset": [["3"]], "total": 1},
+			"code": "0", "success": true}`,
+		"/queries/child2/result": `{"data": {"queryId": "child2", "statementTypeId": 4096,
+			"rowtype": [{"name": "C1"}], "rowset": [], "total": 42},
+			"code": "0", "success": true}`,
+		"/monitoring/queries/child1": `{"data": {"queries": [{"id": "child1", "stats": {"scanBytes": 1024}}]}, "success": true}`,
+		"/monitoring/queries/child2": `{"data": {"queries": [{"id": "child2", "stats": {"scanBytes": 2048}}]}, "success": true}`,
+	}
+	monitoringRequests := 0
+	getMock := func(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		body, ok := responses[fullURL.Path]
+		if !ok {
+			t.Fatalf("unexpected path: %v", fullURL.Path)
+		}
+		if strings.HasPrefix(fullURL.Path, "/monitoring/") {
+			monitoringRequests++
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncPostQuery: postQueryMock,
+			FuncGet:       getMock,
+		},
+	}
+
+	ctx, _ := WithMultiStatement(context.Background(), 2)
+	res, err := sc.ExecContext(ctx, "insert into t values (1), (2), (3); select * from t", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if monitoringRequests != 0 {
+		t.Fatalf("the child stats should not be fetched by the query. requests: %v", monitoringRequests)
+	}
+	stats := res.(ChildResultStatsProvider).ChildResultStats()
+	expected := []ChildResultStat{
+		{QueryID: "child1", Rows: 3, ScanBytes: 1024},
+		{QueryID: "child2", Rows: 42, ScanBytes: 2048},
+	}
+	if len(stats) != len(expected) {
+		t.Fatalf("expected %v child stats, got: %v", len(expected), stats)
+	}
+	for i := range expected {
+		if stats[i] != expected[i] {
+			t.Fatalf("unexpected child stats. expected: %v, got: %v", expected[i], stats[i])
+		}
+	}
+}
+
+func TestMultiStatementFetchResultByID(t *testing.T) {
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		return &execResponse{
+			Data: execResponseData{
+				QueryID:         "parent",
+				StatementTypeID: statementTypeIDMulti,
+				RowType:         []execResponseRowType{{Name: "multiple statement execution"}},
+				ResultIDs:       "child1,child2",
+				ResultTypes:     "4096,4096",
+			},
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	responses := map[string]string{
+		"/queries/parent/result": `{"data": {"queryId": "parent", "statementTypeId": 4096,
+			"rowtype": [{"name": "multiple statement execution", "type": "text"}], "rowset": [["Multiple statements executed successfully."]],
+			"total": 1, "resultIds": "child1,child2", "resultTypes": "4096,4096"},
+			"code": "0", "success": true}`,
+		"/queries/child1/result": `{"data": {"queryId": "child1", "statementTypeId": 4096,
+			"rowtype": [{"name": "123", "type": "fixed"}], "rowset": [["123"]], "total": 1},
+			"code": "0", "success": true}`,
+		"/queries/child2/result": `{"data": {"queryId": "child2", "statementTypeId": 4096,
+			"rowtype": [{"name": "'000'", "type": "text"}], "rowset": [["000"]], "total": 1},
+			"code": "0", "success": true}`,
+	}
+	getMock := func(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		body, ok := responses[fullURL.Path]
+		if !ok {
+			return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:      "https",
+			Host:          "abc.snowflakecomputing.com",
+			Port:          443,
+			TokenAccessor: getSimpleTokenAccessor(),
+			FuncPostQuery: postQueryMock,
+			FuncGet:       getMock,
+		},
+	}
+
+	ctx, _ := WithMultiStatement(context.Background(), 2)
+	rows, err := sc.QueryContext(ctx, "select 123; select '000'", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	qid := rows.(SnowflakeResult).GetQueryID()
+	rows.Close()
+
+	fetched, err := sc.FetchResult(context.Background(), qid)
+	if err != nil {
+		t.Fatalf("failed to fetch the result of %v: %v", qid, err)
+	}
+	defer fetched.Close()
+	multiRows, ok := fetched.(driver.RowsNextResultSet)
+	if !ok {
+		t.Fatal("fetched rows should support multiple result sets")
+	}
+	var values []driver.Value
+	for {
+		dest := make([]driver.Value, 1)
+		for multiRows.Next(dest) == nil {
+			values = append(values, dest[0])
+		}
+		if multiRows.NextResultSet() != nil {
+			break
+		}
+	}
+	expected := []driver.Value{"123", "000"}
+	if len(values) != len(expected) {
+		t.Fatalf("expected values of both statements %v, got: %v", expected, values)
+	}
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Fatalf("unexpected value of statement %v. expected: %v, got: %v", i+1, expected[i], values[i])
+		}
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -527,3 +528,47 @@ func TestInitOCSPCacheFileCreation(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestUnitOCSPModeTransport(t *testing.T) {
+	defer atomic.StoreUint32((*uint32)(&ocspFailOpen), atomic.LoadUint32((*uint32)(&ocspFailOpen)))
+	for _, tc := range []struct {
+		name      string
+		cfg       Config
+		transport http.RoundTripper
+		failOpen  OCSPFailOpenMode
+		mode      string
+	}{
+		{"default", Config{}, SnowflakeTransport, OCSPFailOpenTrue, ocspModeFailOpen},
+		{"fail open", Config{OCSPFailOpen: OCSPFailOpenTrue}, SnowflakeTransport, OCSPFailOpenTrue, ocspModeFailOpen},
+		{"fail open false", Config{OCSPFailOpen: OCSPFailOpenFalse}, SnowflakeTransport, OCSPFailOpenFalse, ocspModeFailClosed},
+		{"fail closed", Config{OCSPFailClosed: true}, SnowflakeTransport, OCSPFailOpenFalse, ocspModeFailClosed},
+		{"fail closed overrides fail open", Config{OCSPFailClosed: true, OCSPFailOpen: OCSPFailOpenTrue}, SnowflakeTransport, OCSPFailOpenFalse, ocspModeFailClosed},
+		{"insecure", Config{InsecureMode: true}, snowflakeInsecureTransport, 0, ocspModeInsecure},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreUint32((*uint32)(&ocspFailOpen), uint32(ocspFailOpenNotSet))
+			sc, err := buildSnowflakeConn(context.Background(), tc.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sc.rest.Client.Transport != tc.transport {
+				t.Fatalf("unexpected transport: %v", sc.rest.Client.Transport)
+			}
+			if tc.failOpen != 0 && atomic.LoadUint32((*uint32)(&ocspFailOpen)) != uint32(tc.failOpen) {
+				t.Fatalf("unexpected OCSP fail open mode. expected: %v, got: %v", tc.failOpen, ocspFailOpen)
+			}
+			if mode := sc.cfg.ocspMode(); mode != tc.mode {
+				t.Fatalf("unexpected OCSP mode. expected: %v, got: %v", tc.mode, mode)
+			}
+		})
+	}
+
+	cfg := Config{OCSPFailClosed: true, InsecureMode: true}
+	if _, err := buildSnowflakeConn(context.Background(), cfg); err != ErrInvalidOCSPMode {
+		t.Fatalf("fail closed OCSP should not be combined with the insecure mode. err: %v", err)
+	}
+	cfg = Config{Account: "a", User: "u", Password: "p", OCSPFailClosed: true, InsecureMode: true}
+	if err := fillMissingConfigParameters(&cfg); err != ErrInvalidOCSPMode {
+		t.Fatalf("fail closed OCSP should not be combined with the insecure mode. err: %v", err)
+	}
+}
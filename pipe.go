@@ -0,0 +1,64 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const pipeStatusQuery = "SELECT SYSTEM$PIPE_STATUS(?)"
+
+// PipeStatus is the ingestion status of a Snowpipe as reported by
+// SYSTEM$PIPE_STATUS. Timestamps are kept in the format Snowflake returns
+// them in.
+type PipeStatus struct {
+	ExecutionState                  string `json:"executionState"`
+	PendingFileCount                int64  `json:"pendingFileCount"`
+	LastIngestedTimestamp           string `json:"lastIngestedTimestamp,omitempty"`
+	LastIngestedFilePath            string `json:"lastIngestedFilePath,omitempty"`
+	NotificationChannelName         string `json:"notificationChannelName,omitempty"`
+	OutstandingMessagesOnChannel    int64  `json:"numOutstandingMessagesOnChannel,omitempty"`
+	LastReceivedMessageTimestamp    string `json:"lastReceivedMessageTimestamp,omitempty"`
+	LastForwardedMessageTimestamp   string `json:"lastForwardedMessageTimestamp,omitempty"`
+	LastPulledFromChannelTimestamp  string `json:"lastPulledFromChannelTimestamp,omitempty"`
+	LastForwardedFilePath           string `json:"lastForwardedFilePath,omitempty"`
+	Error                           string `json:"error,omitempty"`
+	Fault                           string `json:"fault,omitempty"`
+	PendingHistoryRefreshJobsCount  int64  `json:"pendingHistoryRefreshJobsCount,omitempty"`
+	LastErrorFromHistoryRefreshJobs string `json:"lastErrorFromHistoryRefreshJobs,omitempty"`
+}
+
+// IsRunning returns true if the pipe is running and accepting files
+func (ps *PipeStatus) IsRunning() bool {
+	return ps.ExecutionState == "RUNNING"
+}
+
+// GetPipeStatus polls the status of a Snowpipe with SYSTEM$PIPE_STATUS. It
+// covers pipes loading staged files only; the commit status of Snowpipe
+// Streaming channels is not exposed through SQL and can't be read with it.
+func (sc *snowflakeConn) GetPipeStatus(ctx context.Context, pipe string) (*PipeStatus, error) {
+	rows, err := sc.queryContextInternal(ctx, pipeStatusQuery, []driver.NamedValue{{Ordinal: 1, Value: pipe}})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, len(rows.Columns()))
+	if err = rows.Next(dest); err == io.EOF {
+		return nil, fmt.Errorf("no status returned for pipe %v", pipe)
+	} else if err != nil {
+		return nil, err
+	}
+	status, ok := dest[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected status of pipe %v: %v", pipe, dest[0])
+	}
+	var ps PipeStatus
+	if err = json.Unmarshal([]byte(status), &ps); err != nil {
+		return nil, err
+	}
+	return &ps, nil
+}
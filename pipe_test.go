@@ -0,0 +1,65 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGetPipeStatus(t *testing.T) {
+	status := `{"executionState":"RUNNING","pendingFileCount":3,` +
+		`"lastIngestedTimestamp":"2021-08-14T14:33:58.283Z","lastIngestedFilePath":"data/file_1.csv.gz",` +
+		`"notificationChannelName":"arn:aws:sqs:us-west-2:123456789012:sf-snowpipe",` +
+		`"numOutstandingMessagesOnChannel":2,"lastReceivedMessageTimestamp":"2021-08-14T14:35:00.100Z"}`
+	var req execRequest
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		return &execResponse{
+			Data: execResponseData{
+				QueryID: "01a2b3c4-0000-0000-0000-000000000001",
+				RowType: []execResponseRowType{{Name: "SYSTEM$PIPE_STATUS(?)", Type: "text"}},
+				RowSet:  [][]*string{{&status}},
+				Total:   1,
+			},
+			Code:    "0",
+			Success: true,
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	ps, err := sc.GetPipeStatus(context.Background(), "mydb.public.mypipe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.SQLText != pipeStatusQuery {
+		t.Fatalf("unexpected query. expected: %v, got: %v", pipeStatusQuery, req.SQLText)
+	}
+	if b, ok := req.Bindings["1"]; !ok || b.Value != "mydb.public.mypipe" {
+		t.Fatalf("the pipe name should be bound. got: %v", req.Bindings)
+	}
+	expected := PipeStatus{
+		ExecutionState:               "RUNNING",
+		PendingFileCount:             3,
+		LastIngestedTimestamp:        "2021-08-14T14:33:58.283Z",
+		LastIngestedFilePath:         "data/file_1.csv.gz",
+		NotificationChannelName:      "arn:aws:sqs:us-west-2:123456789012:sf-snowpipe",
+		OutstandingMessagesOnChannel: 2,
+		LastReceivedMessageTimestamp: "2021-08-14T14:35:00.100Z",
+	}
+	if *ps != expected {
+		t.Fatalf("unexpected pipe status. expected: %+v, got: %+v", expected, *ps)
+	}
+	if !ps.IsRunning() {
+		t.Fatal("the pipe should be running")
+	}
+}
@@ -4,11 +4,15 @@ package gosnowflake
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	usr "os/user"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -64,6 +68,76 @@ func TestPutError(t *testing.T) {
 	}
 }
 
+func TestPutSizedStreamToLocalStage(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "putstreamdir")
+	defer os.RemoveAll(tmpDir)
+	// the stream is larger than the multi-part threshold of the upload
+	threshold := int64(1024 * 1024)
+	size := 4 * threshold
+
+	data := &execResponseData{
+		Command:           "UPLOAD",
+		AutoCompress:      false,
+		SrcLocations:      []string{"/tmp/placeholder"},
+		SourceCompression: "auto_detect",
+		StageInfo: execResponseStageInfo{
+			Location:     tmpDir,
+			LocationType: "LOCAL_FS",
+			Path:         "remote_loc",
+		},
+	}
+	fta := &snowflakeFileTransferAgent{
+		data:             data,
+		sourceReader:     io.LimitReader(zeroReader{}, size),
+		sourceReaderSize: size,
+		options: &SnowflakeFileTransferOptions{
+			raisePutGetError:   true,
+			multiPartThreshold: threshold,
+		},
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	if err := fta.execute(); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	if _, err := fta.result(); err != nil {
+		t.Fatal(err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > uint64(size/16) {
+		t.Fatalf("upload allocated %v bytes for a %v byte stream", allocated, size)
+	}
+	fi, err := os.Stat(filepath.Join(tmpDir, "placeholder"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != size {
+		t.Fatalf("expected %v bytes on the stage, got: %v", size, fi.Size())
+	}
+
+	data.Overwrite = true
+	fta = &snowflakeFileTransferAgent{
+		data:             data,
+		sourceReader:     strings.NewReader("too short"),
+		sourceReaderSize: size,
+		options: &SnowflakeFileTransferOptions{
+			raisePutGetError: true,
+		},
+	}
+	if err = fta.execute(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = fta.result()
+	if err == nil || !strings.Contains(err.Error(), strconv.Itoa(ErrFileStreamSizeMismatch)) {
+		t.Fatalf("should have failed with a size mismatch. err: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(tmpDir, "placeholder")); !os.IsNotExist(err) {
+		t.Fatalf("the truncated file should have been removed. err: %v", err)
+	}
+}
+
 func TestPercentage(t *testing.T) {
 	testcases := []struct {
 		seen     int64
@@ -424,3 +498,163 @@ func TestPutOverwrite(t *testing.T) {
 		}
 	})
 }
+
+type memStorageFactory struct {
+	stages []StageInfo
+	files  map[string][]byte
+	meta   map[string]map[string]string
+}
+
+func (f *memStorageFactory) NewStorageClient(info StageInfo) (StorageClient, error) {
+	f.stages = append(f.stages, info)
+	return f, nil
+}
+
+func (f *memStorageFactory) Upload(_ context.Context, name string, data io.Reader, size int64, metadata map[string]string) error {
+	b, err := ioutil.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	if size >= 0 && int64(len(b)) != size {
+		return fmt.Errorf("read %v bytes, expected %v", len(b), size)
+	}
+	f.files[name] = b
+	f.meta[name] = metadata
+	return nil
+}
+
+func (f *memStorageFactory) FileHeader(_ context.Context, name string) (*StorageFileHeader, error) {
+	b, ok := f.files[name]
+	if !ok {
+		return nil, nil
+	}
+	return &StorageFileHeader{ContentLength: int64(len(b)), Metadata: f.meta[name]}, nil
+}
+
+func TestPutWithStorageClientFactory(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "putfactorydir")
+	defer os.RemoveAll(tmpDir)
+	file1 := filepath.Join(tmpDir, "file1")
+	if err := ioutil.WriteFile(file1, []byte("test1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := &execResponseData{
+		Command:           "UPLOAD",
+		AutoCompress:      false,
+		SrcLocations:      []string{file1},
+		SourceCompression: "none",
+		StageInfo: execResponseStageInfo{
+			Location:     "bucket/stage/path/",
+			LocationType: "S3",
+			Path:         "stage/path/",
+			Region:       "us-west-2",
+			Creds: execResponseCredentials{
+				AwsKeyID:     "key",
+				AwsSecretKey: "secret",
+			},
+		},
+	}
+	factory := &memStorageFactory{
+		files: make(map[string][]byte),
+		meta:  make(map[string]map[string]string),
+	}
+	fta := &snowflakeFileTransferAgent{
+		sc: &snowflakeConn{
+			cfg: &Config{StorageClientFactory: factory},
+		},
+		data: data,
+		options: &SnowflakeFileTransferOptions{
+			raisePutGetError: true,
+		},
+	}
+	if err := fta.execute(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fta.result(); err != nil {
+		t.Fatal(err)
+	}
+	if len(factory.stages) == 0 {
+		t.Fatal("the factory did not create a storage client")
+	}
+	stage := factory.stages[0]
+	if stage.LocationType != "S3" || stage.Location != "bucket/stage/path/" || stage.Credentials["AWS_KEY_ID"] != "key" {
+		t.Fatalf("unexpected stage info: %+v", stage)
+	}
+	if string(factory.files["file1"]) != "test1" {
+		t.Fatalf("unexpected uploaded files: %v", factory.files)
+	}
+	if factory.meta["file1"][sfcDigest] == "" {
+		t.Fatalf("missing digest in the metadata: %v", factory.meta["file1"])
+	}
+
+	// the file already exists on the stage
+	fta = &snowflakeFileTransferAgent{
+		sc: &snowflakeConn{
+			cfg: &Config{StorageClientFactory: factory},
+		},
+		data: data,
+		options: &SnowflakeFileTransferOptions{
+			raisePutGetError: true,
+		},
+	}
+	if err := fta.execute(); err != nil {
+		t.Fatal(err)
+	}
+	if fta.results[0].resStatus != skipped {
+		t.Fatalf("expected the upload to be skipped, got %v", fta.results[0].resStatus)
+	}
+
+	// the factory fails to create a client
+	factoryErr := errors.New("no credentials")
+	fta = &snowflakeFileTransferAgent{
+		sc: &snowflakeConn{
+			cfg: &Config{StorageClientFactory: failingStorageFactory{factoryErr}},
+		},
+		data: data,
+		options: &SnowflakeFileTransferOptions{
+			raisePutGetError: true,
+		},
+	}
+	if err := fta.execute(); err != factoryErr {
+		t.Fatalf("expected the error of the factory, got: %v", err)
+	}
+}
+
+type failingStorageFactory struct {
+	err error
+}
+
+func (f failingStorageFactory) NewStorageClient(StageInfo) (StorageClient, error) {
+	return nil, f.err
+}
+
+func TestIsFileTransfer(t *testing.T) {
+	for _, tc := range []struct {
+		query    string
+		transfer bool
+	}{
+		{"PUT file:///tmp/data @~", true},
+		{"get @~/data file:///tmp/", true},
+		{"  \n\tput file:///tmp/data @~", true},
+		{"-- upload\nPUT file:///tmp/data @~", true},
+		{"// upload\r\nPUT file:///tmp/data @~", true},
+		{"/* upload */ PUT file:///tmp/data @~", true},
+		{"/* multi\nline */\n-- and a line\n  /* and another */GET @~/data file:///tmp/", true},
+		{"SELECT 'put file:///tmp/data @~'", false},
+		{"select * from put", false},
+		{"/* put */ SELECT 1", false},
+		{"-- PUT file:///tmp/data @~", false},
+		{"/* PUT file:///tmp/data @~", false},
+		{"output file:///tmp/data", false},
+	} {
+		if got := isFileTransfer(tc.query); got != tc.transfer {
+			t.Errorf("isFileTransfer(%q) = %v, expected: %v", tc.query, got, tc.transfer)
+		}
+	}
+
+	sfa := &snowflakeFileTransferAgent{}
+	if path := sfa.getLocalFilePathFromCommand("-- file:///tmp/other\n  PUT 'file:///tmp/data' @~"); path != "/tmp/data" {
+		t.Fatalf("unexpected local file path: %v", path)
+	}
+}
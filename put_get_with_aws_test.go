@@ -133,7 +133,11 @@ func TestPutWithInvalidToken(t *testing.T) {
 	}
 
 	s3Util := new(snowflakeS3Util)
-	client := s3Util.createClient(&data.Data.StageInfo, false).(*s3.Client)
+	s3Cli, err := s3Util.createClient(&data.Data.StageInfo, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := s3Cli.(*s3.Client)
 
 	s3Loc := s3Util.extractBucketNameAndPath(data.Data.StageInfo.Location)
 	s3Path := s3Loc.s3Path + baseName(fname) + ".gz"
@@ -164,7 +168,10 @@ func TestPutWithInvalidToken(t *testing.T) {
 			AwsSecretKey: data.Data.StageInfo.Creds.AwsSecretKey,
 		},
 	}
-	client = s3Util.createClient(&info, false).(*s3.Client)
+	if s3Cli, err = s3Util.createClient(&info, false); err != nil {
+		t.Fatal(err)
+	}
+	client = s3Cli.(*s3.Client)
 
 	uploader = manager.NewUploader(client)
 	if _, err = uploader.Upload(context.Background(), &s3.PutObjectInput{
@@ -222,7 +229,11 @@ func TestPretendToPutButList(t *testing.T) {
 	}
 
 	s3Util := new(snowflakeS3Util)
-	client := s3Util.createClient(&data.Data.StageInfo, false).(*s3.Client)
+	s3Cli, err := s3Util.createClient(&data.Data.StageInfo, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := s3Cli.(*s3.Client)
 	if _, err = client.ListBuckets(context.Background(),
 		&s3.ListBucketsInput{}); err == nil {
 		t.Fatal("list buckets should fail")
@@ -5,6 +5,8 @@ package gosnowflake
 //lint:file-ignore U1000 Ignore all unused code
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 )
 
@@ -169,10 +171,90 @@ var sfqueryStatusError = map[QueryStatusFromServer]struct{}{
 	SFQueryAborting: dummy, SFQueryFailedWithError: dummy, SFQueryAborted: dummy,
 	SFQueryFailedWithIncident: dummy, SFQueryDisconnected: dummy, SFQueryBlocked: dummy}
 
+// QueryPhase is what a query is doing according to the status reported by
+// the server
+type QueryPhase int
+
+const (
+	// QueryPhaseUnknown the server reported a status the driver doesn't recognize
+	QueryPhaseUnknown QueryPhase = iota
+	// QueryPhaseRunning the query is executing
+	QueryPhaseRunning
+	// QueryPhaseQueued the query is queued for a warehouse
+	QueryPhaseQueued
+	// QueryPhaseWaitingForWarehouse the query waits for its warehouse to resume or be repaired
+	QueryPhaseWaitingForWarehouse
+	// QueryPhaseBlocked the query waits on a lock held by another statement
+	QueryPhaseBlocked
+	// QueryPhaseSucceeded the query completed successfully
+	QueryPhaseSucceeded
+	// QueryPhaseFailed the query failed, was aborted or disconnected
+	QueryPhaseFailed
+)
+
+func (p QueryPhase) String() string {
+	switch p {
+	case QueryPhaseRunning:
+		return "running"
+	case QueryPhaseQueued:
+		return "queued"
+	case QueryPhaseWaitingForWarehouse:
+		return "waiting for warehouse"
+	case QueryPhaseBlocked:
+		return "blocked by another statement"
+	case QueryPhaseSucceeded:
+		return "succeeded"
+	case QueryPhaseFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+var sfQueryStatusPhaseMap = map[QueryStatusFromServer]QueryPhase{
+	SFQueryRunning: QueryPhaseRunning, SFQueryRestarted: QueryPhaseRunning, SFQueryNoData: QueryPhaseRunning,
+	SFQueryQueued: QueryPhaseQueued, SFQueryResumingWarehouse: QueryPhaseWaitingForWarehouse,
+	SFQueryQueueRepairingWarehouse: QueryPhaseWaitingForWarehouse, SFQueryBlocked: QueryPhaseBlocked,
+	SFQuerySuccess: QueryPhaseSucceeded, SFQueryAborting: QueryPhaseFailed, SFQueryFailedWithError: QueryPhaseFailed,
+	SFQueryAborted: QueryPhaseFailed, SFQueryFailedWithIncident: QueryPhaseFailed, SFQueryDisconnected: QueryPhaseFailed}
+
+// queryPhase returns the phase of a query with the status string from the
+// server, or QueryPhaseUnknown if the status isn't recognized
+func queryPhase(status string) QueryPhase {
+	s, ok := sfQueryStrStatusMap[status]
+	if !ok {
+		return QueryPhaseUnknown
+	}
+	return sfQueryStatusPhaseMap[s]
+}
+
+// SnowflakeQueryStatus is the status of a query as reported by the server
+type SnowflakeQueryStatus struct {
+	QueryID      string
+	Status       string // status string from the server, e.g. RESUMING_WAREHOUSE
+	Phase        QueryPhase
+	ErrorCode    int
+	ErrorMessage string
+	ScanBytes    int64 // bytes scanned so far
+	ProducedRows int64 // rows produced so far
+}
+
+func newSnowflakeQueryStatus(qid string, queryRet *retStatus) *SnowflakeQueryStatus {
+	return &SnowflakeQueryStatus{
+		QueryID:      qid,
+		Status:       queryRet.Status,
+		Phase:        queryPhase(queryRet.Status),
+		ErrorCode:    queryRet.ErrorCode,
+		ErrorMessage: queryRet.ErrorMessage,
+		ScanBytes:    queryRet.Stats[monitoringStatScanBytes],
+		ProducedRows: queryRet.Stats[monitoringStatProducedRows],
+	}
+}
+
 type retStatus struct {
-	Status       string `json:"status"`
-	ErrorMessage string `json:"errorMessage"`
-	ErrorCode    int    `json:"errorCode"`
+	Status       string           `json:"status"`
+	ErrorMessage string           `json:"errorMessage"`
+	ErrorCode    int              `json:"errorCode"`
+	Stats        map[string]int64 `json:"stats"`
 }
 
 type statusResponse struct {
@@ -217,8 +299,86 @@ type QueryMonitoringData struct {
 	MinorVersionNumber  int              `json:"minorVersionNumber"`
 	PatchVersionNumber  int              `json:"patchVersionNumber"`
 	Stats               map[string]int64 `json:"stats"`
+	AccelerationCredits float64          `json:"queryAccelerationCredits"`
+}
+
+// UsedQueryAcceleration returns true if part of the query was offloaded to
+// the Query Acceleration Service
+func (qmd *QueryMonitoringData) UsedQueryAcceleration() bool {
+	if qmd == nil {
+		return false
+	}
+	return qmd.Stats[monitoringStatQueryAccelerationBytesScanned] > 0 ||
+		qmd.Stats[monitoringStatQueryAccelerationPartitionsScanned] > 0 ||
+		qmd.AccelerationCredits > 0
+}
+
+// QueryAccelerationCredits returns the credits consumed by the Query
+// Acceleration Service for the query
+func (qmd *QueryMonitoringData) QueryAccelerationCredits() float64 {
+	if qmd == nil {
+		return 0
+	}
+	return qmd.AccelerationCredits
+}
+
+// CompilationTime returns how long Snowflake spent compiling the query
+func (qmd *QueryMonitoringData) CompilationTime() time.Duration {
+	if qmd == nil {
+		return 0
+	}
+	return time.Duration(qmd.Stats[monitoringStatCompilationTime]) * time.Millisecond
+}
+
+// ExecutionTime returns how long the warehouse spent executing the query,
+// after it was compiled and left the queue
+func (qmd *QueryMonitoringData) ExecutionTime() time.Duration {
+	if qmd == nil {
+		return 0
+	}
+	return time.Duration(qmd.Stats[monitoringStatExecutionTime]) * time.Millisecond
 }
 
+// TraceID returns the trace ID the query was run with using WithTraceID, or
+// an empty string if it had none
+func (qmd *QueryMonitoringData) TraceID() string {
+	if qmd == nil || qmd.QueryTag == "" {
+		return ""
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(qmd.QueryTag), &fields); err != nil {
+		return ""
+	}
+	id, _ := fields[queryTagFieldTraceID].(string)
+	return id
+}
+
+// QueryStats holds the bytes a query scanned, the rows it produced and how
+// long it ran, compiled and executed, as reported by its monitoring data
+type QueryStats struct {
+	ScanBytes       int64
+	ProducedRows    int64
+	TotalDuration   time.Duration
+	CompilationTime time.Duration
+	ExecutionTime   time.Duration
+}
+
+// fields of a QUERY_TAG carrying a trace ID
+const (
+	queryTagFieldTraceID  = "trace_id"
+	queryTagFieldQueryTag = "query_tag"
+)
+
+// keys of QueryMonitoringData.Stats
+const (
+	monitoringStatScanBytes                          = "scanBytes"
+	monitoringStatProducedRows                       = "producedRows"
+	monitoringStatCompilationTime                    = "compilationTime"
+	monitoringStatExecutionTime                      = "executionTime"
+	monitoringStatQueryAccelerationBytesScanned      = "queryAccelerationBytesScanned"
+	monitoringStatQueryAccelerationPartitionsScanned = "queryAccelerationPartitionsScanned"
+)
+
 type monitoringResponse struct {
 	Data struct {
 		Queries []QueryMonitoringData `json:"queries"`
@@ -227,3 +387,84 @@ type monitoringResponse struct {
 	Code    string `json:"code"`
 	Success bool   `json:"success"`
 }
+
+// QueryGraphData is the struct returned by a request to /monitoring/query-plan-data/$qid
+// Contains the operator graph of a query run as shown in the query profile
+type QueryGraphData struct {
+	Steps []QueryGraphStep `json:"steps"`
+}
+
+// QueryGraphStep is a single step of a query profile
+type QueryGraphStep struct {
+	Step        int    `json:"step"`
+	Description string `json:"description"`
+	TimeInMs    int64  `json:"timeInMs"`
+	GraphData   struct {
+		Nodes []QueryGraphNode `json:"nodes"`
+	} `json:"graphData"`
+}
+
+// QueryGraphNode is an operator in a step of a query profile
+type QueryGraphNode struct {
+	ID         int64                  `json:"id"`
+	LogicalID  int64                  `json:"logicalId"`
+	Name       string                 `json:"name"`
+	Title      string                 `json:"title"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// UsedMaterializedView returns true if Snowflake rewrote the query to read
+// from a materialized view
+func (qg *QueryGraphData) UsedMaterializedView() bool {
+	return qg.hasNode("materializedview")
+}
+
+// UsedSearchOptimization returns true if Snowflake used the search
+// optimization service to run the query
+func (qg *QueryGraphData) UsedSearchOptimization() bool {
+	return qg.hasNode("searchoptimization")
+}
+
+// ExecutionTime returns the time spent executing the steps of the query
+func (qg *QueryGraphData) ExecutionTime() time.Duration {
+	if qg == nil {
+		return 0
+	}
+	var ms int64
+	for _, step := range qg.Steps {
+		ms += step.TimeInMs
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// hasNode reports whether any operator name, title or attribute contains
+// the given lower case term once spaces and underscores are removed
+func (qg *QueryGraphData) hasNode(term string) bool {
+	if qg == nil {
+		return false
+	}
+	normalize := strings.NewReplacer(" ", "", "_", "")
+	matches := func(s string) bool {
+		return strings.Contains(normalize.Replace(strings.ToLower(s)), term)
+	}
+	for _, step := range qg.Steps {
+		for _, node := range step.GraphData.Nodes {
+			if matches(node.Name) || matches(node.Title) {
+				return true
+			}
+			for k, v := range node.Attributes {
+				if s, ok := v.(string); matches(k) || ok && matches(s) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+type queryGraphResponse struct {
+	Data    QueryGraphData `json:"data"`
+	Message string         `json:"message"`
+	Code    string         `json:"code"`
+	Success bool           `json:"success"`
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,6 +30,7 @@ const (
 	queryInProgressCode      = "333333"
 	queryInProgressAsyncCode = "333334"
 	sessionExpiredCode       = "390112"
+	masterTokenExpiredCode   = "390114"
 )
 
 // Snowflake Server Endpoints
@@ -58,9 +60,21 @@ type snowflakeRestful struct {
 	Client        *http.Client
 	TokenAccessor TokenAccessor
 	HeartBeat     *heartbeat
+	TokenRenewal  *tokenRenewal
+
+	// validity of the session token returned at login or by the last renewal
+	sessionTokenValidity      time.Duration
+	sessionTokenValidityMutex sync.Mutex
 
 	Connection *snowflakeConn
 
+	RetryObserver retryObserver
+
+	// runningQueries maps the ID of the queries submitted through the
+	// connection that are still running to the request ID they were
+	// submitted with, which is what an abort request identifies them by
+	runningQueries sync.Map
+
 	FuncPostQuery       func(context.Context, *snowflakeRestful, *url.Values, map[string]string, []byte, time.Duration, uuid.UUID, *Config) (*execResponse, error)
 	FuncPostQueryHelper func(context.Context, *snowflakeRestful, *url.Values, map[string]string, []byte, time.Duration, uuid.UUID, *Config) (*execResponse, error)
 	FuncPost            FuncPostType
@@ -75,6 +89,40 @@ type snowflakeRestful struct {
 	FuncGetSSO       func(context.Context, *snowflakeRestful, *url.Values, map[string]string, string, time.Duration) ([]byte, error)
 }
 
+// trackRunningQuery records the request ID of a query that is still running
+// so that it can be cancelled by its query ID
+func (sr *snowflakeRestful) trackRunningQuery(qid string, requestID uuid.UUID) {
+	if qid != "" {
+		sr.runningQueries.Store(qid, requestID)
+	}
+}
+
+func (sr *snowflakeRestful) untrackRunningQuery(qid string) {
+	sr.runningQueries.Delete(qid)
+}
+
+func (sr *snowflakeRestful) getSessionTokenValidity() time.Duration {
+	sr.sessionTokenValidityMutex.Lock()
+	defer sr.sessionTokenValidityMutex.Unlock()
+	return sr.sessionTokenValidity
+}
+
+func (sr *snowflakeRestful) setSessionTokenValidity(validity time.Duration) {
+	sr.sessionTokenValidityMutex.Lock()
+	defer sr.sessionTokenValidityMutex.Unlock()
+	sr.sessionTokenValidity = validity
+}
+
+// runningQueryRequestID returns the request ID that the running query qid
+// was submitted with through the connection
+func (sr *snowflakeRestful) runningQueryRequestID(qid string) (uuid.UUID, bool) {
+	v, ok := sr.runningQueries.Load(qid)
+	if !ok {
+		return uuid.UUID{}, false
+	}
+	return v.(uuid.UUID), true
+}
+
 func (sr *snowflakeRestful) getURL() *url.URL {
 	return &url.URL{
 		Scheme: sr.Protocol,
@@ -141,7 +189,7 @@ func postRestful(
 	raise4XX bool) (
 	*http.Response, error) {
 	return newRetryHTTP(
-		ctx, sr.Client, http.NewRequest, fullURL, headers, timeout).doPost().setBody(body).doRaise4XX(raise4XX).execute()
+		ctx, sr.Client, http.NewRequest, fullURL, headers, timeout).doPost().setBody(body).doRaise4XX(raise4XX).setObserver(sr.RetryObserver).execute()
 }
 
 func getRestful(
@@ -152,7 +200,7 @@ func getRestful(
 	timeout time.Duration) (
 	*http.Response, error) {
 	return newRetryHTTP(
-		ctx, sr.Client, http.NewRequest, fullURL, headers, timeout).execute()
+		ctx, sr.Client, http.NewRequest, fullURL, headers, timeout).setObserver(sr.RetryObserver).execute()
 }
 
 func postRestfulQuery(
@@ -193,7 +241,7 @@ func postRestfulQueryHelper(
 	logger.Infof("params: %v", params)
 	params.Add(requestIDKey, requestID.String())
 	params.Add("clientStartTime", strconv.FormatInt(time.Now().Unix(), 10))
-	params.Add(requestGUIDKey, uuid.New().String())
+	params.Add(requestGUIDKey, UUIDGenerator().String())
 	token, _, _ := sr.TokenAccessor.GetTokens()
 	if token != "" {
 		headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, token)
@@ -224,6 +272,17 @@ func postRestfulQueryHelper(
 			return sr.FuncPostQuery(ctx, sr, params, headers, body, timeout, requestID, cfg)
 		}
 
+		if respd.Code == queryInProgressCode || respd.Code == queryInProgressAsyncCode {
+			qid := respd.Data.QueryID
+			sr.trackRunningQuery(qid, requestID)
+			// a query left to the async retrieval is untracked once it is done
+			defer func() {
+				if data == nil || (data.Data.AsyncResult == nil && data.Data.AsyncRows == nil) {
+					sr.untrackRunningQuery(qid)
+				}
+			}()
+		}
+
 		if queryIDChan := getQueryIDChan(ctx); queryIDChan != nil {
 			queryIDChan <- respd.Data.QueryID
 			close(queryIDChan)
@@ -236,32 +295,31 @@ func postRestfulQueryHelper(
 
 		// if asynchronous query in progress, kick off retrieval but return object
 		if respd.Code == queryInProgressAsyncCode && noResult {
-			// placeholder object to return to user while retrieving results
-			rows := new(snowflakeRows)
-			res := new(snowflakeResult)
-			switch resType := getResultType(ctx); resType {
-			case execResultType:
-				res.queryID = respd.Data.QueryID
-				res.status = QueryStatusInProgress
-				res.errChannel = make(chan error)
-				respd.Data.AsyncResult = res
-			case queryResultType:
-				rows.queryID = respd.Data.QueryID
-				rows.status = QueryStatusInProgress
-				rows.errChannel = make(chan error)
-				respd.Data.AsyncRows = rows
-			default:
-				return &respd, nil
-			}
-
-			// spawn goroutine to retrieve asynchronous results
-			go getAsync(ctx, sr, headers, sr.getFullURL(respd.Data.GetResultURL, nil), timeout, res, rows, cfg)
-			return &respd, nil
+			return startAsyncRetrieval(ctx, sr, headers, &respd, timeout, cfg), nil
 		}
-		for isSessionRenewed || respd.Code == queryInProgressCode ||
-			respd.Code == queryInProgressAsyncCode {
+		policy := getSubmitPolicy(ctx, cfg)
+		maxSync := policy.MaxSyncDuration()
+		submitted := time.Now()
+		for polls := 0; isSessionRenewed || respd.Code == queryInProgressCode ||
+			respd.Code == queryInProgressAsyncCode; polls++ {
 			if !isSessionRenewed {
 				resultURL = respd.Data.GetResultURL
+				if maxSync >= 0 && time.Since(submitted) >= maxSync && isAsyncResultType(ctx) {
+					logger.WithContext(ctx).Infof("query %v still in progress after %v. returning without its result",
+						respd.Data.QueryID, time.Since(submitted))
+					return startAsyncRetrieval(ctx, sr, headers, &respd, timeout, cfg), nil
+				}
+				wait := policy.PollInterval()
+				if polls == 0 {
+					wait = policy.InitialWait()
+				}
+				if wait > 0 {
+					select {
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					case <-time.After(wait):
+					}
+				}
 			}
 
 			logger.Info("ping pong")
@@ -308,12 +366,52 @@ func postRestfulQueryHelper(
 	}
 }
 
+// isAsyncResultType returns true if the result of the query in ctx can be
+// retrieved in the background by getAsync
+func isAsyncResultType(ctx context.Context) bool {
+	resType, ok := ctx.Value(snowflakeResultType).(resultType)
+	return ok && (resType == execResultType || resType == queryResultType)
+}
+
+// startAsyncRetrieval attaches a placeholder result in progress to respd and
+// spawns a goroutine that retrieves the actual result
+func startAsyncRetrieval(
+	ctx context.Context,
+	sr *snowflakeRestful,
+	headers map[string]string,
+	respd *execResponse,
+	timeout time.Duration,
+	cfg *Config) *execResponse {
+	// placeholder object to return to user while retrieving results
+	rows := new(snowflakeRows)
+	res := new(snowflakeResult)
+	switch resType := getResultType(ctx); resType {
+	case execResultType:
+		res.queryID = respd.Data.QueryID
+		res.status = QueryStatusInProgress
+		res.errChannel = make(chan error)
+		respd.Data.AsyncResult = res
+	case queryResultType:
+		rows.ctx = ctx
+		rows.queryID = respd.Data.QueryID
+		rows.status = QueryStatusInProgress
+		rows.errChannel = make(chan error)
+		respd.Data.AsyncRows = rows
+	default:
+		return respd
+	}
+
+	// spawn goroutine to retrieve asynchronous results
+	go getAsync(ctx, sr, headers, sr.getFullURL(respd.Data.GetResultURL, nil), timeout, res, rows, cfg)
+	return respd
+}
+
 func closeSession(ctx context.Context, sr *snowflakeRestful, timeout time.Duration) error {
 	logger.WithContext(ctx).Info("close session")
 	params := &url.Values{}
 	params.Add("delete", "true")
 	params.Add(requestIDKey, getOrGenerateRequestIDFromContext(ctx).String())
-	params.Add(requestGUIDKey, uuid.New().String())
+	params.Add(requestGUIDKey, UUIDGenerator().String())
 	fullURL := sr.getFullURL(sessionRequestPath, params)
 
 	headers := getHeaders()
@@ -363,7 +461,7 @@ func renewRestfulSession(ctx context.Context, sr *snowflakeRestful, timeout time
 	logger.WithContext(ctx).Info("start renew session")
 	params := &url.Values{}
 	params.Add(requestIDKey, getOrGenerateRequestIDFromContext(ctx).String())
-	params.Add(requestGUIDKey, uuid.New().String())
+	params.Add(requestGUIDKey, UUIDGenerator().String())
 	fullURL := sr.getFullURL(tokenRequestPath, params)
 
 	token, masterToken, _ := sr.TokenAccessor.GetTokens()
@@ -393,6 +491,9 @@ func renewRestfulSession(ctx context.Context, sr *snowflakeRestful, timeout time
 			return err
 		}
 		if !respd.Success {
+			if respd.Code == masterTokenExpiredCode && sr.Connection.canRefreshOAuthToken() {
+				return reauthenticateOAuth(ctx, sr.Connection)
+			}
 			c, err := strconv.Atoi(respd.Code)
 			if err != nil {
 				return err
@@ -403,6 +504,9 @@ func renewRestfulSession(ctx context.Context, sr *snowflakeRestful, timeout time
 			}
 		}
 		sr.TokenAccessor.SetTokens(respd.Data.SessionToken, respd.Data.MasterToken, respd.Data.SessionID)
+		if respd.Data.ValidityInSecondsST > 0 {
+			sr.setSessionTokenValidity(respd.Data.ValidityInSecondsST * time.Second)
+		}
 		return nil
 	}
 	b, err := ioutil.ReadAll(resp.Body)
@@ -424,7 +528,7 @@ func cancelQuery(ctx context.Context, sr *snowflakeRestful, requestID uuid.UUID,
 	logger.WithContext(ctx).Info("cancel query")
 	params := &url.Values{}
 	params.Add(requestIDKey, getOrGenerateRequestIDFromContext(ctx).String())
-	params.Add(requestGUIDKey, uuid.New().String())
+	params.Add(requestGUIDKey, UUIDGenerator().String())
 
 	fullURL := sr.getFullURL(abortRequestPath, params)
 
@@ -384,9 +384,10 @@ func TestUnitRenewRestfulSession(t *testing.T) {
 		}
 		tr := &renewSessionResponse{
 			Data: renewSessionResponseMain{
-				SessionToken: newToken,
-				MasterToken:  newMasterToken,
-				SessionID:    newSessionID,
+				SessionToken:        newToken,
+				ValidityInSecondsST: 1800,
+				MasterToken:         newMasterToken,
+				SessionID:           newSessionID,
 			},
 			Message: "",
 			Success: true,
@@ -440,6 +441,9 @@ func TestUnitRenewRestfulSession(t *testing.T) {
 	if sessionID != newSessionID {
 		t.Fatalf("unexpected new session id %v", sessionID)
 	}
+	if validity := sr.getSessionTokenValidity(); validity != 30*time.Minute {
+		t.Fatalf("the validity of the new token should have been kept. got: %v", validity)
+	}
 }
 
 func TestUnitCloseSession(t *testing.T) {
@@ -2,6 +2,11 @@
 
 package gosnowflake
 
+import (
+	"sync"
+	"time"
+)
+
 type queryStatus string
 
 const (
@@ -24,6 +29,46 @@ type SnowflakeResult interface {
 	Monitoring() *QueryMonitoringData
 }
 
+// MonitoringErrProvider is implemented by the results and rows of queries run
+// on a connection to tell why their monitoring data is missing.
+type MonitoringErrProvider interface {
+	// MonitoringErr returns why Monitoring, or QueryGraph once it is called,
+	// returned nil although the query didn't run too fast for the data to be
+	// fetched. It has the code ErrMonitoringUnavailable if the role may not
+	// read monitoring data.
+	MonitoringErr() error
+}
+
+// QueryGraphProvider is implemented by the results and rows of queries run
+// on a connection. The query profile is fetched on the first call of
+// QueryGraph, e.g.
+//
+//	if p, ok := res.(QueryGraphProvider); ok {
+//		graph := p.QueryGraph()
+//	}
+type QueryGraphProvider interface {
+	// QueryGraph returns the profile of the query, or nil if the query ran
+	// faster than FetchQueryMonitoringDataThreshold or it couldn't be fetched
+	QueryGraph() *QueryGraphData
+}
+
+// ChildResultStatsProvider is implemented by the results and rows of queries
+// run on a connection. The bytes scanned by the statements of a
+// multi-statement query are fetched on the first call of ChildResultStats.
+type ChildResultStatsProvider interface {
+	// ChildResultStats returns the stats of the statements of a
+	// multi-statement query, or nil for other queries
+	ChildResultStats() []ChildResultStat
+}
+
+// ChildResultStat holds the number of rows and bytes scanned by one
+// statement of a multi-statement query
+type ChildResultStat struct {
+	QueryID   string
+	Rows      int64
+	ScanBytes int64
+}
+
 type snowflakeResult struct {
 	affectedRows int64
 	insertID     int64 // Snowflake doesn't support last insert id
@@ -32,6 +77,15 @@ type snowflakeResult struct {
 	err          error
 	errChannel   chan error
 	monitoring   *QueryMonitoringData
+	// monitoringErr is why monitoring or queryGraph couldn't be fetched
+	monitoringErr error
+	childStats    []ChildResultStat
+	// sc and runtime fetch the query graph and child stats on demand
+	sc             *snowflakeConn
+	runtime        time.Duration
+	queryGraphOnce sync.Once
+	queryGraph     *QueryGraphData
+	childStatsOnce sync.Once
 }
 
 func (res *snowflakeResult) LastInsertId() (int64, error) {
@@ -75,3 +129,40 @@ func (res *snowflakeResult) waitForAsyncExecStatus() error {
 func (res *snowflakeResult) Monitoring() *QueryMonitoringData {
 	return res.monitoring
 }
+
+// QueryGraph fetches the profile of the query on the first call.
+//
+// See the QueryGraphProvider interface.
+func (res *snowflakeResult) QueryGraph() *QueryGraphData {
+	res.queryGraphOnce.Do(func() {
+		if res.sc == nil {
+			return
+		}
+		g, err := res.sc.queryGraph(res.queryID, res.runtime)
+		if err != nil && res.monitoringErr == nil {
+			res.monitoringErr = err
+		}
+		res.queryGraph = g
+	})
+	return res.queryGraph
+}
+
+// MonitoringErr returns why the monitoring data couldn't be fetched.
+//
+// See the MonitoringErrProvider interface.
+func (res *snowflakeResult) MonitoringErr() error {
+	return res.monitoringErr
+}
+
+// ChildResultStats fetches the bytes scanned by the statements of a
+// multi-statement query on the first call.
+//
+// See the ChildResultStatsProvider interface.
+func (res *snowflakeResult) ChildResultStats() []ChildResultStat {
+	res.childStatsOnce.Do(func() {
+		if res.sc != nil {
+			res.sc.fetchChildScanBytes(res.childStats)
+		}
+	})
+	return res.childStats
+}
@@ -6,8 +6,8 @@ import (
 	"bytes"
 	"crypto/x509"
 	"fmt"
-	"github.com/google/uuid"
 	"io"
+	"math"
 	"math/rand"
 	"net/http"
 	"net/url"
@@ -18,6 +18,8 @@ import (
 	"context"
 
 	"sync"
+
+	"github.com/google/uuid"
 )
 
 var random *rand.Rand
@@ -83,7 +85,7 @@ generated uuid
 */
 func (replacer *requestGUIDReplace) replace() *url.URL {
 	replacer.urlValues.Del(requestGUIDKey)
-	replacer.urlValues.Add(requestGUIDKey, uuid.New().String())
+	replacer.urlValues.Add(requestGUIDKey, UUIDGenerator().String())
 	replacer.urlPtr.RawQuery = replacer.urlValues.Encode()
 	return replacer.urlPtr
 }
@@ -156,6 +158,82 @@ var defaultWaitAlgo = &waitAlgo{
 	cap:   160 * time.Second,
 }
 
+// backoffConfig configures the exponentially growing intervals between
+// checks of a query's status
+type backoffConfig struct {
+	initial    time.Duration // first interval
+	max        time.Duration // maximum interval
+	multiplier float64       // growth of the interval between checks
+}
+
+var defaultBackoffConfig = backoffConfig{
+	initial:    500 * time.Millisecond,
+	max:        30 * time.Second,
+	multiplier: 2,
+}
+
+// SubmitPolicy controls how the driver waits for the result of a query after
+// submitting it. Each request for the result is held by the server for up to
+// 45 seconds while the query runs.
+type SubmitPolicy interface {
+	// InitialWait is how long to wait before the first request for the result
+	InitialWait() time.Duration
+	// PollInterval is how long to wait between the following requests
+	PollInterval() time.Duration
+	// MaxSyncDuration is how long to wait for the result before returning a
+	// result in progress, which is completed in the background like one of
+	// WithAsyncMode. A negative duration waits until the query completes.
+	MaxSyncDuration() time.Duration
+}
+
+type defaultSubmitPolicyType struct{}
+
+func (defaultSubmitPolicyType) InitialWait() time.Duration     { return 0 }
+func (defaultSubmitPolicyType) PollInterval() time.Duration    { return 0 }
+func (defaultSubmitPolicyType) MaxSyncDuration() time.Duration { return -1 }
+
+var defaultSubmitPolicy SubmitPolicy = defaultSubmitPolicyType{}
+
+// noDataRetryAttempts and noDataRetryInterval bound how often a NO_DATA
+// query status is checked again before the query is reported as running
+var (
+	noDataRetryAttempts = 3
+	noDataRetryInterval = 200 * time.Millisecond
+)
+
+// asyncPollBackoff spaces out the requests for the result of a query that
+// the server reports as still in progress. The time spent in the request
+// counts toward the interval, so a long poll is followed immediately by the
+// next one.
+var asyncPollBackoff = backoffConfig{
+	initial:    100 * time.Millisecond,
+	max:        time.Second,
+	multiplier: 2,
+}
+
+var backoffMutex = &sync.Mutex{} // required for random.Int63n
+
+// interval returns the interval before the check following attempt, without
+// jitter
+func (b backoffConfig) interval(attempt int) time.Duration {
+	d := float64(b.initial) * math.Pow(b.multiplier, float64(attempt))
+	if d > float64(b.max) {
+		return b.max
+	}
+	return time.Duration(d)
+}
+
+// jittered returns a random interval between half and all of interval(attempt)
+func (b backoffConfig) jittered(attempt int) time.Duration {
+	d := b.interval(attempt)
+	if d < 2 {
+		return d
+	}
+	backoffMutex.Lock()
+	defer backoffMutex.Unlock()
+	return d/2 + time.Duration(random.Int63n(int64(d/2)+1))
+}
+
 type requestFunc func(method, urlStr string, body io.Reader) (*http.Request, error)
 
 type clientInterface interface {
@@ -172,8 +250,14 @@ type retryHTTP struct {
 	body     []byte
 	timeout  time.Duration
 	raise4XX bool
+	observer retryObserver
 }
 
+// retryObserver is called before each retry with the number of the failed
+// attempt, starting at 1, the request ID, the URL it requested, why it failed
+// and how long the driver sleeps before the next attempt
+type retryObserver func(attempt int, requestID string, url string, err error, nextSleep time.Duration)
+
 func newRetryHTTP(ctx context.Context,
 	client clientInterface,
 	req requestFunc,
@@ -208,6 +292,24 @@ func (r *retryHTTP) setBody(body []byte) *retryHTTP {
 	return r
 }
 
+func (r *retryHTTP) setObserver(observer retryObserver) *retryHTTP {
+	r.observer = observer
+	return r
+}
+
+// requestID returns the request ID of the URL, or, for the URLs that have
+// none such as the ones of result chunks, the one set in the context with
+// WithRequestID. It is empty if neither is set.
+func (r *retryHTTP) requestID() string {
+	if id := r.fullURL.Query().Get(requestIDKey); id != "" {
+		return id
+	}
+	if id, ok := r.ctx.Value(snowflakeRequestIDKey).(uuid.UUID); ok && id != uuid.Nil {
+		return id.String()
+	}
+	return ""
+}
+
 func (r *retryHTTP) execute() (res *http.Response, err error) {
 	totalTimeout := r.timeout
 	logger.WithContext(r.ctx).Infof("retryHTTP.totalTimeout: %v", totalTimeout)
@@ -230,6 +332,7 @@ func (r *retryHTTP) execute() (res *http.Response, err error) {
 			req.Header.Set(k, v)
 		}
 		res, err = r.client.Do(req)
+		var failure error
 		if err != nil {
 			// check if it can retry.
 			doExit, err := r.isRetryableError(err)
@@ -239,6 +342,7 @@ func (r *retryHTTP) execute() (res *http.Response, err error) {
 			// cannot just return 4xx and 5xx status as the error can be sporadic. run often helps.
 			logger.WithContext(r.ctx).Warningf(
 				"failed http connection. no response is returned. err: %v. retrying...\n", err)
+			failure = err
 		} else {
 			if res.StatusCode == http.StatusOK || r.raise4XX && res != nil && res.StatusCode >= 400 && res.StatusCode < 500 {
 				// exit if success
@@ -250,6 +354,7 @@ func (r *retryHTTP) execute() (res *http.Response, err error) {
 			logger.WithContext(r.ctx).Warningf(
 				"failed http connection. HTTP Status: %v. retrying...\n", res.StatusCode)
 			res.Body.Close()
+			failure = fmt.Errorf("HTTP Status: %v", res.StatusCode)
 		}
 		// uses decorrelated jitter backoff
 		sleepTime = defaultWaitAlgo.decorr(retryCounter, sleepTime)
@@ -269,6 +374,9 @@ func (r *retryHTTP) execute() (res *http.Response, err error) {
 			}
 		}
 		retryCounter++
+		if r.observer != nil {
+			r.observer(retryCounter, r.requestID(), r.fullURL.String(), failure, sleepTime)
+		}
 		if rIDReplacer == nil {
 			rIDReplacer = newRequestGUIDReplace(r.fullURL)
 		}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -261,3 +262,72 @@ func TestRetryLoginRequest(t *testing.T) {
 		t.Fatalf("no retry counter should be attached: %v", retryCounterKey)
 	}
 }
+
+func TestRetryObserver(t *testing.T) {
+	client := &fakeHTTPClient{
+		cnt:     3,
+		success: true,
+	}
+	urlPtr, err := url.Parse("https://fakeaccountretryobserver.snowflakecomputing.com:443/queries/v1/query-request?" + requestIDKey + "=testid&clientStartTime=123456")
+	if err != nil {
+		t.Fatal("failed to parse the test URL")
+	}
+	var attempts []int
+	observer := func(attempt int, requestID string, u string, err error, nextSleep time.Duration) {
+		attempts = append(attempts, attempt)
+		if requestID != "testid" {
+			t.Errorf("the request ID of the URL should have been passed. requestID: %v", requestID)
+		}
+		if !strings.HasPrefix(u, "https://fakeaccountretryobserver.snowflakecomputing.com:443/queries/v1/query-request?") {
+			t.Errorf("unexpected url: %v", u)
+		}
+		if err == nil || err.Error() != "HTTP Status: 0" {
+			t.Errorf("the failure of the attempt should have been passed. err: %v", err)
+		}
+		if nextSleep < 0 {
+			t.Errorf("the sleep before the next attempt should not be negative. sleep: %v", nextSleep)
+		}
+	}
+	_, err = newRetryHTTP(context.TODO(),
+		client,
+		fakeRequestFunc, urlPtr, make(map[string]string), 60*time.Second).doPost().setBody([]byte{0}).setObserver(observer).execute()
+	if err != nil {
+		t.Fatal("failed to run retry")
+	}
+	if !reflect.DeepEqual(attempts, []int{1, 2}) {
+		t.Fatalf("the observer should have been called for each retry. attempts: %v", attempts)
+	}
+}
+
+func TestBackoffIntervals(t *testing.T) {
+	backoff := backoffConfig{
+		initial:    100 * time.Millisecond,
+		max:        3 * time.Second,
+		multiplier: 2,
+	}
+	prev := time.Duration(0)
+	for attempt := 0; attempt < 10; attempt++ {
+		d := backoff.interval(attempt)
+		if d > backoff.max {
+			t.Fatalf("attempt %v: interval %v exceeds the cap %v", attempt, d, backoff.max)
+		}
+		if d < prev || (d == prev && d != backoff.max) {
+			t.Fatalf("attempt %v: interval %v should grow from %v until the cap", attempt, d, prev)
+		}
+		for i := 0; i < 100; i++ {
+			if j := backoff.jittered(attempt); j < d/2 || j > d {
+				t.Fatalf("attempt %v: jittered interval %v out of range [%v, %v]", attempt, j, d/2, d)
+			}
+		}
+		prev = d
+	}
+	if prev != backoff.max {
+		t.Fatalf("interval should reach the cap %v, got: %v", backoff.max, prev)
+	}
+	if d := backoff.interval(5); d != 3*time.Second {
+		t.Fatalf("expected the capped interval %v, got: %v", 3*time.Second, d)
+	}
+	if d := backoff.interval(4); d != 1600*time.Millisecond {
+		t.Fatalf("expected interval %v, got: %v", 1600*time.Millisecond, d)
+	}
+}
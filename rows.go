@@ -3,10 +3,26 @@
 package gosnowflake
 
 import (
+	"bufio"
+	"compress/gzip"
+	"context"
 	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
 )
 
 const (
@@ -20,6 +36,7 @@ var (
 	MaxChunkDownloadWorkers = 10
 
 	// CustomJSONDecoderEnabled has the chunk downloader use the custom JSON decoder to reduce memory footprint.
+	// WithCustomJSONDecoder enables it for a single query.
 	CustomJSONDecoderEnabled = false
 )
 
@@ -29,13 +46,56 @@ var (
 
 type snowflakeRows struct {
 	sc                  *snowflakeConn
+	ctx                 context.Context
 	ChunkDownloader     chunkDownloader
 	tailChunkDownloader chunkDownloader
 	queryID             string
+	version             int64
 	status              queryStatus
 	err                 error
 	errChannel          chan error
 	monitoring          *QueryMonitoringData
+	runtime             time.Duration
+	queryGraphOnce      sync.Once
+	queryGraph          *QueryGraphData
+	monitoringErr       error
+	childStats          []ChildResultStat
+	childStatsOnce      sync.Once
+	truncated           bool
+	arrowIgnored        bool
+	panicErr            error
+	rowsRead            int64
+}
+
+// SnowflakeRows provides the rows-specific metadata of a query result in
+// addition to what SnowflakeResult provides
+type SnowflakeRows interface {
+	SnowflakeResult
+	GetResultVersion() int64
+	ResultTruncated() bool
+	ArrowRequestIgnored() bool
+	TotalRows() int64
+	NumChunks() int
+	NextMap() (map[string]interface{}, error)
+	WriteCSV(w io.Writer) error
+	Stats(ctx context.Context) (*QueryStats, error)
+	ChunkDownloadInfo() ([]ChunkDownloadInfo, error)
+	FetchChunkRange(ctx context.Context, start, end int) ([]array.Record, error)
+	FirstArrowBatch() ([]byte, error)
+	OpenChunk(ctx context.Context, idx int) (io.ReadCloser, error)
+}
+
+// ChunkDownloadInfo holds what is needed to download a result chunk outside
+// of the driver: a GET of URL with Headers returns the chunk, gzipped or not,
+// in Format, i.e. JSON rows without the enclosing brackets or an Arrow IPC
+// stream.
+type ChunkDownloadInfo struct {
+	URL              string
+	Headers          map[string]string
+	Format           string
+	RowCount         int
+	UncompressedSize int64
+	CompressedSize   int64
 }
 
 type snowflakeValue interface{}
@@ -64,12 +124,36 @@ func (rows *snowflakeRows) Close() (err error) {
 	return nil
 }
 
-// ColumnTypeDatabaseTypeName returns the database column name.
+// ColumnTypeDatabaseTypeName returns the database type name of the column, e.g. NUMBER(38,2), VARCHAR(16)
+// or TIMESTAMP_NTZ(9).
 func (rows *snowflakeRows) ColumnTypeDatabaseTypeName(index int) string {
 	if err := rows.waitForAsyncQueryStatus(); err != nil {
 		return err.Error()
 	}
-	return strings.ToUpper(rows.ChunkDownloader.getRowType()[index].Type)
+	return databaseTypeName(rows.ChunkDownloader.getRowType()[index])
+}
+
+// databaseTypeName reconstructs the SQL type of a column from its row type
+func databaseTypeName(rowType execResponseRowType) string {
+	switch getSnowflakeType(strings.ToUpper(rowType.Type)) {
+	case fixedType:
+		return fmt.Sprintf("NUMBER(%v,%v)", rowType.Precision, rowType.Scale)
+	case realType:
+		return "FLOAT"
+	case textType:
+		if rowType.Length > 0 {
+			return fmt.Sprintf("VARCHAR(%v)", rowType.Length)
+		}
+		return "VARCHAR"
+	case binaryType:
+		if rowType.Length > 0 {
+			return fmt.Sprintf("BINARY(%v)", rowType.Length)
+		}
+		return "BINARY"
+	case timeType, timestampNtzType, timestampLtzType, timestampTzType:
+		return fmt.Sprintf("%v(%v)", strings.ToUpper(rowType.Type), rowType.Scale)
+	}
+	return strings.ToUpper(rowType.Type)
 }
 
 // ColumnTypeLength returns the length of the column
@@ -108,9 +192,7 @@ func (rows *snowflakeRows) ColumnTypePrecisionScale(index int) (precision, scale
 	switch rowType[index].Type {
 	case "fixed":
 		return rowType[index].Precision, rowType[index].Scale, true
-	case "time":
-		return rowType[index].Scale, 0, true
-	case "timestamp":
+	case "time", "timestamp_ntz", "timestamp_ltz", "timestamp_tz":
 		return rowType[index].Scale, 0, true
 	}
 	return 0, 0, false
@@ -141,18 +223,407 @@ func (rows *snowflakeRows) GetQueryID() string {
 	return rows.queryID
 }
 
+// GetResultVersion returns the version of the query result as reported by the server.
+func (rows *snowflakeRows) GetResultVersion() int64 {
+	return rows.version
+}
+
 func (rows *snowflakeRows) Monitoring() *QueryMonitoringData {
 	return rows.monitoring
 }
 
+// QueryGraph fetches the profile of the query on the first call.
+//
+// See the QueryGraphProvider interface.
+func (rows *snowflakeRows) QueryGraph() *QueryGraphData {
+	rows.queryGraphOnce.Do(func() {
+		if rows.sc == nil {
+			return
+		}
+		g, err := rows.sc.queryGraph(rows.queryID, rows.runtime)
+		if err != nil && rows.monitoringErr == nil {
+			rows.monitoringErr = err
+		}
+		rows.queryGraph = g
+	})
+	return rows.queryGraph
+}
+
+// MonitoringErr returns why the monitoring data couldn't be fetched.
+//
+// See the MonitoringErrProvider interface.
+func (rows *snowflakeRows) MonitoringErr() error {
+	return rows.monitoringErr
+}
+
+// ChildResultStats fetches the bytes scanned by the statements of a
+// multi-statement query on the first call.
+//
+// See the ChildResultStatsProvider interface.
+func (rows *snowflakeRows) ChildResultStats() []ChildResultStat {
+	rows.childStatsOnce.Do(func() {
+		if rows.sc != nil {
+			rows.sc.fetchChildScanBytes(rows.childStats)
+		}
+	})
+	return rows.childStats
+}
+
+// ResultTruncated returns true if the server returned fewer rows than the query
+// produced, e.g. because the session set ROWS_PER_RESULTSET
+func (rows *snowflakeRows) ResultTruncated() bool {
+	return rows.truncated
+}
+
+// ArrowRequestIgnored returns true if the session set GO_QUERY_RESULT_FORMAT
+// to ARROW but the server returned the result in the JSON format, e.g.
+// because Arrow is disabled for the account
+func (rows *snowflakeRows) ArrowRequestIgnored() bool {
+	return rows.arrowIgnored
+}
+
+// Stats returns the bytes scanned, the rows produced and the total duration
+// of the query. They are read from the monitoring data of the rows, which is
+// fetched from the server with ctx if the query ran too fast for it to be
+// attached.
+func (rows *snowflakeRows) Stats(ctx context.Context) (*QueryStats, error) {
+	if err := rows.waitForAsyncQueryStatus(); err != nil {
+		return nil, err
+	}
+	if rows.monitoring == nil {
+		m, err := rows.sc.GetQueryMonitoringData(ctx, rows.queryID)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			return nil, fmt.Errorf("no monitoring data for query %v", rows.queryID)
+		}
+		rows.monitoring = m
+	}
+	return &QueryStats{
+		ScanBytes:       rows.monitoring.Stats[monitoringStatScanBytes],
+		ProducedRows:    rows.monitoring.Stats[monitoringStatProducedRows],
+		TotalDuration:   time.Duration(rows.monitoring.TotalDuration) * time.Millisecond,
+		CompilationTime: rows.monitoring.CompilationTime(),
+		ExecutionTime:   rows.monitoring.ExecutionTime(),
+	}, nil
+}
+
+// TotalRows returns the number of rows of the current result set, including
+// the rows sent with the response of the query, as reported by the server
+// before any row is read.
+func (rows *snowflakeRows) TotalRows() int64 {
+	if err := rows.waitForAsyncQueryStatus(); err != nil {
+		return 0
+	}
+	return rows.ChunkDownloader.getTotal()
+}
+
+// NumChunks returns the number of batches the rows of the current result set
+// arrive in: the result chunks, plus one if rows were sent with the response
+// of the query.
+func (rows *snowflakeRows) NumChunks() int {
+	if err := rows.waitForAsyncQueryStatus(); err != nil {
+		return 0
+	}
+	n := len(rows.ChunkDownloader.getChunkMetas())
+	if rows.ChunkDownloader.hasInlineRows() {
+		n++
+	}
+	return n
+}
+
+// ChunkDownloadInfo returns how to download each remaining result chunk of
+// the current result set. The rows in the response itself are not included.
+func (rows *snowflakeRows) ChunkDownloadInfo() ([]ChunkDownloadInfo, error) {
+	if err := rows.waitForAsyncQueryStatus(); err != nil {
+		return nil, err
+	}
+	metas := rows.ChunkDownloader.getChunkMetas()
+	headers := rows.ChunkDownloader.getChunkHeaders()
+	format := string(rows.ChunkDownloader.getQueryResultFormat())
+	infos := make([]ChunkDownloadInfo, len(metas))
+	for i, meta := range metas {
+		infos[i] = ChunkDownloadInfo{
+			URL:              meta.URL,
+			Headers:          headers,
+			Format:           format,
+			RowCount:         meta.RowCount,
+			UncompressedSize: meta.UncompressedSize,
+			CompressedSize:   meta.CompressedSize,
+		}
+	}
+	return infos, nil
+}
+
+// FetchChunkRange downloads the result chunks of the current result set with
+// indices in [start, end), as listed by ChunkDownloadInfo, and returns their
+// Arrow records. The chunks are not kept by the driver, so a client can page
+// through a large result by fetching only the chunks it shows. The result must
+// be in the Arrow format, and the caller must Release the records.
+func (rows *snowflakeRows) FetchChunkRange(ctx context.Context, start, end int) ([]array.Record, error) {
+	if err := rows.waitForAsyncQueryStatus(); err != nil {
+		return nil, err
+	}
+	if err := rows.checkArrowFormat(); err != nil {
+		return nil, err
+	}
+	metas := rows.ChunkDownloader.getChunkMetas()
+	if start < 0 || start >= end || end > len(metas) {
+		return nil, &SnowflakeError{
+			Number:      ErrInvalidChunkRange,
+			Message:     errMsgInvalidChunkRange,
+			MessageArgs: []interface{}{start, end, len(metas)},
+			QueryID:     rows.queryID,
+		}
+	}
+	headers := rows.ChunkDownloader.getChunkHeaders()
+	var records []array.Record
+	for idx := start; idx < end; idx++ {
+		recs, err := rows.fetchChunkRecords(ctx, metas[idx].URL, headers)
+		if err != nil {
+			for _, rec := range records {
+				rec.Release()
+			}
+			return nil, &SnowflakeError{
+				Number:      ErrFailedToGetChunk,
+				SQLState:    SQLStateConnectionFailure,
+				Message:     errMsgFailedToFetchChunk,
+				MessageArgs: []interface{}{idx, err},
+				QueryID:     rows.queryID,
+			}
+		}
+		records = append(records, recs...)
+	}
+	return records, nil
+}
+
+// FirstArrowBatch returns the Arrow IPC stream of the rows sent with the
+// response of the query, before any result chunk, as the server encoded it.
+// Together with the chunks opened with OpenChunk, it lets a client pass the
+// result on without decoding it. The result must be in the Arrow format; the
+// stream is empty if all rows are in chunks.
+func (rows *snowflakeRows) FirstArrowBatch() ([]byte, error) {
+	if err := rows.waitForAsyncQueryStatus(); err != nil {
+		return nil, err
+	}
+	if err := rows.checkArrowFormat(); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(rows.ChunkDownloader.getRowSetBase64())
+}
+
+// OpenChunk downloads the result chunk of the current result set at index
+// idx, as listed by ChunkDownloadInfo, and returns its uncompressed Arrow IPC
+// stream. The result must be in the Arrow format, and the caller must Close
+// the stream.
+func (rows *snowflakeRows) OpenChunk(ctx context.Context, idx int) (io.ReadCloser, error) {
+	if err := rows.waitForAsyncQueryStatus(); err != nil {
+		return nil, err
+	}
+	if err := rows.checkArrowFormat(); err != nil {
+		return nil, err
+	}
+	metas := rows.ChunkDownloader.getChunkMetas()
+	if idx < 0 || idx >= len(metas) {
+		return nil, &SnowflakeError{
+			Number:      ErrInvalidChunkRange,
+			Message:     errMsgInvalidChunkRange,
+			MessageArgs: []interface{}{idx, idx + 1, len(metas)},
+			QueryID:     rows.queryID,
+		}
+	}
+	stream, err := rows.openChunk(ctx, metas[idx].URL, rows.ChunkDownloader.getChunkHeaders())
+	if err != nil {
+		return nil, &SnowflakeError{
+			Number:      ErrFailedToGetChunk,
+			SQLState:    SQLStateConnectionFailure,
+			Message:     errMsgFailedToFetchChunk,
+			MessageArgs: []interface{}{idx, err},
+			QueryID:     rows.queryID,
+		}
+	}
+	return stream, nil
+}
+
+func (rows *snowflakeRows) checkArrowFormat() error {
+	if format := rows.ChunkDownloader.getQueryResultFormat(); format != arrowFormat {
+		return &SnowflakeError{
+			Number:      ErrNotArrowResult,
+			Message:     errMsgNotArrowResult,
+			MessageArgs: []interface{}{format},
+			QueryID:     rows.queryID,
+		}
+	}
+	return nil
+}
+
+// gzipReadCloser closes both the gzip reader and the body it reads
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (r *gzipReadCloser) Close() error {
+	r.Reader.Close()
+	return r.body.Close()
+}
+
+// openChunk downloads the chunk at chunkURL, decompressing it if it is gzipped
+func (rows *snowflakeRows) openChunk(ctx context.Context, chunkURL string, headers map[string]string) (io.ReadCloser, error) {
+	u, err := url.Parse(chunkURL)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rows.sc.rest.FuncGet(ctx, rows.sc.rest, u, headers, rows.sc.rest.RequestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP: %v, body: %s", resp.StatusCode, b)
+	}
+	bufStream := bufio.NewReader(resp.Body)
+	if gzipMagic, err := bufStream.Peek(2); err == nil && gzipMagic[0] == 0x1f && gzipMagic[1] == 0x8b {
+		gz, err := gzip.NewReader(bufStream)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		return &gzipReadCloser{gz, resp.Body}, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{bufStream, resp.Body}, nil
+}
+
+func (rows *snowflakeRows) fetchChunkRecords(ctx context.Context, chunkURL string, headers map[string]string) ([]array.Record, error) {
+	source, err := rows.openChunk(ctx, chunkURL, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+	reader, err := ipc.NewReader(source)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+	var records []array.Record
+	for reader.Next() {
+		rec := reader.Record()
+		// the reader releases its record on the next read
+		rec.Retain()
+		records = append(records, rec)
+	}
+	if err = reader.Err(); err != nil {
+		for _, rec := range records {
+			rec.Release()
+		}
+		return nil, err
+	}
+	return records, nil
+}
+
+// WriteCSV writes the remaining rows of the current result set to w as CSV,
+// preceded by a header of the column names. NULL is written as an empty field
+// and an empty string as "". Dates and times are written in ISO format, and
+// binary values in hex.
+func (rows *snowflakeRows) WriteCSV(w io.Writer) error {
+	if err := rows.waitForAsyncQueryStatus(); err != nil {
+		return err
+	}
+	rowType := rows.ChunkDownloader.getRowType()
+	bw := bufio.NewWriter(w)
+	fields := make([]string, len(rowType))
+	for i, rt := range rowType {
+		fields[i] = escapeForCSV(rt.Name)
+	}
+	if _, err := bw.WriteString(strings.Join(fields, ",") + "\n"); err != nil {
+		return err
+	}
+	dest := make([]driver.Value, len(rowType))
+	for {
+		if err := rows.Next(dest); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		for i, v := range dest {
+			if v == nil {
+				fields[i] = ""
+			} else {
+				fields[i] = escapeForCSV(valueToCSVString(v, rowType[i]))
+			}
+		}
+		if _, err := bw.WriteString(strings.Join(fields, ",") + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func valueToCSVString(v driver.Value, rowType execResponseRowType) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []byte:
+		return strings.ToUpper(hex.EncodeToString(val))
+	case bool:
+		return strconv.FormatBool(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case *big.Float:
+		return val.Text('f', int(rowType.Scale))
+	case time.Time:
+		switch rowType.Type {
+		case "date":
+			return val.Format("2006-01-02")
+		case "time":
+			return val.Format("15:04:05.999999999")
+		case "timestamp_ntz":
+			return val.Format("2006-01-02 15:04:05.999999999")
+		}
+		return val.Format("2006-01-02 15:04:05.999999999 -07:00")
+	}
+	return fmt.Sprint(v)
+}
+
 func (rows *snowflakeRows) GetStatus() queryStatus {
 	return rows.status
 }
 
 func (rows *snowflakeRows) Next(dest []driver.Value) (err error) {
+	if rows.ctx == nil {
+		// the options of the query are read from the context of the rows
+		rows.ctx = context.Background()
+	}
+	if rows.panicErr != nil {
+		return rows.panicErr
+	}
+	if chunkPanicsRecovered(rows.ctx) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.WithContext(rows.ctx).Errorf("recovered from a panic while reading rows: %v", r)
+				rows.panicErr = &SnowflakeError{
+					Number:      ErrChunkPanic,
+					Message:     errMsgChunkPanic,
+					MessageArgs: []interface{}{r},
+					QueryID:     rows.queryID,
+				}
+				err = rows.panicErr
+			}
+		}()
+	}
 	if err := rows.waitForAsyncQueryStatus(); err != nil {
 		return err
 	}
+	if limit := getRowLimit(rows.ctx); limit > 0 && rows.rowsRead >= limit {
+		return io.EOF
+	}
 	row, err := rows.ChunkDownloader.next()
 	if err != nil {
 		// includes io.EOF
@@ -161,6 +632,7 @@ func (rows *snowflakeRows) Next(dest []driver.Value) (err error) {
 		}
 		return err
 	}
+	rows.rowsRead++
 
 	if rows.ChunkDownloader.getQueryResultFormat() == arrowFormat {
 		for i, n := 0, len(row.ArrowRow); i < n; i++ {
@@ -170,7 +642,7 @@ func (rows *snowflakeRows) Next(dest []driver.Value) (err error) {
 		for i, n := 0, len(row.RowSet); i < n; i++ {
 			// could move to chunk downloader so that each go routine
 			// can convert data
-			err := stringToValue(&dest[i], rows.ChunkDownloader.getRowType()[i], row.RowSet[i])
+			err := stringToValue(rows.ctx, &dest[i], rows.ChunkDownloader.getRowType()[i], row.RowSet[i])
 			if err != nil {
 				return err
 			}
@@ -179,6 +651,22 @@ func (rows *snowflakeRows) Next(dest []driver.Value) (err error) {
 	return err
 }
 
+// NextMap reads the next row like Next and returns it keyed by column name,
+// with NULL values as nil. Of columns with the same name, the last one is
+// kept. It returns io.EOF after the last row.
+func (rows *snowflakeRows) NextMap() (map[string]interface{}, error) {
+	columns := rows.Columns()
+	dest := make([]driver.Value, len(columns))
+	if err := rows.Next(dest); err != nil {
+		return nil, err
+	}
+	row := make(map[string]interface{}, len(columns))
+	for i, name := range columns {
+		row[name] = dest[i]
+	}
+	return row, nil
+}
+
 func (rows *snowflakeRows) HasNextResultSet() bool {
 	if err := rows.waitForAsyncQueryStatus(); err != nil {
 		return false
@@ -197,6 +685,7 @@ func (rows *snowflakeRows) NextResultSet() error {
 		rows.ChunkDownloader = rows.ChunkDownloader.getNextChunkDownloader()
 		rows.ChunkDownloader.start()
 	}
+	rows.rowsRead = 0
 	return rows.ChunkDownloader.nextResultSet()
 }
 
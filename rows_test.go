@@ -3,14 +3,27 @@
 package gosnowflake
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"database/sql/driver"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/google/uuid"
 )
 
 // test variables
@@ -288,6 +301,93 @@ func TestRowsWithChunkDownloaderErrorFail(t *testing.T) {
 	}
 }
 
+func TestRowsWithChunkDownloaderErrorFailReportsProgress(t *testing.T) {
+	numChunks := 12
+	cc := make([][]*string, 0)
+	for i := 0; i < 100; i++ {
+		v1 := fmt.Sprintf("%v", i)
+		v2 := fmt.Sprintf("Test%v", i)
+		cc = append(cc, []*string{&v1, &v2})
+	}
+	rt := []execResponseRowType{
+		{Name: "c1", ByteLength: 10, Length: 10, Type: "FIXED", Scale: 0, Nullable: true},
+		{Name: "c2", ByteLength: 100000, Length: 100000, Type: "TEXT", Scale: 0, Nullable: false},
+	}
+	cm := make([]execResponseChunk, 0)
+	for i := 0; i < numChunks; i++ {
+		cm = append(cm, execResponseChunk{URL: fmt.Sprintf("dummyURL%v", i+1), RowCount: rowsInChunk})
+	}
+	rows := new(snowflakeRows)
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		ctx:           context.Background(),
+		Total:         int64(len(cc) + numChunks*rowsInChunk),
+		ChunkMetas:    cm,
+		TotalRowIndex: int64(-1),
+		Qrmk:          "HOHOHO",
+		FuncDownload:  downloadChunkTestErrorFail,
+		RowSet:        rowSetType{RowType: rt, JSON: cc},
+	}
+	rows.ChunkDownloader.start()
+	cnt := 0
+	dest := make([]driver.Value, 2)
+	var err error
+	for {
+		if err = rows.Next(dest); err != nil {
+			break
+		}
+		cnt++
+	}
+	serr, ok := err.(*SnowflakeError)
+	if !ok {
+		t.Fatalf("should have been snowflake error. err: %v", err)
+	}
+	if serr.Number != ErrFailedToGetChunk {
+		t.Fatalf("message error code is not correct. msg: %v", serr.Number)
+	}
+	expectedRows := int64(len(cc) + 6*rowsInChunk)
+	if cnt != int(expectedRows) {
+		t.Fatalf("unexpected number of rows before the failure. expected: %v, got: %v", expectedRows, cnt)
+	}
+	if len(serr.MessageArgs) != 3 || serr.MessageArgs[0] != 6 || serr.MessageArgs[1] != expectedRows {
+		t.Fatalf("the error should carry the chunk idx and rows consumed. args: %v", serr.MessageArgs)
+	}
+}
+
+func TestRowsWithChunkDownloaderErrorReportsFailedChunk(t *testing.T) {
+	rt := []execResponseRowType{
+		{Name: "c1", ByteLength: 10, Length: 10, Type: "FIXED", Scale: 0, Nullable: true},
+	}
+	cm := []execResponseChunk{{URL: "dummyURL1", RowCount: 1}, {URL: "dummyURL2", RowCount: 1}}
+	rows := new(snowflakeRows)
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		ctx:           context.Background(),
+		Total:         int64(len(cm)),
+		ChunkMetas:    cm,
+		TotalRowIndex: int64(-1),
+		MaxRetries:    1,
+		// the first chunk is never downloaded while the second one fails
+		FuncDownload: func(_ context.Context, scd *snowflakeChunkDownloader, idx int) {
+			if idx != 1 {
+				return
+			}
+			scd.ChunksMutex.Lock()
+			defer scd.ChunksMutex.Unlock()
+			scd.ChunksError <- &chunkError{Index: idx, Error: fmt.Errorf("dummy error. idx: %v", idx+1)}
+			scd.DoneDownloadCond.Broadcast()
+		},
+		RowSet: rowSetType{RowType: rt},
+	}
+	rows.ChunkDownloader.start()
+	err := rows.Next(make([]driver.Value, 1))
+	serr, ok := err.(*SnowflakeError)
+	if !ok || serr.Number != ErrFailedToGetChunk {
+		t.Fatalf("should have failed to get a chunk. err: %v", err)
+	}
+	if len(serr.MessageArgs) != 3 || serr.MessageArgs[0] != 1 {
+		t.Fatalf("the error should carry the idx of the failed chunk. args: %v", serr.MessageArgs)
+	}
+}
+
 func getChunkTestInvalidResponseBody(_ context.Context, _ *snowflakeChunkDownloader, _ string, _ map[string]string, _ time.Duration) (
 	*http.Response, error) {
 	return &http.Response{
@@ -378,3 +478,765 @@ func TestDownloadChunkErrorStatus(t *testing.T) {
 		t.Fatal("should have caused an error and queued in scd.ChunksError")
 	}
 }
+
+func TestDownloadChunkSseCAlgorithm(t *testing.T) {
+	var sentHeaders map[string]string
+	getChunkRecordHeaders := func(_ context.Context, _ *snowflakeChunkDownloader, _ string, headers map[string]string, _ time.Duration) (
+		*http.Response, error) {
+		sentHeaders = headers
+		return &http.Response{
+			StatusCode: http.StatusBadGateway,
+			Body:       &fakeResponseBody{body: []byte{0x12, 0x34}},
+		}, nil
+	}
+	for _, tc := range []struct {
+		algorithm string
+		expected  string
+	}{
+		{algorithm: "", expected: headerSseCAes},
+		{algorithm: "AES512", expected: "AES512"},
+	} {
+		scd := &snowflakeChunkDownloader{
+			sc: &snowflakeConn{
+				rest: &snowflakeRestful{RequestTimeout: defaultRequestTimeout},
+			},
+			ctx:           context.Background(),
+			ChunkMetas:    []execResponseChunk{{URL: "dummyURL1", RowCount: rowsInChunk}},
+			TotalRowIndex: int64(-1),
+			Qrmk:          "HOHOHO",
+			SseCAlgorithm: tc.algorithm,
+			FuncGet:       getChunkRecordHeaders,
+		}
+		if err := downloadChunkHelper(scd.ctx, scd, 0); err == nil {
+			t.Fatal("should have failed to download chunk")
+		}
+		if sentHeaders[headerSseCAlgorithm] != tc.expected {
+			t.Fatalf("unexpected SSE-C algorithm. expected: %v, got: %v", tc.expected, sentHeaders[headerSseCAlgorithm])
+		}
+		if sentHeaders[headerSseCKey] != scd.Qrmk {
+			t.Fatalf("unexpected SSE-C key. expected: %v, got: %v", scd.Qrmk, sentHeaders[headerSseCKey])
+		}
+	}
+
+	// server-provided chunk headers take precedence over the configured algorithm
+	scd := &snowflakeChunkDownloader{
+		sc: &snowflakeConn{
+			rest: &snowflakeRestful{RequestTimeout: defaultRequestTimeout},
+		},
+		ctx:           context.Background(),
+		ChunkMetas:    []execResponseChunk{{URL: "dummyURL1", RowCount: rowsInChunk}},
+		ChunkHeader:   map[string]string{headerSseCAlgorithm: "SERVER", headerSseCKey: "KEY"},
+		SseCAlgorithm: "AES512",
+		FuncGet:       getChunkRecordHeaders,
+	}
+	if err := downloadChunkHelper(scd.ctx, scd, 0); err == nil {
+		t.Fatal("should have failed to download chunk")
+	}
+	if sentHeaders[headerSseCAlgorithm] != "SERVER" {
+		t.Fatalf("unexpected SSE-C algorithm. expected: %v, got: %v", "SERVER", sentHeaders[headerSseCAlgorithm])
+	}
+}
+
+func TestDownloadChunkRawChunks(t *testing.T) {
+	plain := []byte(`["1","a"],["2","b"]`)
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	if _, err := w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		raw     bool
+		body    []byte
+		success bool
+	}{
+		{raw: false, body: plain, success: true},
+		{raw: false, body: gz.Bytes(), success: true},
+		{raw: true, body: plain, success: true},
+		// the gzip magic isn't detected, so the compressed body is decoded as is
+		{raw: true, body: gz.Bytes(), success: false},
+	} {
+		ctx := context.Background()
+		if tc.raw {
+			ctx = WithRawChunks(ctx)
+		}
+		body := tc.body
+		scd := &snowflakeChunkDownloader{
+			sc: &snowflakeConn{
+				rest: &snowflakeRestful{RequestTimeout: defaultRequestTimeout},
+			},
+			ctx:           ctx,
+			ChunkMetas:    []execResponseChunk{{URL: "dummyURL1", RowCount: 2}},
+			TotalRowIndex: int64(-1),
+			FuncGet: func(_ context.Context, _ *snowflakeChunkDownloader, _ string, _ map[string]string, _ time.Duration) (
+				*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       &fakeResponseBody{body: body},
+				}, nil
+			},
+		}
+		scd.ChunksMutex = &sync.Mutex{}
+		scd.DoneDownloadCond = sync.NewCond(scd.ChunksMutex)
+		scd.Chunks = make(map[int][]chunkRowType)
+		err := downloadChunkHelper(scd.ctx, scd, 0)
+		if !tc.success {
+			if err == nil {
+				t.Fatalf("should have failed to decode the chunk. raw: %v", tc.raw)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to download the chunk. raw: %v, err: %v", tc.raw, err)
+		}
+		if len(scd.Chunks[0]) != 2 || *scd.Chunks[0][1].RowSet[1] != "b" {
+			t.Fatalf("unexpected chunk rows. raw: %v, rows: %v", tc.raw, scd.Chunks[0])
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	rt := []execResponseRowType{
+		{Name: "ID", Type: "fixed", Scale: 0, Nullable: true},
+		{Name: "AMOUNT", Type: "fixed", Precision: 10, Scale: 2, Nullable: true},
+		{Name: "NOTE", Type: "text", Nullable: true},
+		{Name: "FLAG", Type: "boolean", Nullable: true},
+		{Name: "DAY", Type: "date", Nullable: true},
+		{Name: "CREATED", Type: "timestamp_ntz", Scale: 9, Nullable: true},
+		{Name: "PAYLOAD", Type: "binary", Nullable: true},
+	}
+	expected := "ID,AMOUNT,NOTE,FLAG,DAY,CREATED,PAYLOAD\n" +
+		"42,123.45,\"say \"\"hi\"\", bye\",true,2021-01-01,2021-01-01 12:00:00.123,48656C6C6F\n" +
+		",,\"\",,,,\n"
+
+	id, amount, note, flag, day, created, payload := "42", "123.45", `say "hi", bye`, "true", "18628", "1609502400.123000000", "48656C6C6F"
+	empty := ""
+	rows := &snowflakeRows{ctx: context.Background()}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		ctx:           context.Background(),
+		Total:         2,
+		TotalRowIndex: int64(-1),
+		RowSet: rowSetType{RowType: rt, JSON: [][]*string{
+			{&id, &amount, &note, &flag, &day, &created, &payload},
+			{nil, nil, &empty, nil, nil, nil, nil},
+		}},
+	}
+	rows.ChunkDownloader.start()
+	var buf bytes.Buffer
+	if err := rows.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Fatalf("unexpected CSV from JSON result. expected:\n%v\ngot:\n%v", expected, buf.String())
+	}
+
+	ts := time.Date(2021, 1, 1, 12, 0, 0, 123000000, time.UTC)
+	rows = &snowflakeRows{ctx: context.Background()}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		ctx:               context.Background(),
+		Total:             2,
+		TotalRowIndex:     int64(-1),
+		CurrentIndex:      -1,
+		CurrentChunkIndex: -1,
+		CurrentChunkSize:  2,
+		CurrentChunk: []chunkRowType{
+			{ArrowRow: []snowflakeValue{int64(42), intToBigFloat(12345, 2), `say "hi", bye`, true,
+				time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), ts, []byte("Hello")}},
+			{ArrowRow: []snowflakeValue{nil, nil, "", nil, nil, nil, nil}},
+		},
+		QueryResultFormat: "arrow",
+		RowSet:            rowSetType{RowType: rt},
+	}
+	buf.Reset()
+	if err := rows.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Fatalf("unexpected CSV from Arrow result. expected:\n%v\ngot:\n%v", expected, buf.String())
+	}
+}
+
+func TestChunkDownloaderMaxRetries(t *testing.T) {
+	numChunks := 4
+	rt := []execResponseRowType{
+		{Name: "c1", ByteLength: 10, Length: 10, Type: "FIXED", Scale: 0, Nullable: true},
+		{Name: "c2", ByteLength: 100000, Length: 100000, Type: "TEXT", Scale: 0, Nullable: false},
+	}
+	cm := make([]execResponseChunk, 0)
+	for i := 0; i < numChunks; i++ {
+		cm = append(cm, execResponseChunk{URL: fmt.Sprintf("dummyURL%v", i+1), RowCount: rowsInChunk})
+	}
+	// download one chunk at a time so that the retries of chunks 1 and 3 don't interleave
+	backupMaxChunkDownloadWorkers := MaxChunkDownloadWorkers
+	MaxChunkDownloadWorkers = 1
+	defer func() { MaxChunkDownloadWorkers = backupMaxChunkDownloadWorkers }()
+
+	// chunks 1 and 3 fail twice each before they download, which only recovers
+	// with at least 2 retries if the retries are counted per chunk
+	newDownloadChunk := func() func(context.Context, *snowflakeChunkDownloader, int) {
+		attempts := make(map[int]int)
+		return func(ctx context.Context, scd *snowflakeChunkDownloader, idx int) {
+			scd.ChunksMutex.Lock()
+			defer scd.ChunksMutex.Unlock()
+			attempts[idx]++
+			if (idx == 1 || idx == 3) && attempts[idx] <= 2 {
+				scd.ChunksError <- &chunkError{
+					Index: idx,
+					Error: fmt.Errorf("dummy error. idx: %v, attempt: %v", idx+1, attempts[idx])}
+				scd.DoneDownloadCond.Broadcast()
+				return
+			}
+			d := make([][]*string, 0)
+			for i := 0; i < rowsInChunk; i++ {
+				v1 := fmt.Sprintf("%v", idx*1000+i)
+				v2 := fmt.Sprintf("testchunk%v", idx*1000+i)
+				d = append(d, []*string{&v1, &v2})
+			}
+			scd.Chunks[idx] = make([]chunkRowType, len(d))
+			populateJSONRowSet(scd.Chunks[idx], d)
+			scd.DoneDownloadCond.Broadcast()
+		}
+	}
+
+	for _, tc := range []struct {
+		retries int
+		fail    bool
+	}{
+		{retries: 1, fail: true},
+		{retries: -1, fail: true},
+		{retries: 3, fail: false},
+		{retries: 0, fail: false},
+	} {
+		sc := &snowflakeConn{cfg: &Config{MaxChunkDownloadRetries: tc.retries}, rest: &snowflakeRestful{}}
+		scd := populateChunkDownloader(context.Background(), sc, execResponseData{
+			RowType: rt,
+			Total:   int64(numChunks * rowsInChunk),
+			Chunks:  cm,
+		}).(*snowflakeChunkDownloader)
+		scd.FuncDownload = newDownloadChunk()
+		rows := &snowflakeRows{ctx: context.Background(), ChunkDownloader: scd}
+		if err := rows.ChunkDownloader.start(); err != nil {
+			t.Fatal(err)
+		}
+		cnt := 0
+		dest := make([]driver.Value, 2)
+		var err error
+		for {
+			if err = rows.Next(dest); err != nil {
+				break
+			}
+			cnt++
+		}
+		if tc.fail {
+			if err == io.EOF {
+				t.Fatalf("retries: %v. the download should have failed", tc.retries)
+			}
+			driverErr, ok := err.(*SnowflakeError)
+			if !ok || driverErr.Number != ErrFailedToGetChunk {
+				t.Fatalf("retries: %v. unexpected error: %v", tc.retries, err)
+			}
+			if cnt != rowsInChunk {
+				t.Fatalf("retries: %v. the rows of the first chunk should have been read. expected: %v, got: %v",
+					tc.retries, rowsInChunk, cnt)
+			}
+		} else {
+			if err != io.EOF {
+				t.Fatalf("retries: %v. the download should have recovered. err: %v", tc.retries, err)
+			}
+			if cnt != numChunks*rowsInChunk {
+				t.Fatalf("retries: %v. failed to get all results. expected: %v, got: %v",
+					tc.retries, numChunks*rowsInChunk, cnt)
+			}
+		}
+	}
+}
+
+func TestRowsStats(t *testing.T) {
+	var requested int
+	getMock := func(ctx context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		requested++
+		if fullURL.Path != "/monitoring/queries/01a2b3c4-0000-0000-0000-000000000001" {
+			t.Fatalf("unexpected path: %v", fullURL.Path)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(`{"data": {"queries": [{"id": "01a2b3c4-0000-0000-0000-000000000001",
+				"status": "SUCCESS", "totalDuration": 1500, "stats": {"scanBytes": 1048576, "producedRows": 42, "compilationTime": 300, "executionTime": 1100}}]},
+				"code": null, "message": null, "success": true}`)),
+		}, nil
+	}
+	// the context of the query is done by the time the stats are read
+	queryCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rows := &snowflakeRows{
+		sc: &snowflakeConn{
+			cfg: &Config{Params: map[string]*string{}},
+			rest: &snowflakeRestful{
+				Protocol:      "https",
+				Host:          "abc.snowflakecomputing.com",
+				Port:          443,
+				TokenAccessor: getSimpleTokenAccessor(),
+				FuncGet:       getMock,
+			},
+		},
+		ctx:     queryCtx,
+		queryID: "01a2b3c4-0000-0000-0000-000000000001",
+	}
+	expected := QueryStats{
+		ScanBytes:       1048576,
+		ProducedRows:    42,
+		TotalDuration:   1500 * time.Millisecond,
+		CompilationTime: 300 * time.Millisecond,
+		ExecutionTime:   1100 * time.Millisecond,
+	}
+	for i := 0; i < 2; i++ {
+		stats, err := rows.Stats(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *stats != expected {
+			t.Fatalf("unexpected stats. expected: %+v, got: %+v", expected, *stats)
+		}
+	}
+	if requested != 1 {
+		t.Fatalf("the monitoring data should have been fetched once. fetched: %v", requested)
+	}
+}
+
+func TestColumnTypeDatabaseTypeName(t *testing.T) {
+	rt := []execResponseRowType{
+		{Name: "c1", Type: "fixed", Precision: 38, Scale: 2},
+		{Name: "c2", Type: "fixed", Precision: 10, Scale: 0},
+		{Name: "c3", Type: "real"},
+		{Name: "c4", Type: "text", Length: 16},
+		{Name: "c5", Type: "text"},
+		{Name: "c6", Type: "binary", Length: 8388608},
+		{Name: "c7", Type: "boolean"},
+		{Name: "c8", Type: "date"},
+		{Name: "c9", Type: "time", Scale: 3},
+		{Name: "c10", Type: "timestamp_ntz", Scale: 9},
+		{Name: "c11", Type: "timestamp_ltz", Scale: 6},
+		{Name: "c12", Type: "timestamp_tz", Scale: 0},
+		{Name: "c13", Type: "variant", Length: 16777216},
+		{Name: "c14", Type: "geography"},
+	}
+	expected := []string{"NUMBER(38,2)", "NUMBER(10,0)", "FLOAT", "VARCHAR(16)", "VARCHAR", "BINARY(8388608)",
+		"BOOLEAN", "DATE", "TIME(3)", "TIMESTAMP_NTZ(9)", "TIMESTAMP_LTZ(6)", "TIMESTAMP_TZ(0)", "VARIANT", "GEOGRAPHY"}
+	rows := &snowflakeRows{
+		ChunkDownloader: &snowflakeChunkDownloader{RowSet: rowSetType{RowType: rt}},
+	}
+	for i, e := range expected {
+		if name := rows.ColumnTypeDatabaseTypeName(i); name != e {
+			t.Fatalf("unexpected database type name of %v. expected: %v, got: %v", rt[i].Name, e, name)
+		}
+	}
+}
+
+func TestRowsChunkDownloadInfo(t *testing.T) {
+	chunks := []execResponseChunk{
+		{URL: "https://sfc-stage/results/data_0_0_0", RowCount: 100, UncompressedSize: 2048, CompressedSize: 512},
+		{URL: "https://sfc-stage/results/data_0_0_1", RowCount: 50, UncompressedSize: 1024, CompressedSize: 256},
+	}
+	for _, tc := range []struct {
+		name    string
+		scd     *snowflakeChunkDownloader
+		headers map[string]string
+		format  string
+	}{
+		{
+			name:    "chunk headers",
+			scd:     &snowflakeChunkDownloader{ChunkHeader: map[string]string{"x-amz-server-side-encryption-customer-key": "key"}, QueryResultFormat: "arrow"},
+			headers: map[string]string{"x-amz-server-side-encryption-customer-key": "key"},
+			format:  "arrow",
+		},
+		{
+			name:    "qrmk",
+			scd:     &snowflakeChunkDownloader{Qrmk: "qrmk"},
+			headers: map[string]string{headerSseCAlgorithm: headerSseCAes, headerSseCKey: "qrmk"},
+			format:  "json",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.scd.ChunkMetas = chunks
+			rows := &snowflakeRows{ChunkDownloader: tc.scd}
+			infos, err := rows.ChunkDownloadInfo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(infos) != len(chunks) {
+				t.Fatalf("expected %v chunks, got: %v", len(chunks), len(infos))
+			}
+			for i, info := range infos {
+				expected := ChunkDownloadInfo{
+					URL:              chunks[i].URL,
+					Headers:          tc.headers,
+					Format:           tc.format,
+					RowCount:         chunks[i].RowCount,
+					UncompressedSize: chunks[i].UncompressedSize,
+					CompressedSize:   chunks[i].CompressedSize,
+				}
+				if !reflect.DeepEqual(info, expected) {
+					t.Fatalf("unexpected download info of chunk %v. expected: %+v, got: %+v", i, expected, info)
+				}
+			}
+		})
+	}
+}
+
+func TestRowsFetchChunkRange(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema([]arrow.Field{{Name: "C1", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	chunks := make([]execResponseChunk, 3)
+	bodies := make(map[string][]byte)
+	for i := range chunks {
+		b := array.NewInt64Builder(pool)
+		b.AppendValues([]int64{int64(i * 10), int64(i*10 + 1)}, nil)
+		col := b.NewArray()
+		rec := array.NewRecord(schema, []array.Interface{col}, int64(col.Len()))
+		var buf bytes.Buffer
+		w := ipc.NewWriter(&buf, ipc.WithSchema(schema), ipc.WithAllocator(pool))
+		if err := w.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		rec.Release()
+		col.Release()
+
+		chunks[i] = execResponseChunk{URL: fmt.Sprintf("https://sfc-stage/results/data_0_0_%v", i), RowCount: 2}
+		if i == 1 {
+			// chunks may be gzipped
+			var gz bytes.Buffer
+			zw := gzip.NewWriter(&gz)
+			zw.Write(buf.Bytes())
+			zw.Close()
+			buf = gz
+		}
+		bodies[chunks[i].URL] = buf.Bytes()
+	}
+
+	var requested []string
+	getMock := func(_ context.Context, _ *snowflakeRestful, u *url.URL, headers map[string]string, _ time.Duration) (*http.Response, error) {
+		requested = append(requested, u.String())
+		if headers[headerSseCKey] != "qrmk" {
+			t.Fatalf("missing chunk headers: %v", headers)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader(bodies[u.String()])),
+		}, nil
+	}
+	rows := &snowflakeRows{
+		sc: &snowflakeConn{
+			cfg:  &Config{Params: map[string]*string{}},
+			rest: &snowflakeRestful{FuncGet: getMock},
+		},
+		ChunkDownloader: &snowflakeChunkDownloader{
+			ChunkMetas:        chunks,
+			Qrmk:              "qrmk",
+			QueryResultFormat: "arrow",
+		},
+	}
+	var sr SnowflakeRows = rows
+
+	records, err := sr.FetchChunkRange(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requested) != 1 || requested[0] != chunks[1].URL {
+		t.Fatalf("only the middle chunk should have been fetched. got: %v", requested)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got: %v", len(records))
+	}
+	values := records[0].Column(0).(*array.Int64).Int64Values()
+	if !reflect.DeepEqual(values, []int64{10, 11}) {
+		t.Fatalf("unexpected values of the middle chunk: %v", values)
+	}
+	records[0].Release()
+
+	records, err = sr.FetchChunkRange(context.Background(), 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got: %v", len(records))
+	}
+	for _, rec := range records {
+		rec.Release()
+	}
+
+	for _, r := range [][2]int{{-1, 1}, {2, 2}, {2, 4}, {3, 4}} {
+		_, err = sr.FetchChunkRange(context.Background(), r[0], r[1])
+		if se, ok := err.(*SnowflakeError); !ok || se.Number != ErrInvalidChunkRange {
+			t.Fatalf("range %v should have been rejected. err: %v", r, err)
+		}
+	}
+
+	rows.ChunkDownloader = &snowflakeChunkDownloader{ChunkMetas: chunks}
+	_, err = sr.FetchChunkRange(context.Background(), 0, 1)
+	if se, ok := err.(*SnowflakeError); !ok || se.Number != ErrNotArrowResult {
+		t.Fatalf("a JSON result should have been rejected. err: %v", err)
+	}
+}
+
+func TestRowsRawArrowBatches(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema([]arrow.Field{{Name: "C1", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	ipcStream := func(values ...int64) []byte {
+		b := array.NewInt64Builder(pool)
+		b.AppendValues(values, nil)
+		col := b.NewArray()
+		rec := array.NewRecord(schema, []array.Interface{col}, int64(col.Len()))
+		var buf bytes.Buffer
+		w := ipc.NewWriter(&buf, ipc.WithSchema(schema), ipc.WithAllocator(pool))
+		if err := w.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		rec.Release()
+		col.Release()
+		return buf.Bytes()
+	}
+	readValues := func(r io.Reader) []int64 {
+		reader, err := ipc.NewReader(r)
+		if err != nil {
+			t.Fatalf("not an IPC stream: %v", err)
+		}
+		defer reader.Release()
+		var values []int64
+		for reader.Next() {
+			values = append(values, reader.Record().Column(0).(*array.Int64).Int64Values()...)
+		}
+		if err = reader.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return values
+	}
+
+	first := ipcStream(1, 2)
+	chunk := ipcStream(3, 4)
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(chunk)
+	zw.Close()
+	chunks := []execResponseChunk{{URL: "https://sfc-stage/results/data_0_0_0", RowCount: 2}}
+	getMock := func(_ context.Context, _ *snowflakeRestful, u *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader(gz.Bytes())),
+		}, nil
+	}
+	rows := &snowflakeRows{
+		sc: &snowflakeConn{
+			cfg:  &Config{Params: map[string]*string{}},
+			rest: &snowflakeRestful{FuncGet: getMock},
+		},
+		ChunkDownloader: &snowflakeChunkDownloader{
+			ChunkMetas:        chunks,
+			QueryResultFormat: "arrow",
+			RowSet:            rowSetType{RowSetBase64: base64.StdEncoding.EncodeToString(first)},
+		},
+	}
+	var sr SnowflakeRows = rows
+
+	b, err := sr.FirstArrowBatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, first) {
+		t.Fatal("the first batch should be the bytes sent by the server")
+	}
+	if values := readValues(bytes.NewReader(b)); !reflect.DeepEqual(values, []int64{1, 2}) {
+		t.Fatalf("unexpected values of the first batch: %v", values)
+	}
+
+	stream, err := sr.OpenChunk(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := readValues(stream)
+	if err = stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []int64{3, 4}) {
+		t.Fatalf("unexpected values of the chunk: %v", values)
+	}
+
+	if _, err = sr.OpenChunk(context.Background(), 1); err == nil {
+		t.Fatal("a chunk out of range should have been rejected")
+	} else if se, ok := err.(*SnowflakeError); !ok || se.Number != ErrInvalidChunkRange {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{ChunkMetas: chunks}
+	if _, err = sr.FirstArrowBatch(); err == nil {
+		t.Fatal("a JSON result should have been rejected")
+	} else if se, ok := err.(*SnowflakeError); !ok || se.Number != ErrNotArrowResult {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type chunkBodiesTransport map[string]string
+
+func (t chunkBodiesTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	body, ok := t[r.URL.String()]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+}
+
+func TestRowsTotalRowsAndNumChunks(t *testing.T) {
+	str := func(s string) *string { return &s }
+	transport := chunkBodiesTransport{
+		"https://sfc-stage/results/data_0_0_0": `["3"],["4"],["5"]`,
+		"https://sfc-stage/results/data_0_0_1": `["6"],["7"]`,
+	}
+	var data execResponseData
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ uuid.UUID, _ *Config) (*execResponse, error) {
+		return &execResponse{Data: data, Code: "0", Success: true}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: postQueryMock,
+			Client:        &http.Client{Transport: transport},
+		},
+	}
+	for _, tc := range []struct {
+		name      string
+		data      execResponseData
+		numChunks int
+	}{
+		{"inline rows and chunks", execResponseData{
+			RowType:           []execResponseRowType{{Name: "C1", Type: "fixed"}},
+			RowSet:            [][]*string{{str("1")}, {str("2")}},
+			Total:             7,
+			Returned:          7,
+			QueryResultFormat: "json",
+			Chunks: []execResponseChunk{
+				{URL: "https://sfc-stage/results/data_0_0_0", RowCount: 3},
+				{URL: "https://sfc-stage/results/data_0_0_1", RowCount: 2},
+			},
+		}, 3},
+		{"chunks only", execResponseData{
+			RowType:           []execResponseRowType{{Name: "C1", Type: "fixed"}},
+			Total:             5,
+			Returned:          5,
+			QueryResultFormat: "json",
+			Chunks: []execResponseChunk{
+				{URL: "https://sfc-stage/results/data_0_0_0", RowCount: 3},
+				{URL: "https://sfc-stage/results/data_0_0_1", RowCount: 2},
+			},
+		}, 2},
+		{"inline rows only", execResponseData{
+			RowType:           []execResponseRowType{{Name: "C1", Type: "fixed"}},
+			RowSet:            [][]*string{{str("1")}, {str("2")}},
+			Total:             2,
+			Returned:          2,
+			QueryResultFormat: "json",
+		}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data = tc.data
+			rows, err := sc.QueryContext(context.Background(), "select 1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			sr := rows.(SnowflakeRows)
+			if total := sr.TotalRows(); total != tc.data.Total {
+				t.Fatalf("expected %v rows, got: %v", tc.data.Total, total)
+			}
+			if n := sr.NumChunks(); n != tc.numChunks {
+				t.Fatalf("expected %v chunks, got: %v", tc.numChunks, n)
+			}
+			var read int64
+			dest := make([]driver.Value, 1)
+			for rows.Next(dest) == nil {
+				read++
+			}
+			if read != tc.data.Total {
+				t.Fatalf("the rows should match the total. expected: %v, read: %v", tc.data.Total, read)
+			}
+		})
+	}
+}
+
+// panickingChunkDownloader is a chunk downloader that panics when its rows
+// are read
+type panickingChunkDownloader struct {
+	*snowflakeChunkDownloader
+}
+
+func (pcd *panickingChunkDownloader) next() (chunkRowType, error) {
+	panic("chunk download failed")
+}
+
+func TestRowsRecoverChunkPanics(t *testing.T) {
+	newRows := func(ctx context.Context) *snowflakeRows {
+		return &snowflakeRows{
+			ctx:             ctx,
+			queryID:         "qid",
+			ChunkDownloader: &panickingChunkDownloader{&snowflakeChunkDownloader{}},
+		}
+	}
+
+	rows := newRows(WithRecoverChunkPanics(context.Background()))
+	dest := make([]driver.Value, 1)
+	for i := 0; i < 2; i++ {
+		err := rows.Next(dest)
+		driverErr, ok := err.(*SnowflakeError)
+		if !ok || driverErr.Number != ErrChunkPanic || driverErr.QueryID != "qid" {
+			t.Fatalf("should have failed with ErrChunkPanic, got: %v", err)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("the panic should have been propagated by default")
+		}
+	}()
+	newRows(context.Background()).Next(dest)
+}
+
+func TestRowsNextMap(t *testing.T) {
+	id, name, ts := "1", "a", "1549462651.123"
+	rt := []execResponseRowType{
+		{Name: "ID", Type: "fixed"},
+		{Name: "NAME", Type: "text", Nullable: true},
+		{Name: "TS", Type: "timestamp_ntz", Scale: 3},
+	}
+	ctx := WithRawTimestamps(context.Background())
+	rows := &snowflakeRows{
+		ctx: ctx,
+		ChunkDownloader: &snowflakeChunkDownloader{
+			ctx:           ctx,
+			Total:         2,
+			TotalRowIndex: int64(-1),
+			RowSet:        rowSetType{RowType: rt, JSON: [][]*string{{&id, &name, &ts}, {&id, nil, &ts}}},
+		},
+	}
+	rows.ChunkDownloader.start()
+
+	expected := []map[string]interface{}{
+		{"ID": "1", "NAME": "a", "TS": int64(1549462651123)},
+		{"ID": "1", "NAME": nil, "TS": int64(1549462651123)},
+	}
+	for i := range expected {
+		row, err := rows.NextMap()
+		if err != nil {
+			t.Fatalf("failed to get row %v. err: %v", i, err)
+		}
+		if !reflect.DeepEqual(row, expected[i]) {
+			t.Fatalf("unexpected row %v. expected: %v, got: %v", i, expected[i], row)
+		}
+	}
+	if _, err := rows.NextMap(); err != io.EOF {
+		t.Fatalf("failed to finish getting data. err: %v", err)
+	}
+}
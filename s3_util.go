@@ -35,7 +35,7 @@ type s3Location struct {
 	s3Path     string
 }
 
-func (util *snowflakeS3Util) createClient(info *execResponseStageInfo, useAccelerateEndpoint bool) cloudClient {
+func (util *snowflakeS3Util) createClient(info *execResponseStageInfo, useAccelerateEndpoint bool) (cloudClient, error) {
 	stageCredentials := info.Creds
 	var resolver s3.EndpointResolver
 	if info.EndPoint != "" {
@@ -50,7 +50,7 @@ func (util *snowflakeS3Util) createClient(info *execResponseStageInfo, useAccele
 			stageCredentials.AwsToken)),
 		EndpointResolver: resolver,
 		UseAccelerate:    useAccelerateEndpoint,
-	})
+	}), nil
 }
 
 type s3HeaderAPI interface {
@@ -145,7 +145,19 @@ func (util *snowflakeS3Util) uploadFile(
 	}
 
 	var err error
-	if meta.srcStream != nil {
+	if meta.srcReader != nil {
+		uploadReader := meta.srcReader
+		if meta.realSrcReader != nil {
+			uploadReader = meta.realSrcReader
+		}
+		_, err = uploader.Upload(context.Background(), &s3.PutObjectInput{
+			Bucket:        &s3loc.bucketName,
+			Key:           &s3path,
+			Body:          uploadReader,
+			ContentLength: meta.uploadSize,
+			Metadata:      s3Meta,
+		})
+	} else if meta.srcStream != nil {
 		uploadStream := meta.srcStream
 		if meta.realSrcStream != nil {
 			uploadStream = meta.realSrcStream
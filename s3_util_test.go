@@ -4,8 +4,11 @@ package gosnowflake
 
 import (
 	"context"
+	"io"
+	"io/ioutil"
 	"os"
 	"path"
+	"runtime"
 	"strconv"
 	"testing"
 
@@ -60,12 +63,16 @@ func TestUploadOneFileToS3WSAEConnAborted(t *testing.T) {
 	initialParallel := int64(100)
 	dir, _ := os.Getwd()
 
+	s3Cli, err := new(snowflakeS3Util).createClient(&info, false)
+	if err != nil {
+		t.Fatal(err)
+	}
 	uploadMeta := fileMetadata{
 		name:              "data1.txt.gz",
 		stageLocationType: "S3",
 		noSleepingTime:    true,
 		parallel:          initialParallel,
-		client:            new(snowflakeS3Util).createClient(&info, false),
+		client:            s3Cli,
 		sha256Digest:      "123456789abcdef",
 		stageInfo:         &info,
 		dstFileName:       "data1.txt.gz",
@@ -125,12 +132,16 @@ func TestUploadOneFileToS3ConnReset(t *testing.T) {
 	initialParallel := int64(100)
 	dir, _ := os.Getwd()
 
+	s3Cli, err := new(snowflakeS3Util).createClient(&info, false)
+	if err != nil {
+		t.Fatal(err)
+	}
 	uploadMeta := fileMetadata{
 		name:              "data1.txt.gz",
 		stageLocationType: "S3",
 		noSleepingTime:    true,
 		parallel:          initialParallel,
-		client:            new(snowflakeS3Util).createClient(&info, false),
+		client:            s3Cli,
 		sha256Digest:      "123456789abcdef",
 		stageInfo:         &info,
 		dstFileName:       "data1.txt.gz",
@@ -172,12 +183,16 @@ func TestUploadFileWithS3UploadFailedError(t *testing.T) {
 	initialParallel := int64(100)
 	dir, _ := os.Getwd()
 
+	s3Cli, err := new(snowflakeS3Util).createClient(&info, false)
+	if err != nil {
+		t.Fatal(err)
+	}
 	uploadMeta := fileMetadata{
 		name:              "data1.txt.gz",
 		stageLocationType: "S3",
 		noSleepingTime:    true,
 		parallel:          initialParallel,
-		client:            new(snowflakeS3Util).createClient(&info, false),
+		client:            s3Cli,
 		sha256Digest:      "123456789abcdef",
 		stageInfo:         &info,
 		dstFileName:       "data1.txt.gz",
@@ -257,3 +272,73 @@ func TestGetHeaderUnexpectedError(t *testing.T) {
 		t.Fatalf("expected %v result status, got: %v", errStatus, meta.resStatus)
 	}
 }
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestUploadSizedStreamToS3BoundedMemory(t *testing.T) {
+	info := execResponseStageInfo{
+		Location:     "sfc-teststage/rwyitestacco/users/1234/",
+		LocationType: "S3",
+	}
+	size := int64(256 * 1024 * 1024)
+	var uploadedBytes int64
+	var contentLength int64
+
+	s3Cli, err := new(snowflakeS3Util).createClient(&info, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploadMeta := fileMetadata{
+		name:              "data1.txt",
+		stageLocationType: "S3",
+		noSleepingTime:    true,
+		parallel:          1,
+		client:            s3Cli,
+		stageInfo:         &info,
+		dstFileName:       "data1.txt",
+		srcFileName:       "data1.txt",
+		srcReader:         io.LimitReader(zeroReader{}, size),
+		srcFileSize:       int(size),
+		uploadSize:        size,
+		overwrite:         true,
+		encryptionMaterial: &snowflakeFileEncryption{
+			"ztke8tIdVt1zmlQIZm0BMA==",
+			"123873c7-3a66-40c4-ab89-e3722fbccce1",
+			3112,
+		},
+		options: &SnowflakeFileTransferOptions{
+			multiPartThreshold: dataSizeThreshold,
+		},
+		mockUploader: mockUploadObjectAPI(func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+			contentLength = params.ContentLength
+			n, err := io.Copy(ioutil.Discard, params.Body)
+			uploadedBytes = n
+			return &manager.UploadOutput{}, err
+		}),
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	if err := new(remoteStorageUtil).uploadOneFile(&uploadMeta); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+
+	if uploadMeta.resStatus != uploaded {
+		t.Fatalf("expected %v result status, got: %v", uploaded, uploadMeta.resStatus)
+	}
+	if uploadedBytes != encryptedSize(size) || contentLength != uploadedBytes {
+		t.Fatalf("expected %v bytes, got: %v uploaded, %v declared", encryptedSize(size), uploadedBytes, contentLength)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > uint64(size/16) {
+		t.Fatalf("upload allocated %v bytes for a %v byte stream", allocated, size)
+	}
+}
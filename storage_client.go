@@ -4,8 +4,11 @@ package gosnowflake
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"math"
+	"os"
 	"time"
 )
 
@@ -16,14 +19,14 @@ const (
 
 // implemented by localUtil and remoteStorageUtil
 type storageUtil interface {
-	createClient(*execResponseStageInfo, bool) cloudClient
+	createClient(*execResponseStageInfo, bool) (cloudClient, error)
 	uploadOneFileWithRetry(*fileMetadata) error
 	downloadOneFile()
 }
 
 // implemented by snowflakeS3Util, snowflakeAzureUtil and snowflakeGcsUtil
 type cloudUtil interface {
-	createClient(*execResponseStageInfo, bool) cloudClient
+	createClient(*execResponseStageInfo, bool) (cloudClient, error)
 	getFileHeader(*fileMetadata, string) *fileHeader
 	uploadFile(string, *fileMetadata, *encryptMetadata, int, int64) error
 	nativeDownloadFile()
@@ -32,9 +35,13 @@ type cloudUtil interface {
 type cloudClient interface{}
 
 type remoteStorageUtil struct {
+	factory StorageClientFactory // replaces the clients of the cloud SDKs when set
 }
 
 func (rsu *remoteStorageUtil) getNativeCloudType(cli string) cloudUtil {
+	if rsu.factory != nil {
+		return &factoryStorageUtil{rsu.factory}
+	}
 	if cloudType(cli) == s3Client {
 		return &snowflakeS3Util{}
 	} else if cloudType(cli) == azureClient {
@@ -46,7 +53,7 @@ func (rsu *remoteStorageUtil) getNativeCloudType(cli string) cloudUtil {
 }
 
 // call cloud utils' native create client methods
-func (rsu *remoteStorageUtil) createClient(info *execResponseStageInfo, useAccelerateEndpoint bool) cloudClient {
+func (rsu *remoteStorageUtil) createClient(info *execResponseStageInfo, useAccelerateEndpoint bool) (cloudClient, error) {
 	utilClass := rsu.getNativeCloudType(info.LocationType)
 	return utilClass.createClient(info, useAccelerateEndpoint)
 }
@@ -56,7 +63,14 @@ func (rsu *remoteStorageUtil) uploadOneFile(meta *fileMetadata) error {
 	var dataFile string
 	var err error
 	if meta.encryptionMaterial != nil {
-		if meta.srcStream != nil {
+		if meta.srcReader != nil {
+			encryptMeta, meta.realSrcReader, err = newEncryptReader(meta.encryptionMaterial, meta.srcReader, 0)
+			if err != nil {
+				return err
+			}
+			meta.uploadSize = encryptedSize(int64(meta.srcFileSize))
+			dataFile = meta.realSrcFileName
+		} else if meta.srcStream != nil {
 			var encryptedStream bytes.Buffer
 			srcStream := meta.srcStream
 			if meta.realSrcStream != nil {
@@ -82,6 +96,10 @@ func (rsu *remoteStorageUtil) uploadOneFile(meta *fileMetadata) error {
 	maxConcurrency := int(meta.parallel)
 	var lastErr error
 	maxRetry := defaultMaxRetry
+	if meta.srcReader != nil {
+		// a sized stream is consumed by the first attempt and cannot be replayed
+		maxRetry = 1
+	}
 	for retry := 0; retry < maxRetry; retry++ {
 		if !meta.overwrite {
 			header := utilClass.getFileHeader(meta, meta.dstFileName)
@@ -161,3 +179,185 @@ func (rsu *remoteStorageUtil) downloadOneFile() {
 	// TODO SNOW-294151
 	panic("not implemented")
 }
+
+// StageInfo is the cloud storage location of a stage and the temporary
+// credentials to access it, as returned by Snowflake for a file transfer
+type StageInfo struct {
+	LocationType   string // S3, AZURE or GCS
+	Location       string // bucket or container followed by the path of the stage
+	Path           string
+	Region         string
+	StorageAccount string
+	EndPoint       string
+	PresignedURL   string
+	// Credentials holds AWS_KEY_ID, AWS_SECRET_KEY and AWS_TOKEN for S3,
+	// AZURE_SAS_TOKEN for Azure and GCS_ACCESS_TOKEN for GCS
+	Credentials map[string]string
+}
+
+// StorageFileHeader describes a file stored on a stage
+type StorageFileHeader struct {
+	ContentLength int64
+	Metadata      map[string]string
+}
+
+// StorageClient stores the files of PUT commands on a stage. The metadata of
+// a file holds its SHA-256 digest under sfc-digest and, if the stage is
+// encrypted, its encryption key, IV and material description under
+// x-amz-key, x-amz-iv and x-amz-matdesc.
+type StorageClient interface {
+	// Upload stores the data of the file name, which is size bytes long or
+	// -1 if unknown, under the location of the stage
+	Upload(ctx context.Context, name string, data io.Reader, size int64, metadata map[string]string) error
+	// FileHeader returns the header of the file name stored under the
+	// location of the stage, or nil if there is no such file
+	FileHeader(ctx context.Context, name string) (*StorageFileHeader, error)
+}
+
+// StorageClientFactory creates the storage clients used by file transfers to
+// cloud stages instead of the clients of the S3, Azure and GCS SDKs, e.g. to
+// route them through a storage gateway
+type StorageClientFactory interface {
+	NewStorageClient(info StageInfo) (StorageClient, error)
+}
+
+func toStageInfo(info *execResponseStageInfo) StageInfo {
+	creds := make(map[string]string)
+	for k, v := range map[string]string{
+		"AWS_KEY_ID":       info.Creds.AwsKeyID,
+		"AWS_SECRET_KEY":   info.Creds.AwsSecretKey,
+		"AWS_TOKEN":        info.Creds.AwsToken,
+		"AZURE_SAS_TOKEN":  info.Creds.AzureSasToken,
+		"GCS_ACCESS_TOKEN": info.Creds.GcsAccessToken,
+	} {
+		if v != "" {
+			creds[k] = v
+		}
+	}
+	return StageInfo{
+		LocationType:   info.LocationType,
+		Location:       info.Location,
+		Path:           info.Path,
+		Region:         info.Region,
+		StorageAccount: info.StorageAccount,
+		EndPoint:       info.EndPoint,
+		PresignedURL:   info.PresignedURL,
+		Credentials:    creds,
+	}
+}
+
+// factoryStorageUtil is the cloudUtil of the clients created by a
+// StorageClientFactory
+type factoryStorageUtil struct {
+	factory StorageClientFactory
+}
+
+// cloudUtil implementation
+func (util *factoryStorageUtil) createClient(info *execResponseStageInfo, _ bool) (cloudClient, error) {
+	client, err := util.factory.NewStorageClient(toStageInfo(info))
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// cloudUtil implementation
+func (util *factoryStorageUtil) getFileHeader(meta *fileMetadata, filename string) *fileHeader {
+	client, ok := meta.client.(StorageClient)
+	if !ok {
+		meta.resStatus = errStatus
+		meta.lastError = fmt.Errorf("no storage client for the stage")
+		return nil
+	}
+	header, err := client.FileHeader(context.Background(), filename)
+	if err != nil {
+		meta.resStatus = errStatus
+		meta.lastError = err
+		return nil
+	}
+	if header == nil {
+		meta.resStatus = notFoundFile
+		return &fileHeader{}
+	}
+	meta.resStatus = uploaded
+	var encMeta encryptMetadata
+	if header.Metadata[amzKey] != "" {
+		encMeta = encryptMetadata{
+			header.Metadata[amzKey],
+			header.Metadata[amzIv],
+			header.Metadata[amzMatdesc],
+		}
+	}
+	return &fileHeader{
+		header.Metadata[sfcDigest],
+		header.ContentLength,
+		&encMeta,
+	}
+}
+
+// cloudUtil implementation
+func (util *factoryStorageUtil) uploadFile(
+	dataFile string,
+	meta *fileMetadata,
+	encryptMeta *encryptMetadata,
+	maxConcurrency int,
+	multiPartThreshold int64) error {
+	client, ok := meta.client.(StorageClient)
+	if !ok {
+		meta.resStatus = errStatus
+		meta.lastError = fmt.Errorf("no storage client for the stage")
+		return meta.lastError
+	}
+	metadata := map[string]string{
+		sfcDigest: meta.sha256Digest,
+	}
+	if encryptMeta != nil {
+		metadata[amzIv] = encryptMeta.iv
+		metadata[amzKey] = encryptMeta.key
+		metadata[amzMatdesc] = encryptMeta.matdesc
+	}
+
+	var data io.Reader
+	size := int64(-1)
+	if meta.srcReader != nil {
+		data = meta.srcReader
+		if meta.realSrcReader != nil {
+			data = meta.realSrcReader
+		}
+		size = meta.uploadSize
+	} else if meta.srcStream != nil {
+		stream := meta.srcStream
+		if meta.realSrcStream != nil {
+			stream = meta.realSrcStream
+		}
+		data = bytes.NewReader(stream.Bytes())
+		size = int64(stream.Len())
+	} else {
+		file, err := os.Open(dataFile)
+		if err != nil {
+			meta.resStatus = errStatus
+			meta.lastError = err
+			return err
+		}
+		defer file.Close()
+		if fi, err := file.Stat(); err == nil {
+			size = fi.Size()
+		}
+		data = file
+	}
+
+	if err := client.Upload(context.Background(), meta.dstFileName, data, size, metadata); err != nil {
+		meta.lastError = err
+		meta.resStatus = needRetry
+		return err
+	}
+	meta.dstFileSize = meta.uploadSize
+	meta.resStatus = uploaded
+	return nil
+}
+
+// cloudUtil implementation
+func (util *factoryStorageUtil) nativeDownloadFile() {
+	// TODO SNOW-294151
+	panic("not implemented")
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeOfDay is the value of a TIME column as the nanoseconds since midnight.
+// Scanning a TIME column into a TimeOfDay avoids the meaningless date of the
+// time.Time that the driver returns for it. The scale of the column is the
+// precision returned by ColumnType.DecimalSize. Scan into a **TimeOfDay for
+// nullable columns.
+type TimeOfDay struct {
+	Nanoseconds int64
+}
+
+// String returns t in the HH:MM:SS format followed by the fraction of second
+// without trailing zeros, if any.
+func (t TimeOfDay) String() string {
+	sec := t.Nanoseconds / int64(time.Second)
+	s := fmt.Sprintf("%02d:%02d:%02d", sec/3600, sec/60%60, sec%60)
+	if nsec := t.Nanoseconds % int64(time.Second); nsec > 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%09d", nsec), "0")
+	}
+	return s
+}
+
+// Scan sets t to the time of day of a TIME column, which the driver returns as
+// a time.Time on January 1 of year 1, or of a string in the HH:MM:SS[.fffffffff]
+// format.
+func (t *TimeOfDay) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case time.Time:
+		h, m, s := v.Clock()
+		t.Nanoseconds = int64(h)*int64(time.Hour) + int64(m)*int64(time.Minute) +
+			int64(s)*int64(time.Second) + int64(v.Nanosecond())
+	case string:
+		return t.parse(v)
+	case []byte:
+		return t.parse(string(v))
+	case nil:
+		return fmt.Errorf("cannot scan NULL into a TimeOfDay")
+	default:
+		return fmt.Errorf("cannot scan %T into a TimeOfDay", src)
+	}
+	return nil
+}
+
+func (t *TimeOfDay) parse(s string) error {
+	v, err := time.Parse("15:04:05.999999999", s)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into a TimeOfDay: %v", s, err)
+	}
+	return t.Scan(v)
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// session tokens are valid for an hour unless the login response says otherwise
+	defaultSessionTokenValidity = time.Hour
+)
+
+// tokenRenewalRetryBackoff spaces out the retries of a failed renewal, which
+// must succeed before the current token expires
+var tokenRenewalRetryBackoff = backoffConfig{
+	initial:    time.Second,
+	max:        time.Minute,
+	multiplier: 2,
+}
+
+// tokenRenewal renews the session token with the master token in the background
+// before it expires, so that queries don't have to wait for a renewal after the
+// server reports the token as expired.
+type tokenRenewal struct {
+	restful      *snowflakeRestful
+	shutdownChan chan bool
+}
+
+// renewAfter returns how long to wait before renewing the current token,
+// leaving a quarter of its validity for the renewal to complete. The validity
+// is the one returned at login or by the last renewal.
+func (tr *tokenRenewal) renewAfter() time.Duration {
+	validity := tr.restful.getSessionTokenValidity()
+	if validity <= 0 {
+		validity = defaultSessionTokenValidity
+	}
+	return validity - validity/4
+}
+
+func (tr *tokenRenewal) run() {
+	timer := time.NewTimer(tr.renewAfter())
+	defer timer.Stop()
+	failures := 0
+	for {
+		select {
+		case <-timer.C:
+			if err := tr.restful.FuncRenewSession(context.Background(), tr.restful, tr.restful.RequestTimeout); err != nil {
+				logger.Errorf("failed to renew the session token. retrying. err: %v", err)
+				timer.Reset(tokenRenewalRetryBackoff.jittered(failures))
+				failures++
+				continue
+			}
+			failures = 0
+			timer.Reset(tr.renewAfter())
+		case <-tr.shutdownChan:
+			logger.Info("stopping token renewal")
+			return
+		}
+	}
+}
+
+func (tr *tokenRenewal) start() {
+	tr.shutdownChan = make(chan bool)
+	go tr.run()
+	logger.Info("token renewal started")
+}
+
+func (tr *tokenRenewal) stop() {
+	tr.shutdownChan <- true
+	close(tr.shutdownChan)
+	logger.Info("token renewal stopped")
+}
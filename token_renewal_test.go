@@ -0,0 +1,91 @@
+// Copyright (c) 2026 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProactiveTokenRenewal(t *testing.T) {
+	var renewals int32
+	renewMock := func(_ context.Context, _ *snowflakeRestful, _ time.Duration) error {
+		atomic.AddInt32(&renewals, 1)
+		return nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{
+			Params:                map[string]*string{},
+			ProactiveTokenRenewal: true,
+		},
+		rest: &snowflakeRestful{
+			FuncRenewSession:     renewMock,
+			sessionTokenValidity: 40 * time.Millisecond, // renewed every 30ms
+		},
+	}
+	sc.startTokenRenewal()
+	if sc.rest.TokenRenewal == nil {
+		t.Fatal("the token renewal should have been started")
+	}
+	time.Sleep(200 * time.Millisecond)
+	sc.stopTokenRenewal()
+	n := atomic.LoadInt32(&renewals)
+	if n < 3 {
+		t.Fatalf("expected the token to be renewed before it expires. got %v renewals in 200ms", n)
+	}
+	if sc.rest.TokenRenewal != nil {
+		t.Fatal("the token renewal should have been cleared once stopped")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if m := atomic.LoadInt32(&renewals); m != n {
+		t.Fatalf("the token should not be renewed once stopped. got %v more renewals", m-n)
+	}
+}
+
+func TestProactiveTokenRenewalRetry(t *testing.T) {
+	defer func(backoff backoffConfig) {
+		tokenRenewalRetryBackoff = backoff
+	}(tokenRenewalRetryBackoff)
+	tokenRenewalRetryBackoff = backoffConfig{initial: 5 * time.Millisecond, max: 5 * time.Millisecond, multiplier: 1}
+
+	var renewals int32
+	renewMock := func(_ context.Context, sr *snowflakeRestful, _ time.Duration) error {
+		if atomic.AddInt32(&renewals, 1) < 3 {
+			return errors.New("renewal failed")
+		}
+		// the new token is valid for long enough to not be renewed again
+		sr.setSessionTokenValidity(time.Hour)
+		return nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{
+			Params:                map[string]*string{},
+			ProactiveTokenRenewal: true,
+		},
+		rest: &snowflakeRestful{
+			FuncRenewSession:     renewMock,
+			sessionTokenValidity: 40 * time.Millisecond, // renewed after 30ms
+		},
+	}
+	sc.startTokenRenewal()
+	time.Sleep(200 * time.Millisecond)
+	sc.stopTokenRenewal()
+	if n := atomic.LoadInt32(&renewals); n != 3 {
+		t.Fatalf("expected the failed renewals to be retried until one succeeds. got %v renewals", n)
+	}
+}
+
+func TestProactiveTokenRenewalDisabled(t *testing.T) {
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{},
+	}
+	sc.startTokenRenewal()
+	if sc.rest.TokenRenewal != nil {
+		t.Fatal("the token renewal should not have been started")
+	}
+	sc.stopTokenRenewal()
+}
@@ -5,12 +5,13 @@ package gosnowflake
 import (
 	"context"
 	"database/sql/driver"
+	"fmt"
 	"io"
 	"math/rand"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/apache/arrow/go/arrow"
 	"github.com/google/uuid"
 )
 
@@ -31,14 +32,43 @@ const (
 	fetchResultByID contextKey = "SF_FETCH_RESULT_BY_ID"
 	// fileStreamFile is the address of the file to be uploaded via PUT
 	fileStreamFile contextKey = "STREAMING_PUT_FILE"
+	// fileStreamSize is the content length of the file stream to be uploaded via PUT
+	fileStreamSize contextKey = "STREAMING_PUT_FILE_SIZE"
 	// fileTransferOptions allows the user to pass in custom
 	fileTransferOptions contextKey = "FILE_TRANSFER_OPTIONS"
 	// describeOnly returns the description of the query
 	describeOnly contextKey = "DESCRIBE_ONLY"
 	// queryTag is a parameter that allows clients to append metadata to a query
 	queryTag contextKey = "QUERY_TAG"
+	// traceID is a correlation ID recorded in the QUERY_TAG of a query
+	traceID contextKey = "TRACE_ID"
+	// decimal128Mode returns fixed-point columns as decimal128.Num values in both result formats
+	decimal128Mode contextKey = "DECIMAL128_MODE"
+	// disableServerResultCache disables Snowflake's server-side result reuse for a single query
+	disableServerResultCache contextKey = "DISABLE_SERVER_RESULT_CACHE"
+	// rawTimestampMode returns date, time and timestamp columns as int64 values in both result formats
+	rawTimestampMode contextKey = "RAW_TIMESTAMP_MODE"
+	// statementTimeout is the STATEMENT_TIMEOUT_IN_SECONDS parameter of a single query
+	statementTimeout contextKey = "STATEMENT_TIMEOUT_IN_SECONDS"
+	// rowLimit is the ROWS_PER_RESULTSET parameter of a single query
+	rowLimit contextKey = "ROWS_PER_RESULTSET"
+	// rawChunks hands result chunk bodies to the decoder without checking for gzip compression
+	rawChunks contextKey = "RAW_CHUNKS"
+	// arrowSchemaTimestampUnit is the arrow unit of the timestamp columns described by DescribeArrowSchema
+	arrowSchemaTimestampUnit contextKey = "ARROW_SCHEMA_TIMESTAMP_UNIT"
+	// customJSONDecoder decodes the JSON result chunks of a query with the custom decoder
+	customJSONDecoder contextKey = "CUSTOM_JSON_DECODER"
+	// requireArrow fails queries whose results are not in the arrow format
+	requireArrow contextKey = "REQUIRE_ARROW"
+	// submitPolicy is the SubmitPolicy of a single query
+	submitPolicy contextKey = "SUBMIT_POLICY"
+	// recoverChunkPanics turns panics while reading the rows of a query into errors
+	recoverChunkPanics contextKey = "RECOVER_CHUNK_PANICS"
 )
 
+// useCachedResult is the session parameter controlling server-side result reuse
+const useCachedResult = "USE_CACHED_RESULT"
+
 // WithMultiStatement returns a context that allows the user to execute the desired number of sql queries in one query
 func WithMultiStatement(ctx context.Context, num int) (context.Context, error) {
 	return context.WithValue(ctx, multiStatementCount, num), nil
@@ -74,6 +104,13 @@ func WithFileStream(ctx context.Context, reader io.Reader) context.Context {
 	return context.WithValue(ctx, fileStreamFile, reader)
 }
 
+// WithFileStreamSize returns a context that contains the content length of
+// the file stream to be PUT. When the size is known up front the stream is
+// uploaded as it is read instead of being buffered in memory first.
+func WithFileStreamSize(ctx context.Context, size int64) context.Context {
+	return context.WithValue(ctx, fileStreamSize, size)
+}
+
 // WithFileTransferOptions returns a context that contains the address of file transfer options
 func WithFileTransferOptions(ctx context.Context, options *SnowflakeFileTransferOptions) context.Context {
 	return context.WithValue(ctx, fileTransferOptions, options)
@@ -90,13 +127,126 @@ func WithQueryTag(ctx context.Context, tag string) context.Context {
 	return context.WithValue(ctx, queryTag, tag)
 }
 
+// WithTraceID returns a context that records id in the QUERY_TAG of any
+// queries that are run, so they can be found in the query history by the
+// trace they belong to. The tag becomes a JSON object with a "trace_id"
+// field; a tag set with WithQueryTag or Config.DefaultQueryTag is kept as
+// the object's other fields if it is a JSON object, and as its "query_tag"
+// field otherwise. QueryMonitoringData.TraceID reads the ID back.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceID, id)
+}
+
+// WithDecimal128 returns a context that makes fixed-point (NUMBER) columns be
+// returned as unscaled decimal128.Num values, regardless of whether the result
+// is fetched in JSON or Arrow format. The column scale is available through
+// ColumnTypePrecisionScale.
+func WithDecimal128(ctx context.Context) context.Context {
+	return context.WithValue(ctx, decimal128Mode, true)
+}
+
+// WithRawTimestamps returns a context that makes DATE, TIME and TIMESTAMP_*
+// columns be returned as int64 values instead of time.Time, regardless of
+// whether the result is fetched in JSON or Arrow format. TIMESTAMP_NTZ,
+// TIMESTAMP_LTZ and TIMESTAMP_TZ values count units of 10^-scale seconds since
+// the Unix epoch in UTC, dropping the time zone offset of TIMESTAMP_TZ values.
+// TIME values count units of 10^-scale seconds since midnight, and DATE values
+// count days since the Unix epoch.
+//
+// The scale of a TIME or TIMESTAMP_* column (0 to 9) is reported as its
+// precision by ColumnTypePrecisionScale, so a TIMESTAMP_NTZ(6) column yields
+// epoch microseconds and a TIMESTAMP_NTZ(9) column epoch nanoseconds.
+func WithRawTimestamps(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawTimestampMode, true)
+}
+
+// WithDisableServerResultCache returns a context that sets USE_CACHED_RESULT to
+// false for the query it is used with, forcing Snowflake to recompute the
+// result instead of reusing a cached one. The session parameter is not changed.
+func WithDisableServerResultCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, disableServerResultCache, true)
+}
+
+// WithStatementTimeout returns a context that sets STATEMENT_TIMEOUT_IN_SECONDS
+// for the query it is used with, rounded up to whole seconds. The session
+// parameter is not changed. A query that runs longer fails with
+// ErrStatementTimeout.
+func WithStatementTimeout(ctx context.Context, d time.Duration) (context.Context, error) {
+	if d <= 0 {
+		return ctx, fmt.Errorf("statement timeout must be positive: %v", d)
+	}
+	seconds := int64((d + time.Second - 1) / time.Second)
+	return context.WithValue(ctx, statementTimeout, seconds), nil
+}
+
+// WithRowLimit returns a context that sets ROWS_PER_RESULTSET for the query it
+// is used with, so that Snowflake returns at most n rows per result set
+// without rewriting its SQL, e.g. to preview a result. The session parameter
+// is not changed. The rows are also capped to n when they are read.
+func WithRowLimit(ctx context.Context, n int) (context.Context, error) {
+	if n <= 0 {
+		return ctx, fmt.Errorf("row limit must be positive: %v", n)
+	}
+	return context.WithValue(ctx, rowLimit, int64(n)), nil
+}
+
+// WithArrowSchemaTimestampUnit returns a context that makes DescribeArrowSchema
+// describe TIMESTAMP_NTZ, TIMESTAMP_LTZ and TIMESTAMP_TZ columns as Arrow
+// timestamps of the given unit instead of nanoseconds. It only changes the
+// described schema: the rows of a query and its Arrow batches are unchanged.
+func WithArrowSchemaTimestampUnit(ctx context.Context, unit arrow.TimeUnit) (context.Context, error) {
+	switch unit {
+	case arrow.Second, arrow.Millisecond, arrow.Microsecond, arrow.Nanosecond:
+		return context.WithValue(ctx, arrowSchemaTimestampUnit, unit), nil
+	}
+	return ctx, fmt.Errorf("unsupported timestamp unit: %v", unit)
+}
+
+// WithRawChunks returns a context that makes the result chunks of a query be
+// decoded as they are received, without detecting and decompressing gzip
+// compressed chunks. Use it when a proxy in front of the chunk storage already
+// decompresses the chunks.
+func WithRawChunks(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawChunks, true)
+}
+
+// WithRecoverChunkPanics returns a context that makes a panic while reading
+// the rows of a query, e.g. in the chunk downloader, be returned by Next, and
+// so by Rows.Err, as an ErrChunkPanic error instead of crashing the caller.
+// The rows keep returning the error afterwards. By default the panic is
+// propagated.
+func WithRecoverChunkPanics(ctx context.Context) context.Context {
+	return context.WithValue(ctx, recoverChunkPanics, true)
+}
+
+// WithRequireArrow returns a context that makes a query fail with
+// ErrNotArrowResult if Snowflake returns its result in the JSON format
+// instead of Arrow, e.g. because the result format parameter is set to JSON,
+// rather than reading the result through the slower JSON path.
+func WithRequireArrow(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requireArrow, true)
+}
+
+// WithCustomJSONDecoder returns a context that has the JSON result chunks of a
+// query decoded with the custom JSON decoder. It is the per-query equivalent
+// of CustomJSONDecoderEnabled.
+func WithCustomJSONDecoder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, customJSONDecoder, true)
+}
+
+// UUIDGenerator generates the request IDs and request GUIDs sent to Snowflake
+// and the names of the stage directories of array binds. It can be replaced,
+// e.g. by a seeded generator for deterministic IDs, but it is read without
+// synchronization, so it is not safe to change while the driver is in use.
+var UUIDGenerator = uuid.New
+
 // Get the request ID from the context if specified, otherwise generate one
 func getOrGenerateRequestIDFromContext(ctx context.Context) uuid.UUID {
 	requestID, ok := ctx.Value(snowflakeRequestIDKey).(uuid.UUID)
 	if ok && requestID != uuid.Nil {
 		return requestID
 	}
-	return uuid.New()
+	return UUIDGenerator()
 }
 
 // integer min
@@ -204,14 +354,7 @@ func (sta *simpleTokenAccessor) SetTokens(token string, masterToken string, sess
 }
 
 func escapeForCSV(value string) string {
-	if value == "" {
-		return "\"\""
-	}
-	if strings.Contains(value, "\"") || strings.Contains(value, "\n") ||
-		strings.Contains(value, ",") || strings.Contains(value, "\\") {
-		return "\"" + strings.ReplaceAll(value, "\"", "\"\"") + "\""
-	}
-	return value
+	return defaultCSVDialect.escape(value)
 }
 
 func randomString(n int) string {
@@ -223,3 +366,9 @@ func randomString(n int) string {
 	}
 	return string(b)
 }
+
+// WithSubmitPolicy returns a context that waits for the result of a query as
+// policy says instead of as Config.SubmitPolicy says
+func WithSubmitPolicy(ctx context.Context, policy SubmitPolicy) context.Context {
+	return context.WithValue(ctx, submitPolicy, policy)
+}
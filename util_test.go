@@ -5,8 +5,12 @@ package gosnowflake
 import (
 	"context"
 	"database/sql/driver"
+	"io/ioutil"
 	"math/rand"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -232,3 +236,43 @@ func TestGetMin(t *testing.T) {
 		}
 	}
 }
+
+func TestUUIDGenerator(t *testing.T) {
+	defer func(gen func() uuid.UUID) { UUIDGenerator = gen }(UUIDGenerator)
+	var n byte
+	UUIDGenerator = func() uuid.UUID {
+		n++
+		return uuid.UUID{15: n}
+	}
+
+	var postedURL *url.URL
+	postMock := func(_ context.Context, _ *snowflakeRestful, u *url.URL, _ map[string]string, _ []byte, _ time.Duration, _ bool) (*http.Response, error) {
+		postedURL = u
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"code": "0", "success": true, "data": {}}`)),
+		}, nil
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:            "https",
+			Host:                "abc.snowflakecomputing.com",
+			Port:                443,
+			TokenAccessor:       getSimpleTokenAccessor(),
+			FuncPostQuery:       postRestfulQuery,
+			FuncPostQueryHelper: postRestfulQueryHelper,
+			FuncPost:            postMock,
+		},
+	}
+	if _, err := sc.exec(context.Background(), "select 1", false /* noResult */, false /* isInternal */, false /* describeOnly */, nil); err != nil {
+		t.Fatal(err)
+	}
+	params := postedURL.Query()
+	if id := params.Get(requestIDKey); id != "00000000-0000-0000-0000-000000000001" {
+		t.Fatalf("unexpected request ID: %v", id)
+	}
+	if guid := params.Get(requestGUIDKey); guid != "00000000-0000-0000-0000-000000000002" {
+		t.Fatalf("unexpected request GUID: %v", guid)
+	}
+}